@@ -0,0 +1,223 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+//
+// Accessibility
+// - Fyne draws its own controls and exposes none of them to the platform's
+//   accessibility API, so screen readers see one blank view. While
+//   VoiceOver runs, the window speaks for itself instead: whichever control
+//   takes the keyboard focus is announced by name, role and state ("Target
+//   size KB, text field", "Shrink..., checkbox, checked"), as are dialogs
+//   as they open and the outcome of each run
+// - Names come from an explicit accessibleName, a "Label:" paired with the
+//   control in the same row or form, else its own text or placeholder
+// - Every control is reachable with Tab / Shift-Tab in reading order, left
+//   pane first; Space activates, arrow keys move in lists, sliders and
+//   selects. Shortcuts: Cmd/Ctrl-O add files, Cmd/Ctrl-Shift-O add a
+//   folder, Cmd/Ctrl-Return start, Cmd/Ctrl-Backspace remove the selection,
+//   Cmd/Ctrl-L focus the queue
+// - Announcements need macOS; elsewhere the focus order and shortcuts still
+//   apply
+//
+
+const focusPollInterval = 250 * time.Millisecond
+
+// accessibleNames are names given to controls that have no text or label
+// of their own.
+var accessibleNames = map[fyne.CanvasObject]string{}
+
+func accessibleName(o fyne.CanvasObject, name string) {
+	accessibleNames[o] = name
+}
+
+// pairedLabel finds the "Label:" that names target in the tree under root:
+// a two-object row of a label and the control, or a form item.
+func pairedLabel(root, target fyne.CanvasObject) (string, bool) {
+	switch o := root.(type) {
+	case *fyne.Container:
+		if len(o.Objects) == 2 {
+			for i, c := range o.Objects {
+				l, ok := o.Objects[1-i].(*widget.Label)
+				if c == target && ok && strings.HasSuffix(l.Text, ":") {
+					return strings.TrimSuffix(l.Text, ":"), true
+				}
+			}
+		}
+		for _, c := range o.Objects {
+			if name, ok := pairedLabel(c, target); ok {
+				return name, true
+			}
+		}
+	case *widget.Form:
+		for _, item := range o.Items {
+			if item.Widget == target && item.Text != "" {
+				return item.Text, true
+			}
+			if name, ok := pairedLabel(item.Widget, target); ok {
+				return name, true
+			}
+		}
+	case *container.Scroll:
+		return pairedLabel(o.Content, target)
+	case *container.Split:
+		if name, ok := pairedLabel(o.Leading, target); ok {
+			return name, true
+		}
+		return pairedLabel(o.Trailing, target)
+	case *container.AppTabs:
+		for _, t := range o.Items {
+			if name, ok := pairedLabel(t.Content, target); ok {
+				return name, true
+			}
+		}
+	case *widget.PopUp:
+		return pairedLabel(o.Content, target)
+	}
+	return "", false
+}
+
+// dialogTitle is the bold heading of a dialog overlay, or "".
+func dialogTitle(o fyne.CanvasObject) string {
+	switch o := o.(type) {
+	case *widget.PopUp:
+		return dialogTitle(o.Content)
+	case *fyne.Container:
+		for _, c := range o.Objects {
+			if t := dialogTitle(c); t != "" {
+				return t
+			}
+		}
+	case *widget.Label:
+		if o.TextStyle.Bold {
+			return o.Text
+		}
+	}
+	return ""
+}
+
+func onOff(b bool, on, off string) string {
+	if b {
+		return on
+	}
+	return off
+}
+
+// describeControl is what a screen reader says for o; roots are searched
+// for a paired label.
+func describeControl(o fyne.Focusable, roots ...fyne.CanvasObject) string {
+	obj, _ := o.(fyne.CanvasObject)
+	name := accessibleNames[obj]
+	if name == "" {
+		for _, r := range roots {
+			if n, ok := pairedLabel(r, obj); ok {
+				name = n
+				break
+			}
+		}
+	}
+	named := func(own string) string {
+		if name != "" {
+			return name
+		}
+		return own
+	}
+	var parts []string
+	switch w := o.(type) {
+	case *widget.Button:
+		parts = []string{named(w.Text), "button"}
+	case *widget.Check:
+		parts = []string{named(w.Text), "checkbox", onOff(w.Checked, "checked", "unchecked")}
+	case *widget.Select:
+		parts = []string{named(w.PlaceHolder), "pop-up button", w.Selected}
+	case *widget.SelectEntry:
+		parts = []string{named(w.PlaceHolder), "combo box", w.Text}
+	case *widget.Entry:
+		role, value := onOff(w.MultiLine, "text area", "text field"), w.Text
+		if w.Password {
+			role, value = "secure text field", "" // never read a secret aloud
+		}
+		parts = []string{named(w.PlaceHolder), role, value}
+	case *widget.Slider:
+		parts = []string{named(""), "slider", fmt.Sprintf("%.4g", w.Value)}
+	case *widget.List:
+		parts = []string{named(""), "list"}
+	default:
+		parts = []string{named(""), "control"}
+	}
+	if d, ok := o.(fyne.Disableable); ok && d.Disabled() {
+		parts = append(parts, "dimmed")
+	}
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// watchFocus announces focus changes and newly opened dialogs while a
+// screen reader runs. It does nothing where announcements are unsupported.
+func watchFocus(w fyne.Window) {
+	if !announceSupported {
+		return
+	}
+	var lastFocus fyne.Focusable
+	var lastOverlay fyne.CanvasObject
+	check := func() {
+		if !screenReaderRunning() {
+			return
+		}
+		c := w.Canvas()
+		if top := c.Overlays().Top(); top != lastOverlay {
+			lastOverlay = top
+			if t := dialogTitle(top); t != "" {
+				announce(t + ", dialog")
+			}
+		}
+		if f := c.Focused(); f != nil && f != lastFocus {
+			lastFocus = f
+			roots := []fyne.CanvasObject{c.Content()}
+			if top := c.Overlays().Top(); top != nil {
+				roots = append([]fyne.CanvasObject{top}, roots...)
+			}
+			announce(describeControl(f, roots...))
+		}
+	}
+	go func() {
+		for range time.Tick(focusPollInterval) {
+			fyne.Do(check)
+		}
+	}()
+}
+
+// addKeyboardShortcuts binds the window's main actions; tap runs a button
+// only when it is enabled.
+func addKeyboardShortcuts(w fyne.Window, add, addFolder, start, remove *widget.Button, queue fyne.Focusable) {
+	tap := func(b *widget.Button) func(fyne.Shortcut) {
+		return func(fyne.Shortcut) {
+			if !b.Disabled() && b.OnTapped != nil {
+				b.OnTapped()
+			}
+		}
+	}
+	mod := fyne.KeyModifierShortcutDefault
+	c := w.Canvas()
+	c.AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyO, Modifier: mod}, tap(add))
+	c.AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyO, Modifier: mod | fyne.KeyModifierShift}, tap(addFolder))
+	c.AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyReturn, Modifier: mod}, tap(start))
+	c.AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyBackspace, Modifier: mod}, tap(remove))
+	c.AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyL, Modifier: mod}, func(fyne.Shortcut) { c.Focus(queue) })
+}
@@ -0,0 +1,45 @@
+//go:build darwin && !headless
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework AppKit
+#include <stdlib.h>
+#import <AppKit/AppKit.h>
+
+static int voiceOverEnabled(void) {
+	return [[NSWorkspace sharedWorkspace] isVoiceOverEnabled] ? 1 : 0;
+}
+
+// announceText asks VoiceOver to speak text now, interrupting what it was
+// saying.
+static void announceText(const char *text) {
+	NSString *s = [[NSString alloc] initWithUTF8String:text];
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSDictionary *info = @{
+			NSAccessibilityAnnouncementKey: s,
+			NSAccessibilityPriorityKey: @(NSAccessibilityPriorityHigh),
+		};
+		id element = [NSApp mainWindow] ? (id)[NSApp mainWindow] : (id)NSApp;
+		NSAccessibilityPostNotificationWithUserInfo(element, NSAccessibilityAnnouncementRequestedNotification, info);
+		[s release];
+	});
+}
+*/
+import "C"
+
+import "unsafe"
+
+const announceSupported = true
+
+func screenReaderRunning() bool {
+	return C.voiceOverEnabled() != 0
+}
+
+// announce has VoiceOver speak text; it is silent when VoiceOver is off.
+func announce(text string) {
+	cs := C.CString(text)
+	defer C.free(unsafe.Pointer(cs))
+	C.announceText(cs)
+}
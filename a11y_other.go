@@ -0,0 +1,12 @@
+//go:build !darwin && !headless
+
+package main
+
+// Screen reader announcements need macOS's NSAccessibility; see a11y.go.
+const announceSupported = false
+
+func screenReaderRunning() bool {
+	return false
+}
+
+func announce(text string) {}
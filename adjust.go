@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// colorAdjust holds the batch-wide manual adjustments, in imaging's units:
+// brightness/contrast/saturation are percentages in -100..100, gamma is a
+// multiplier where 1 (or 0, the zero value) means unchanged.
+type colorAdjust struct {
+	brightness, contrast, saturation float64
+	gamma                            float64
+}
+
+func (a colorAdjust) identity() bool {
+	return a.brightness == 0 && a.contrast == 0 && a.saturation == 0 && (a.gamma == 0 || a.gamma == 1)
+}
+
+func (a colorAdjust) apply(img image.Image) image.Image {
+	if a.identity() {
+		return img
+	}
+	if a.gamma != 0 && a.gamma != 1 {
+		img = imaging.AdjustGamma(img, a.gamma)
+	}
+	if a.brightness != 0 {
+		img = imaging.AdjustBrightness(img, a.brightness)
+	}
+	if a.contrast != 0 {
+		img = imaging.AdjustContrast(img, a.contrast)
+	}
+	if a.saturation != 0 {
+		img = imaging.AdjustSaturation(img, a.saturation)
+	}
+	return img
+}
+
+func (a colorAdjust) String() string {
+	var parts []string
+	if a.brightness != 0 {
+		parts = append(parts, fmt.Sprintf("brightness %+.0f", a.brightness))
+	}
+	if a.contrast != 0 {
+		parts = append(parts, fmt.Sprintf("contrast %+.0f", a.contrast))
+	}
+	if a.saturation != 0 {
+		parts = append(parts, fmt.Sprintf("saturation %+.0f", a.saturation))
+	}
+	if a.gamma != 0 && a.gamma != 1 {
+		parts = append(parts, fmt.Sprintf("gamma %.2f", a.gamma))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,217 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+//
+// Folder-size analyzer
+// - "Analyze Folder..." scans a folder, subfolders included, and shows
+//   where its image bytes go before anything is queued: the largest files,
+//   totals by extension and by resolution
+// - Resolutions are read from the file headers only, so even large
+//   libraries scan quickly; a file whose header cannot be read counts as
+//   "unknown"
+// - "Queue Largest" adds the listed largest files, "Queue Folder" the
+//   whole folder
+//
+
+const analyzerTopN = 25
+
+// resolutionBuckets are the megapixel ranges the analyzer totals by.
+var resolutionBuckets = []struct {
+	label string
+	maxMP float64
+}{
+	{"up to 1MP", 1}, {"1–4MP", 4}, {"4–12MP", 12}, {"12–24MP", 24}, {"24–50MP", 50}, {"over 50MP", 1e9},
+}
+
+type analyzedFile struct {
+	path string
+	size int64
+	w, h int // 0 when the header cannot be read
+}
+
+// sizeTally is a file count and byte total.
+type sizeTally struct {
+	label string
+	count int
+	bytes int64
+}
+
+type folderAnalysis struct {
+	total   sizeTally
+	largest []analyzedFile // biggest first, at most analyzerTopN
+	byExt   []sizeTally    // biggest first
+	byRes   []sizeTally    // in resolutionBuckets order, then unknown
+}
+
+// imageHeader reads an image's dimensions without decoding its pixels.
+func imageHeader(path string) (w, h int) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0
+	}
+	return cfg.Width, cfg.Height
+}
+
+// analyzeFolder totals the images under root; progress is called with the
+// number of files read so far.
+func analyzeFolder(root string, progress func(done, total int)) (folderAnalysis, error) {
+	paths, err := listImages(root)
+	if err != nil {
+		return folderAnalysis{}, err
+	}
+	a := folderAnalysis{total: sizeTally{label: "All images"}}
+	files := make([]analyzedFile, 0, len(paths))
+	exts := map[string]*sizeTally{}
+	res := make([]sizeTally, len(resolutionBuckets)+1)
+	for i, b := range resolutionBuckets {
+		res[i].label = b.label
+	}
+	res[len(res)-1].label = "unknown"
+	for i, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		f := analyzedFile{path: p, size: info.Size()}
+		f.w, f.h = imageHeader(p)
+		files = append(files, f)
+		a.total.count++
+		a.total.bytes += f.size
+
+		ext := strings.ToLower(filepath.Ext(p))
+		if exts[ext] == nil {
+			exts[ext] = &sizeTally{label: ext}
+		}
+		exts[ext].count++
+		exts[ext].bytes += f.size
+
+		bucket := len(res) - 1
+		if f.w > 0 {
+			mp := float64(f.w*f.h) / 1e6
+			for bi, b := range resolutionBuckets {
+				if mp <= b.maxMP {
+					bucket = bi
+					break
+				}
+			}
+		}
+		res[bucket].count++
+		res[bucket].bytes += f.size
+		if progress != nil && (i+1)%50 == 0 {
+			progress(i+1, len(paths))
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].size > files[j].size })
+	a.largest = files[:min(len(files), analyzerTopN)]
+	for _, t := range exts {
+		a.byExt = append(a.byExt, *t)
+	}
+	sort.Slice(a.byExt, func(i, j int) bool { return a.byExt[i].bytes > a.byExt[j].bytes })
+	for _, t := range res {
+		if t.count > 0 {
+			a.byRes = append(a.byRes, t)
+		}
+	}
+	return a, nil
+}
+
+// share is t's part of the total bytes, as a line of the report.
+func (a folderAnalysis) share(t sizeTally) string {
+	pct := 0.0
+	if a.total.bytes > 0 {
+		pct = float64(t.bytes) / float64(a.total.bytes) * 100
+	}
+	return fmt.Sprintf("%-12s %6d files  %9s  %5.1f%%", t.label, t.count, formatSize(t.bytes), pct)
+}
+
+func (a folderAnalysis) report() (largest, breakdown string) {
+	var l []string
+	for _, f := range a.largest {
+		dims := "?"
+		if f.w > 0 {
+			dims = fmt.Sprintf("%dx%d", f.w, f.h)
+		}
+		l = append(l, fmt.Sprintf("%9s  %11s  %s", formatSize(f.size), dims, f.path))
+	}
+	b := []string{fmt.Sprintf("%d images, %s", a.total.count, formatSize(a.total.bytes)), "", "By extension:"}
+	for _, t := range a.byExt {
+		b = append(b, a.share(t))
+	}
+	b = append(b, "", "By resolution:")
+	for _, t := range a.byRes {
+		b = append(b, a.share(t))
+	}
+	return strings.Join(l, "\n"), strings.Join(b, "\n")
+}
+
+// showFolderAnalyzer scans root in the background and shows the report;
+// onQueue adds files or the folder to the queue.
+func showFolderAnalyzer(app fyne.App, root string, onQueue func(paths []string)) {
+	win := app.NewWindow("Analyze — " + filepath.Base(root))
+	win.Resize(fyne.NewSize(900, 600))
+	status := widget.NewLabel("Scanning " + root + "...")
+	mono := func() *widget.Label {
+		l := widget.NewLabel("")
+		l.TextStyle = fyne.TextStyle{Monospace: true}
+		return l
+	}
+	largestLabel, breakdownLabel := mono(), mono()
+	queueLargest := widget.NewButton("Queue Largest", nil)
+	queueLargest.Disable()
+	queueFolder := widget.NewButton("Queue Folder", func() {
+		onQueue([]string{root})
+		win.Close()
+	})
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Breakdown", container.NewVScroll(breakdownLabel)),
+		container.NewTabItem(fmt.Sprintf("Largest %d", analyzerTopN), container.NewScroll(largestLabel)),
+	)
+	win.SetContent(container.NewBorder(status, container.NewHBox(queueLargest, queueFolder), nil, nil, tabs))
+	win.Show()
+
+	go func() {
+		a, err := analyzeFolder(root, func(done, total int) {
+			fyne.Do(func() { status.SetText(fmt.Sprintf("Scanning %s... %d of %d", root, done, total)) })
+		})
+		fyne.Do(func() {
+			if err != nil {
+				status.SetText("Scan failed: " + err.Error())
+				return
+			}
+			status.SetText(fmt.Sprintf("%s: %d images, %s", root, a.total.count, formatSize(a.total.bytes)))
+			largest, breakdown := a.report()
+			largestLabel.SetText(largest)
+			breakdownLabel.SetText(breakdown)
+			if len(a.largest) > 0 {
+				queueLargest.OnTapped = func() {
+					paths := make([]string, len(a.largest))
+					for i, f := range a.largest {
+						paths[i] = f.path
+					}
+					onQueue(paths)
+					win.Close()
+				}
+				queueLargest.Enable()
+			}
+		})
+	}()
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//
+// Archive input
+// - A queued .zip, .tar, .tar.gz or .tgz is processed entry by entry: each
+//   image is copied to a scratch file, compressed, and the scratch file is
+//   removed, so the archive is never extracted as a whole
+// - An entry may expand to at most maxArchiveEntry, and to no more than the
+//   scratch space has left under its cap; a bigger one (or a "zip bomb")
+//   fails on its own and the rest of the archive carries on
+// - TAR is read sequentially, so even a compressed tarball streams; ZIP is
+//   read through its central directory
+// - Outputs go to a folder named after the archive, keeping the entries'
+//   folders, or back into a new "<name>-compressed.zip"
+// - Folder overrides, rules and the up-to-date check do not apply to
+//   entries; the window's settings do
+//
+
+const maxArchiveEntry = 1 << 30 // bytes one entry may expand to
+
+// isArchive reports whether path is an archive input.
+func isArchive(p string) bool {
+	lower := strings.ToLower(p)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveStem is the archive's name without its archive extensions.
+func archiveStem(p string) string {
+	base := filepath.Base(p)
+	lower := strings.ToLower(base)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return base[:len(base)-len(ext)]
+		}
+	}
+	return base
+}
+
+// cleanEntryName rejects absolute names and ones escaping the archive
+// ("zip slip"), and normalizes the rest to slash-separated form.
+func cleanEntryName(name string) (string, bool) {
+	name = path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") || filepath.VolumeName(name) != "" {
+		return "", false
+	}
+	return name, true
+}
+
+// walkArchive copies each image entry of archive into scratchDir in turn
+// and calls fn with the entry name and the scratch copy, which is removed
+// once fn returns, or with the reason the entry could not be copied; no
+// entry may expand past limit bytes. Other entries are skipped.
+func walkArchive(archive, scratchDir string, limit int64, fn func(entry, scratch string, err error) error) error {
+	visit := func(name string, open func() (io.ReadCloser, error)) error {
+		entry, ok := cleanEntryName(name)
+		if !ok || !imageExts[strings.ToLower(path.Ext(entry))] {
+			return nil
+		}
+		r, err := open()
+		if err != nil {
+			return fn(entry, "", fmt.Errorf("extract failed: %v", err))
+		}
+		scratch := filepath.Join(scratchDir, path.Base(entry))
+		f, err := os.Create(scratch)
+		if err == nil {
+			var n int64
+			n, err = io.Copy(f, io.LimitReader(r, limit+1))
+			if err == nil && n > limit {
+				err = fmt.Errorf("expands to over %s, more than an archive entry may", formatSize(limit))
+			}
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		r.Close()
+		defer os.Remove(scratch)
+		if err != nil {
+			return fn(entry, "", fmt.Errorf("extract failed: %v", err))
+		}
+		return fn(entry, scratch, nil)
+	}
+
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		zr, err := zip.OpenReader(archive)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() {
+				continue
+			}
+			if err := visit(zf.Name, zf.Open); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if lower := strings.ToLower(archive); strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := visit(hdr.Name, func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }); err != nil {
+			return err
+		}
+	}
+}
+
+// archiveRun is how one archive input is processed.
+type archiveRun struct {
+	outDir  string
+	opts    compressOptions
+	timeout time.Duration
+	repack  bool // write outputs into <name>-compressed.zip instead of a folder
+	// deliver gets each successful result while its output file still
+	// exists, to copy it to the other destinations
+	deliver func(res fileResult) error
+}
+
+// processArchive compresses every image in archive. Entry failures are
+// reported in their results; err is for the archive as a whole. zipPath is
+// the repacked archive, if any.
+func processArchive(archive string, run archiveRun) (results []fileResult, zipPath string, err error) {
+	scratchDir, err := scratch.dir("archive")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	stem := archiveStem(archive)
+	var packed *zipDest
+	if run.repack {
+		if err := os.MkdirAll(run.outDir, 0755); err != nil {
+			return nil, "", err
+		}
+		zipPath = uniqueOutputPath(filepath.Join(run.outDir, stem+"-compressed.zip"))
+		if packed, err = newZipDest(zipPath); err != nil {
+			return nil, "", err
+		}
+	}
+	looseDir := filepath.Join(run.outDir, stem)
+	if run.repack {
+		looseDir = filepath.Join(scratchDir, "out")
+	}
+
+	limit := int64(maxArchiveEntry)
+	if room := scratch.room(); room >= 0 {
+		limit = min(limit, room)
+	}
+	err = walkArchive(archive, scratchDir, limit, func(entry, src string, xerr error) error {
+		virtual := filepath.Join(archive, filepath.FromSlash(entry))
+		if xerr != nil {
+			results = append(results, fileResult{inPath: virtual, err: xerr})
+			return nil
+		}
+		dir := path.Dir(entry)
+		base := path.Base(entry)
+		name := run.opts.naming.outputStem(strings.TrimSuffix(base, path.Ext(base)), outputExt(run.opts.format))
+		outPath := uniqueOutputPath(filepath.Join(looseDir, filepath.FromSlash(dir), name+outputExt(run.opts.format)))
+
+		res, perr := runWithTimeout(virtual, run.timeout, func(ctx context.Context) (fileResult, error) {
+			return processImageSync(ctx, src, outPath, run.opts)
+		})
+		res.inPath = virtual
+		res.msg = strings.ReplaceAll(res.msg, src, virtual)
+		if perr == nil && run.deliver != nil {
+			perr = run.deliver(res)
+		}
+		if perr == nil && packed != nil {
+			var zipEntry string
+			if zipEntry, perr = packed.putAs(res.outPath, path.Join(dir, filepath.Base(res.outPath))); perr == nil {
+				os.Remove(res.outPath)
+				inZip := filepath.Join(zipPath, filepath.FromSlash(zipEntry))
+				res.msg = strings.ReplaceAll(res.msg, res.outPath, inZip)
+				res.outPath = inZip
+			}
+		}
+		if res.err = perr; perr != nil {
+			res.outPath = ""
+		}
+		results = append(results, res)
+		return nil
+	})
+	if packed != nil {
+		if cerr := packed.close(); err == nil {
+			err = cerr
+		}
+	}
+	return results, zipPath, err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+// Auto-correction limits: keep fixes gentle so a deliberately warm sunset or
+// a low-key portrait is nudged, not "corrected" into something else.
+const (
+	wbMinGain       = 0.75
+	wbMaxGain       = 1.35
+	exposureClip    = 0.005 // share of pixels allowed to clip at each end
+	exposureTarget  = 0.46  // mean luminance after correction (0..1)
+	exposureMaxGam  = 1.6
+	exposureMaxGain = 2.0
+)
+
+// autoWhiteBalance applies a grey-world correction over mid-tone pixels:
+// clipped highlights and deep shadows carry no reliable colour cast.
+func autoWhiteBalance(img image.Image) image.Image {
+	small := imaging.Fit(img, 400, 400, imaging.Box)
+	var sr, sg, sb, n float64
+	for i := 0; i < len(small.Pix); i += 4 {
+		r, g, b := float64(small.Pix[i]), float64(small.Pix[i+1]), float64(small.Pix[i+2])
+		if l := (r + g + b) / 3; l < 16 || l > 240 {
+			continue
+		}
+		sr, sg, sb = sr+r, sg+g, sb+b
+		n++
+	}
+	if n == 0 || sr == 0 || sg == 0 || sb == 0 {
+		return img
+	}
+	grey := (sr + sg + sb) / 3
+	clamp := func(v float64) float64 { return math.Max(wbMinGain, math.Min(wbMaxGain, v)) }
+	gr, gg, gb := clamp(grey/sr), clamp(grey/sg), clamp(grey/sb)
+
+	return imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		c.R = clampByte(float64(c.R) * gr)
+		c.G = clampByte(float64(c.G) * gg)
+		c.B = clampByte(float64(c.B) * gb)
+		return c
+	})
+}
+
+// autoExpose stretches the luminance range to the clip points and then
+// applies a gamma that brings average brightness towards exposureTarget.
+func autoExpose(img image.Image) image.Image {
+	small := imaging.Fit(img, 400, 400, imaging.Box)
+	var lum []float64
+	for i := 0; i < len(small.Pix); i += 4 {
+		lum = append(lum, 0.299*float64(small.Pix[i])+0.587*float64(small.Pix[i+1])+0.114*float64(small.Pix[i+2]))
+	}
+	if len(lum) == 0 {
+		return img
+	}
+	sort.Float64s(lum)
+	lo := lum[int(float64(len(lum)-1)*exposureClip)]
+	hi := lum[int(float64(len(lum)-1)*(1-exposureClip))]
+	if hi-lo < 1 {
+		return img
+	}
+	scale := math.Min(255/(hi-lo), exposureMaxGain)
+
+	var mean float64
+	for _, l := range lum {
+		mean += math.Max(0, math.Min(1, (l-lo)*scale/255))
+	}
+	mean /= float64(len(lum))
+	gamma := 1.0
+	if mean > 0.01 && mean < 0.99 {
+		// out = in^(1/gamma): solve mean^(1/gamma) = target
+		gamma = math.Log(mean) / math.Log(exposureTarget)
+		gamma = math.Max(1/exposureMaxGam, math.Min(exposureMaxGam, gamma))
+	}
+
+	var lut [256]uint8
+	for i := range lut {
+		v := math.Max(0, math.Min(1, (float64(i)-lo)*scale/255))
+		lut[i] = clampByte(255 * math.Pow(v, 1/gamma))
+	}
+	return imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		c.R, c.G, c.B = lut[c.R], lut[c.G], lut[c.B]
+		return c
+	})
+}
+
+func clampByte(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// Print bleed
+// - Print shops want artwork extended past the trim line so a slightly off
+//   cut leaves no white edge. "Bleed" adds a margin on every side of each
+//   image, after any resize, so the image keeps its size inside the margin
+// - The margin is given in mm or inches (converted with the Print DPI, 300
+//   when none is set) or px, e.g. "3mm" or "36px"; a bare number is mm
+// - It is filled by mirroring the image's edges, which continues most
+//   backgrounds naturally, or with a solid colour
+//
+
+const bleedDefaultDPI = 300
+
+type bleedOptions struct {
+	size   float64 // 0 for no bleed
+	unit   string  // "mm" or "px"
+	mirror bool    // mirror the edges rather than fill with fill
+	fill   color.NRGBA
+}
+
+// parseBleed reads a margin such as "3mm", "0.125in" or "36px".
+func parseBleed(s string) (size float64, unit string, err error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, "", nil
+	}
+	unit = "mm"
+	for _, u := range []string{"mm", "px", "in"} {
+		if strings.HasSuffix(s, u) {
+			unit, s = u, strings.TrimSpace(strings.TrimSuffix(s, u))
+			break
+		}
+	}
+	size, err = strconv.ParseFloat(s, 64)
+	if err != nil || size < 0 {
+		return 0, "", fmt.Errorf("bleed must be a size like 3mm or 36px")
+	}
+	if unit == "in" {
+		size, unit = size*25.4, "mm"
+	}
+	return size, unit, nil
+}
+
+// parseHexColor reads "#RRGGBB" or "RRGGBB".
+func parseHexColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || len(s) != 6 {
+		return color.NRGBA{}, fmt.Errorf("colour must be #RRGGBB, got %q", s)
+	}
+	return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
+
+// pixels is the margin in pixels at dpi (bleedDefaultDPI when 0).
+func (b bleedOptions) pixels(dpi int) int {
+	if b.unit == "px" {
+		return int(math.Round(b.size))
+	}
+	if dpi <= 0 {
+		dpi = bleedDefaultDPI
+	}
+	return int(math.Round(b.size / 25.4 * float64(dpi)))
+}
+
+func (b bleedOptions) String() string {
+	if b.size <= 0 {
+		return ""
+	}
+	how := "mirrored"
+	if !b.mirror {
+		how = fmt.Sprintf("#%02X%02X%02X", b.fill.R, b.fill.G, b.fill.B)
+	}
+	return fmt.Sprintf("%g%s bleed, %s", b.size, b.unit, how)
+}
+
+// apply extends img by the margin on every side.
+func (b bleedOptions) apply(img image.Image, dpi int) image.Image {
+	m := b.pixels(dpi)
+	if m <= 0 {
+		return img
+	}
+	src := imaging.Clone(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if !b.mirror {
+		return imaging.Paste(imaging.New(w+2*m, h+2*m, b.fill), src, image.Pt(m, m))
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w+2*m, h+2*m))
+	cols := make([]int, w+2*m) // source column of each destination column
+	for x := range cols {
+		cols[x] = reflectIndex(x-m, w)
+	}
+	for y := 0; y < h+2*m; y++ {
+		srow := src.Pix[reflectIndex(y-m, h)*src.Stride:]
+		drow := dst.Pix[y*dst.Stride:]
+		copy(drow[m*4:(m+w)*4], srow[:w*4])
+		for x, sx := range cols {
+			if x < m || x >= m+w {
+				copy(drow[x*4:x*4+4], srow[sx*4:sx*4+4])
+			}
+		}
+	}
+	return dst
+}
+
+// reflectIndex mirrors i back into [0, n), for margins wider than the
+// image too.
+func reflectIndex(i, n int) int {
+	period := 2 * n
+	i %= period
+	if i < 0 {
+		i += period
+	}
+	if i >= n {
+		i = period - 1 - i
+	}
+	return i
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+)
+
+//
+// Sandbox access grants
+// - A sandboxed macOS build may only read what the user picked, and that
+//   permission ends with the process; security-scoped bookmarks carry it
+//   over to the next launch
+// - A bookmark is saved for each file or folder picked as an input or an
+//   output; at startup they are resolved and opened again, and the ones
+//   that no longer work are reported instead of failing reads later
+// - Elsewhere createBookmark returns nothing and this is a no-op
+//
+
+const (
+	bookmarksKey = "bookmarks"
+	bookmarksMax = 64
+)
+
+type savedBookmark struct {
+	Path string `json:"path"`
+	Data []byte `json:"data"`
+}
+
+func loadBookmarks(p preferences) []savedBookmark {
+	var marks []savedBookmark
+	json.Unmarshal([]byte(p.String(bookmarksKey)), &marks) // unreadable means none
+	return marks
+}
+
+func saveBookmarks(p preferences, marks []savedBookmark) {
+	if len(marks) > bookmarksMax {
+		marks = marks[:bookmarksMax]
+	}
+	data, err := json.Marshal(marks)
+	if err == nil {
+		p.SetString(bookmarksKey, string(data))
+	}
+}
+
+// grantAccess bookmarks a file or folder the user picked, newest first.
+func grantAccess(p preferences, path string) {
+	path = filepath.Clean(path)
+	data, err := createBookmark(path)
+	if err != nil || data == nil {
+		return
+	}
+	marks := []savedBookmark{{Path: path, Data: data}}
+	for _, m := range loadBookmarks(p) {
+		if m.Path != path {
+			marks = append(marks, m)
+		}
+	}
+	saveBookmarks(p, marks)
+}
+
+// restoreAccess reopens the saved bookmarks. Stale ones (the item was moved
+// or renamed) are refreshed; lost lists the paths that cannot be opened and
+// have to be picked again.
+func restoreAccess(p preferences) (lost []string) {
+	marks := loadBookmarks(p)
+	if len(marks) == 0 {
+		return nil
+	}
+	var kept []savedBookmark
+	for _, m := range marks {
+		path, stale, err := resolveBookmark(m.Data)
+		if err != nil {
+			lost = append(lost, m.Path)
+			continue
+		}
+		if stale {
+			if data, err := createBookmark(path); err == nil && data != nil {
+				m.Data = data
+			}
+		}
+		m.Path = path
+		kept = append(kept, m)
+	}
+	saveBookmarks(p, kept)
+	return lost
+}
@@ -0,0 +1,84 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Foundation
+#include <stdlib.h>
+#include <string.h>
+#import <Foundation/Foundation.h>
+
+// makeBookmark returns a malloc'd security-scoped bookmark for path, or
+// NULL with a malloc'd message in *errOut.
+static void *makeBookmark(const char *path, int *n, char **errOut) {
+	@autoreleasepool {
+		NSURL *url = [NSURL fileURLWithPath:[NSString stringWithUTF8String:path]];
+		NSError *err = nil;
+		NSData *data = [url bookmarkDataWithOptions:NSURLBookmarkCreationWithSecurityScope
+			includingResourceValuesForKeys:nil relativeToURL:nil error:&err];
+		if (data == nil) {
+			*errOut = strdup([[err localizedDescription] UTF8String]);
+			return NULL;
+		}
+		*n = (int)[data length];
+		void *out = malloc(*n);
+		memcpy(out, [data bytes], *n);
+		return out;
+	}
+}
+
+// openBookmark resolves a bookmark and starts accessing it for the rest of
+// the process, returning the malloc'd path.
+static char *openBookmark(const void *bytes, int n, int *stale, char **errOut) {
+	@autoreleasepool {
+		NSData *data = [NSData dataWithBytes:bytes length:n];
+		BOOL isStale = NO;
+		NSError *err = nil;
+		NSURL *url = [NSURL URLByResolvingBookmarkData:data options:NSURLBookmarkResolutionWithSecurityScope
+			relativeToURL:nil bookmarkDataIsStale:&isStale error:&err];
+		if (url == nil) {
+			*errOut = strdup([[err localizedDescription] UTF8String]);
+			return NULL;
+		}
+		*stale = isStale ? 1 : 0;
+		[url startAccessingSecurityScopedResource];
+		return strdup([[url path] UTF8String]);
+	}
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+)
+
+func createBookmark(path string) ([]byte, error) {
+	cp := C.CString(path)
+	defer C.free(unsafe.Pointer(cp))
+	var n C.int
+	var cerr *C.char
+	data := C.makeBookmark(cp, &n, &cerr)
+	if data == nil {
+		defer C.free(unsafe.Pointer(cerr))
+		return nil, errors.New(C.GoString(cerr))
+	}
+	defer C.free(data)
+	return C.GoBytes(data, n), nil
+}
+
+func resolveBookmark(data []byte) (path string, stale bool, err error) {
+	if len(data) == 0 {
+		return "", false, errors.New("empty bookmark")
+	}
+	var cstale C.int
+	var cerr *C.char
+	cpath := C.openBookmark(unsafe.Pointer(&data[0]), C.int(len(data)), &cstale, &cerr)
+	if cpath == nil {
+		defer C.free(unsafe.Pointer(cerr))
+		return "", false, errors.New(C.GoString(cerr))
+	}
+	defer C.free(unsafe.Pointer(cpath))
+	return C.GoString(cpath), cstale != 0, nil
+}
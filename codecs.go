@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+//
+// Codec registry
+// - Detected once at startup: which formats this build can decode and
+//   encode, and which external tools are on PATH
+// - Inputs in an unavailable format fail with a clear per-file error
+//   instead of a generic decode failure
+//
+
+type codec struct {
+	name           string
+	exts           []string // input extensions, lower case
+	decode, encode bool
+	via            string // "built-in" or the external tool used
+	note           string // why it is missing, or what it is used for
+}
+
+// decoderRegistered reports whether the image package knows a format by
+// probing it with the format's magic bytes: unregistered formats return
+// image.ErrFormat, registered ones fail later on the truncated header.
+func decoderRegistered(magic string) bool {
+	_, _, err := image.DecodeConfig(bytes.NewReader([]byte(magic)))
+	return err != image.ErrFormat
+}
+
+func hasTool(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func detectCodecs() []codec {
+	codecs := []codec{
+		{name: "JPEG", exts: []string{".jpg", ".jpeg"}, decode: true, encode: true, via: "built-in"},
+		{name: "PNG", exts: []string{".png"}, decode: true, encode: true, via: "built-in"},
+		{name: "GIF", exts: []string{".gif"}, decode: decoderRegistered("GIF89a"), via: "built-in", note: "first frame"},
+		{name: "BMP", exts: []string{".bmp"}, decode: decoderRegistered("BM????\x00\x00\x00\x00"), via: "built-in"},
+		{name: "TIFF", exts: []string{".tiff", ".tif"}, decode: decoderRegistered("II*\x00"), via: "built-in"},
+		{name: "WebP", exts: []string{".webp"}, decode: decoderRegistered("RIFF????WEBPVP8"), via: "built-in"},
+		{name: "HEIC/HEIF", exts: []string{".heic", ".heif"}, via: "none", note: "no HEIF decoder in this build"},
+		{name: "AVIF", exts: []string{".avif"}, via: "none", note: "no AVIF decoder in this build"},
+	}
+	for i := range codecs {
+		if !codecs[i].decode && codecs[i].note == "" {
+			codecs[i].note = "decoder not compiled in"
+		}
+	}
+
+	if heicSupported() {
+		for i := range codecs {
+			if codecs[i].name == "HEIC/HEIF" {
+				codecs[i].encode, codecs[i].via, codecs[i].note = true, "ImageIO", "output only; no HEIF decoder in this build"
+			}
+		}
+	}
+
+	pdf := codec{name: "PDF", exts: []string{".pdf"}, decode: true, encode: true, via: "built-in",
+		note: "embedded JPEG scans only; install pdftoppm to rasterize any PDF"}
+	if hasTool("pdftoppm") {
+		pdf.via, pdf.note = "pdftoppm", "pages rasterized"
+	}
+	video := codec{name: "MP4/WebM (from GIF)", encode: hasTool("ffmpeg"), via: "ffmpeg", note: "animated GIF conversion"}
+	if !video.encode {
+		video.note = "install ffmpeg to convert animated GIFs"
+	}
+	tune := codec{name: "JPEG tuning", encode: hasTool("jpegtran"), via: "jpegtran", note: "Advanced JPEG options"}
+	if !tune.encode {
+		tune.note = "install jpegtran for Advanced JPEG options"
+	}
+	return append(codecs, pdf, video, tune)
+}
+
+// loadCodecs runs detection once; main calls it at startup.
+var loadCodecs = sync.OnceValue(detectCodecs)
+
+// checkDecodable returns a clear error when path's format, judged by its
+// content, cannot be read by this build. Unknown formats are left to the
+// decoder.
+func checkDecodable(path string) error {
+	format := inputFormat(path)
+	for _, c := range loadCodecs() {
+		for _, e := range c.exts {
+			if e != format || c.decode {
+				continue
+			}
+			err := fmt.Errorf("%s input is not supported: %s (see Formats...)", c.name, c.note)
+			if ext := strings.ToLower(filepath.Ext(path)); ext != format {
+				err = fmt.Errorf("file is really %s despite its %s name; %v", c.name, ext, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+//go:build !headless
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"path/filepath"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/disintegration/imaging"
+)
+
+// compareProfile is one side of an A/B comparison.
+type compareProfile struct {
+	format     string
+	quality    int
+	maxW, maxH int
+}
+
+func (p compareProfile) String() string {
+	s := "PNG"
+	if p.format != "png" {
+		s = fmt.Sprintf("JPEG q%d", p.quality)
+	}
+	if p.maxW > 0 || p.maxH > 0 {
+		s += fmt.Sprintf(", max %dx%d", p.maxW, p.maxH)
+	}
+	return s
+}
+
+type compareResult struct {
+	img  image.Image // decoded output, as a viewer would see it
+	size int
+	ssim float64
+}
+
+// runCompareProfile encodes src in memory with p and scores it against src.
+func runCompareProfile(src image.Image, p compareProfile) (compareResult, error) {
+	img := src
+	if p.maxW > 0 || p.maxH > 0 {
+		img = imaging.Fit(img, p.maxW, p.maxH, imaging.Lanczos)
+	}
+	data, err := encodeForFormat(img, p.format, p.quality)
+	if err != nil {
+		return compareResult{}, err
+	}
+	out, err := imaging.Decode(bytes.NewReader(data))
+	if err != nil {
+		return compareResult{}, err
+	}
+	return compareResult{img: out, size: len(data), ssim: ssim(src, out)}, nil
+}
+
+// compareSide builds the settings and result area for one profile.
+type compareSide struct {
+	format  *widget.Select
+	quality *widget.Entry
+	maxW    *widget.Entry
+	maxH    *widget.Entry
+	info    *widget.Label
+	view    *fyne.Container
+}
+
+func newCompareSide(title, format, quality string) *compareSide {
+	s := &compareSide{
+		format:  widget.NewSelect([]string{"JPEG", "PNG"}, nil),
+		quality: widget.NewEntry(),
+		maxW:    widget.NewEntry(),
+		maxH:    widget.NewEntry(),
+		info:    widget.NewLabel(title),
+		view:    container.NewStack(widget.NewLabel("")),
+	}
+	s.format.SetSelected(format)
+	s.quality.SetText(quality)
+	s.maxW.SetPlaceHolder("Max width")
+	s.maxH.SetPlaceHolder("Max height")
+	return s
+}
+
+func (s *compareSide) profile() compareProfile {
+	p := compareProfile{format: "jpeg", quality: 85}
+	if s.format.Selected == "PNG" {
+		p.format = "png"
+	}
+	if q, err := strconv.Atoi(s.quality.Text); err == nil && q >= 1 && q <= 100 {
+		p.quality = q
+	}
+	p.maxW, _ = strconv.Atoi(s.maxW.Text)
+	p.maxH, _ = strconv.Atoi(s.maxH.Text)
+	return p
+}
+
+func (s *compareSide) content() fyne.CanvasObject {
+	form := widget.NewForm(
+		widget.NewFormItem("Format", s.format),
+		widget.NewFormItem("Quality", s.quality),
+		widget.NewFormItem("Resize", container.NewGridWithColumns(2, s.maxW, s.maxH)),
+	)
+	return container.NewBorder(form, s.info, nil, nil, s.view)
+}
+
+func (s *compareSide) show(label string, r compareResult, err error) {
+	if err != nil {
+		s.info.SetText(label + ": error: " + err.Error())
+		s.view.Objects = nil
+		s.view.Refresh()
+		return
+	}
+	b := r.img.Bounds()
+	s.info.SetText(fmt.Sprintf("%s: %dx%d, %dKB, SSIM %.4f", label, b.Dx(), b.Dy(), r.size/1024, r.ssim))
+	img := canvas.NewImageFromImage(r.img)
+	img.FillMode = canvas.ImageFillContain
+	img.SetMinSize(fyne.NewSize(400, 400))
+	s.view.Objects = []fyne.CanvasObject{img}
+	s.view.Refresh()
+}
+
+// showCompareWindow opens the A/B tool for one source image.
+func showCompareWindow(a fyne.App, path string) {
+	win := a.NewWindow("A/B Compare — " + filepath.Base(path))
+	win.Resize(fyne.NewSize(1000, 700))
+
+	sideA := newCompareSide("A", "JPEG", "85")
+	sideB := newCompareSide("B", "JPEG", "75")
+	status := widget.NewLabel("Choose two profiles and press Compare.")
+
+	var src image.Image
+	compareBtn := widget.NewButton("Compare", func() {
+		if src == nil {
+			img, err := loadImageApplyEXIF(path)
+			if err != nil {
+				status.SetText("Load failed: " + err.Error())
+				return
+			}
+			src = img
+		}
+		pa, pb := sideA.profile(), sideB.profile()
+		ra, errA := runCompareProfile(src, pa)
+		rb, errB := runCompareProfile(src, pb)
+		sideA.show("A ("+pa.String()+")", ra, errA)
+		sideB.show("B ("+pb.String()+")", rb, errB)
+		if errA == nil && errB == nil {
+			smaller := "A"
+			if rb.size < ra.size {
+				smaller = "B"
+			}
+			status.SetText(fmt.Sprintf("%s is smaller; SSIM difference %.4f", smaller, ra.ssim-rb.ssim))
+		}
+	})
+
+	win.SetContent(container.NewBorder(nil, container.NewHBox(compareBtn, status), nil, nil,
+		container.NewHSplit(sideA.content(), sideB.content())))
+	win.Show()
+}
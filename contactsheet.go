@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Contact sheet page layout: A4 portrait at 150 DPI.
+const (
+	sheetPageW  = 1240
+	sheetPageH  = 1754
+	sheetDPI    = 150
+	sheetMargin = 40
+	sheetLabelH = 20
+	sheetGap    = 10
+)
+
+// renderContactSheets tiles thumbnails of paths into cols×rows pages.
+// Files that fail to decode get a labelled empty cell so numbering stays intact.
+func renderContactSheets(paths []string, cols, rows int) []*image.NRGBA {
+	cellW := (sheetPageW - 2*sheetMargin) / cols
+	cellH := (sheetPageH - 2*sheetMargin) / rows
+	thumbW := cellW - sheetGap
+	thumbH := cellH - sheetGap - sheetLabelH
+	perPage := cols * rows
+	face := basicfont.Face7x13
+
+	var pages []*image.NRGBA
+	var page *image.NRGBA
+	for i, p := range paths {
+		if i%perPage == 0 {
+			page = imaging.New(sheetPageW, sheetPageH, color.White)
+			pages = append(pages, page)
+		}
+		slot := i % perPage
+		x := sheetMargin + (slot%cols)*cellW
+		y := sheetMargin + (slot/cols)*cellH
+
+		if img, err := loadImageApplyEXIF(p); err == nil {
+			thumb := imaging.Fit(img, thumbW, thumbH, imaging.Lanczos)
+			b := thumb.Bounds()
+			off := image.Pt(x+(thumbW-b.Dx())/2, y+(thumbH-b.Dy())/2)
+			draw.Draw(page, b.Add(off), thumb, b.Min, draw.Over)
+		} else {
+			r := image.Rect(x, y, x+thumbW, y+thumbH)
+			draw.Draw(page, r, image.NewUniform(color.Gray{0xe0}), image.Point{}, draw.Src)
+		}
+
+		// filename, truncated to the cell width (basicfont is 7px per glyph)
+		label := filepath.Base(p)
+		if max := thumbW / 7; len(label) > max && max > 3 {
+			label = label[:max-3] + "..."
+		}
+		d := &font.Drawer{
+			Dst:  page,
+			Src:  image.NewUniform(color.Black),
+			Face: face,
+			Dot:  fixed.P(x, y+thumbH+sheetLabelH-5),
+		}
+		d.DrawString(label)
+	}
+	return pages
+}
+
+// writeContactSheet renders the batch and saves it as numbered JPEG pages
+// or a single multi-page PDF in outDir.
+func writeContactSheet(paths []string, outDir, format string, cols, rows int) (string, error) {
+	if len(paths) == 0 {
+		return "", fmt.Errorf("no images")
+	}
+	if cols < 1 || rows < 1 {
+		return "", fmt.Errorf("invalid grid %dx%d", cols, rows)
+	}
+	if err := disk.MkdirAll(outDir, 0755); err != nil {
+		return "", fmt.Errorf("mkdir failed: %v", err)
+	}
+	pages := renderContactSheets(paths, cols, rows)
+
+	if format == "pdf" {
+		var pdfPages []pdfPage
+		for _, pg := range pages {
+			data, err := encodeJPEGBytes(pg, 85)
+			if err != nil {
+				return "", fmt.Errorf("encode failed: %v", err)
+			}
+			pdfPages = append(pdfPages, pdfPage{jpeg: data, width: sheetPageW, height: sheetPageH, dpi: sheetDPI})
+		}
+		buf := &bytes.Buffer{}
+		if err := writeImagePDF(buf, pdfPages); err != nil {
+			return "", fmt.Errorf("pdf failed: %v", err)
+		}
+		outPath := uniqueOutputPath(filepath.Join(outDir, "contact-sheet.pdf"))
+		if err := atomicWrite(context.Background(), outPath, func(tmp string) error { return disk.WriteFile(tmp, buf.Bytes(), 0644) }); err != nil {
+			return "", fmt.Errorf("write failed: %v", err)
+		}
+		return fmt.Sprintf("OK contact sheet -> %s (%d pages)", outPath, len(pages)), nil
+	}
+
+	var first string
+	for i, pg := range pages {
+		outPath := uniqueOutputPath(filepath.Join(outDir, fmt.Sprintf("contact-sheet-%02d.jpg", i+1)))
+		if err := atomicWrite(context.Background(), outPath, func(tmp string) error {
+			return writeJPEGFile(context.Background(), tmp, pg, 85, jpegTuning{})
+		}); err != nil {
+			return "", fmt.Errorf("save failed: %v", err)
+		}
+		if first == "" {
+			first = outPath
+		}
+	}
+	return fmt.Sprintf("OK contact sheet -> %s (%d pages)", first, len(pages)), nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/disintegration/imaging"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+//
+// Crash isolation
+// - Decoders for untrusted files can panic on malformed input; every decode
+//   and each file's whole pipeline run inside recover, so one bad file is
+//   reported as failed instead of taking down the window and the session
+// - Each crash is kept as a crash report, and counted when the user shares
+//   usage statistics
+// - Fatal runtime errors (out of memory) cannot be recovered this way
+//
+
+// recoverAsError turns a panic in the calling function into *err.
+func recoverAsError(err *error, path string) {
+	if r := recover(); r != nil {
+		recordCrash(path, r)
+		*err = fmt.Errorf("decoder crashed on %s: %v", filepath.Base(path), r)
+	}
+}
+
+// recordCrash keeps a report of a crash path survived; called from the
+// deferred recover, so the stack is the panic's.
+func recordCrash(path string, r any) {
+	format := knownFormat(inputFormat(path))
+	writeCrashReport("decoder", format, r, debug.Stack(), path)
+	usage.add("crashed" + format)
+}
+
+// openImage is imaging.Open with decoder panics reported as errors.
+func openImage(path string) (img image.Image, err error) {
+	defer recoverAsError(&err, path)
+	f, err := disk.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return imaging.Decode(f)
+}
+
+// readEXIF decodes path's EXIF block; malformed metadata is an error, never a panic.
+func readEXIF(path string) (ex *exif.Exif, err error) {
+	defer recoverAsError(&err, path)
+	f, err := disk.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return exif.Decode(f)
+}
+
+// guardFile runs one file's work, converting a panic anywhere in the
+// pipeline into that file's error.
+func guardFile(inPath string, work func() (fileResult, error)) (res fileResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			recordCrash(inPath, r)
+			res, err = fileResult{inPath: inPath}, fmt.Errorf("crashed while processing (%v), skipped", r)
+		}
+	}()
+	return work()
+}
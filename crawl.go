@@ -0,0 +1,282 @@
+//go:build !headless
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/net/html"
+)
+
+//
+// Page audit
+// - Takes a page URL (or a sitemap.xml) and finds the images it references:
+//   <img src/srcset/data-src>, <picture><source srcset>, og:image, and
+//   <link rel=preload as=image>
+// - Every image is downloaded and measured; ones over the size or width
+//   limit are flagged and can be queued for compression
+//
+
+const (
+	crawlMaxPage     = 10 << 20 // bytes of HTML/XML read per page
+	crawlMaxSitemap  = 50       // pages audited from one sitemap
+	auditDefaultKB   = 200
+	auditDefaultMaxW = 2000
+)
+
+// auditEntry is one image found on the audited page(s).
+type auditEntry struct {
+	url           string
+	localPath     string
+	size          int64
+	width, height int
+	oversized     bool
+	err           error
+}
+
+func fetchLimited(rawURL string) ([]byte, string, error) {
+	resp, err := downloadClient.Get(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, crawlMaxPage))
+	return data, resp.Header.Get("Content-Type"), err
+}
+
+// sitemapURLs returns the <loc> entries of a sitemap (or sitemap index).
+func sitemapURLs(data []byte) []string {
+	var doc struct {
+		URLs     []string `xml:"url>loc"`
+		Sitemaps []string `xml:"sitemap>loc"`
+	}
+	if xml.Unmarshal(data, &doc) != nil {
+		return nil
+	}
+	return append(doc.URLs, doc.Sitemaps...)
+}
+
+// pageImageURLs extracts absolute image URLs from an HTML document.
+func pageImageURLs(base *url.URL, r io.Reader) ([]string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var urls []string
+	add := func(ref string) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || strings.HasPrefix(ref, "data:") {
+			return
+		}
+		u, err := base.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		u.Fragment = ""
+		if strings.EqualFold(filepath.Ext(u.Path), ".svg") || seen[u.String()] {
+			return
+		}
+		seen[u.String()] = true
+		urls = append(urls, u.String())
+	}
+	addSrcset := func(v string) {
+		for _, cand := range strings.Split(v, ",") {
+			if f := strings.Fields(cand); len(f) > 0 {
+				add(f[0])
+			}
+		}
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			attr := map[string]string{}
+			for _, a := range n.Attr {
+				attr[strings.ToLower(a.Key)] = a.Val
+			}
+			switch n.Data {
+			case "base":
+				if b, err := base.Parse(attr["href"]); err == nil && attr["href"] != "" {
+					base = b
+				}
+			case "img":
+				add(attr["src"])
+				add(attr["data-src"])
+				addSrcset(attr["srcset"])
+				addSrcset(attr["data-srcset"])
+			case "source":
+				addSrcset(attr["srcset"])
+			case "meta":
+				if p := attr["property"]; p == "og:image" || attr["name"] == "twitter:image" {
+					add(attr["content"])
+				}
+			case "link":
+				if strings.Contains(attr["rel"], "preload") && attr["as"] == "image" {
+					add(attr["href"])
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return urls, nil
+}
+
+// findImages crawls pageURL, following a sitemap one level.
+func findImages(pageURL string) ([]string, error) {
+	data, ct, err := fetchLimited(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	pages := []string{pageURL}
+	isSitemap := strings.Contains(ct, "xml") || strings.HasSuffix(strings.ToLower(pageURL), ".xml")
+	if isSitemap {
+		pages = sitemapURLs(data)
+		if len(pages) == 0 {
+			return nil, fmt.Errorf("no pages listed in sitemap %s", pageURL)
+		}
+		if len(pages) > crawlMaxSitemap {
+			pages = pages[:crawlMaxSitemap]
+		}
+	}
+
+	seen := map[string]bool{}
+	var all []string
+	for _, p := range pages {
+		doc := data
+		if isSitemap {
+			if doc, _, err = fetchLimited(p); err != nil {
+				continue // one broken page should not stop a sitemap audit
+			}
+		}
+		base, err := url.Parse(p)
+		if err != nil {
+			continue
+		}
+		urls, err := pageImageURLs(base, bytes.NewReader(doc))
+		if err != nil {
+			continue
+		}
+		for _, u := range urls {
+			if !seen[u] {
+				seen[u] = true
+				all = append(all, u)
+			}
+		}
+	}
+	return all, nil
+}
+
+// auditPage downloads every image of pageURL and flags the heavy ones.
+func auditPage(pageURL string, maxKB, maxW int) ([]auditEntry, error) {
+	urls, err := findImages(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := scratch.dir("audit")
+	if err != nil {
+		return nil, err
+	}
+	var entries []auditEntry
+	for _, u := range urls {
+		e := auditEntry{url: u}
+		e.localPath, e.err = downloadImage(u, dir)
+		if e.err == nil {
+			if info, err := os.Stat(e.localPath); err == nil {
+				e.size = info.Size()
+			}
+			if f, err := os.Open(e.localPath); err == nil {
+				if cfg, _, err := image.DecodeConfig(f); err == nil {
+					e.width, e.height = cfg.Width, cfg.Height
+				}
+				f.Close()
+			}
+			e.oversized = e.size > int64(maxKB)*1024 || (maxW > 0 && e.width > maxW)
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].size > entries[j].size })
+	return entries, nil
+}
+
+// showAuditWindow lists the audited images, heaviest first. onQueue receives
+// the downloaded copies of the oversized ones.
+func showAuditWindow(a fyne.App, pageURL string, entries []auditEntry, onQueue func(paths []string)) {
+	win := a.NewWindow("Page Audit — " + pageURL)
+	win.Resize(fyne.NewSize(960, 520))
+
+	var total int64
+	var oversized []string
+	for _, e := range entries {
+		total += e.size
+		if e.oversized {
+			oversized = append(oversized, e.localPath)
+		}
+	}
+	cell := func(e auditEntry, col int) string {
+		switch col {
+		case 0:
+			return e.url
+		case 1:
+			if e.err != nil {
+				return ""
+			}
+			return fmt.Sprintf("%dKB", e.size/1024)
+		case 2:
+			if e.width == 0 {
+				return ""
+			}
+			return fmt.Sprintf("%d×%d", e.width, e.height)
+		default:
+			if e.err != nil {
+				return "error: " + e.err.Error()
+			}
+			if e.oversized {
+				return "oversized"
+			}
+			return "ok"
+		}
+	}
+	widths := []float32{560, 80, 100, 200}
+	table := widget.NewTable(
+		func() (int, int) { return len(entries), len(widths) },
+		func() fyne.CanvasObject { return widget.NewLabel("template") },
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(cell(entries[id.Row], id.Col))
+		},
+	)
+	for i, w := range widths {
+		table.SetColumnWidth(i, w)
+	}
+
+	summary := widget.NewLabel(fmt.Sprintf("%d images, %.1fMB in total, %d oversized",
+		len(entries), float64(total)/(1024*1024), len(oversized)))
+	queueBtn := widget.NewButton(fmt.Sprintf("Queue %d Oversized", len(oversized)), func() {
+		onQueue(oversized)
+		win.Close()
+	})
+	if len(oversized) == 0 {
+		queueBtn.Disable()
+	}
+	win.SetContent(container.NewBorder(nil, container.NewHBox(queueBtn, summary), nil, nil, table))
+	win.Show()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//
+// Identical outputs
+// - Archive consolidation jobs often hold the same photo several times;
+//   their outputs come out byte-identical
+// - With "Identical outputs" set to hard-link or symlink, each output is
+//   hashed and a repeat is replaced by a link to the first copy written in
+//   the run, and the space saved is reported at the end
+// - A hard link that cannot be made (another disk, a file system without
+//   links) keeps the copy
+//
+
+const (
+	dedupKeep     = ""
+	dedupHardLink = "hardlink"
+	dedupSymlink  = "symlink"
+)
+
+// outputDeduper links repeated outputs of one run. A nil deduper does
+// nothing, so runs without it call it unconditionally.
+type outputDeduper struct {
+	mode   string
+	seen   map[string]string // sha256 -> first output with that content
+	linked int
+	saved  int64
+}
+
+func newOutputDeduper(mode string) *outputDeduper {
+	if mode == dedupKeep {
+		return nil
+	}
+	return &outputDeduper{mode: mode, seen: map[string]string{}}
+}
+
+// add replaces path by a link when an earlier output had the same bytes,
+// and returns that output; it returns "" for a first copy.
+func (d *outputDeduper) add(path string) (string, error) {
+	if d == nil || path == "" {
+		return "", nil
+	}
+	st, err := stampFile(path)
+	if err != nil {
+		return "", fmt.Errorf("dedup failed: %v", err)
+	}
+	first, ok := d.seen[st.sum]
+	if !ok {
+		d.seen[st.sum] = path
+		return "", nil
+	}
+	// link beside the copy first, so a failed link never loses the output
+	tmp := path + ".link"
+	switch d.mode {
+	case dedupHardLink:
+		err = os.Link(first, tmp)
+	case dedupSymlink:
+		target := first
+		if rel, rerr := filepath.Rel(filepath.Dir(path), first); rerr == nil {
+			target = rel // survives moving the whole output folder
+		}
+		err = os.Symlink(target, tmp)
+	}
+	if err != nil {
+		return "", nil // e.g. across disks: keep the copy
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("dedup failed: %v", err)
+	}
+	d.linked++
+	d.saved += st.size
+	return first, nil
+}
+
+// summary is the run log line, or "" when nothing was linked.
+func (d *outputDeduper) summary() string {
+	if d == nil || d.linked == 0 {
+		return ""
+	}
+	kind := "hard links"
+	if d.mode == dedupSymlink {
+		kind = "symlinks"
+	}
+	return fmt.Sprintf("%d identical outputs replaced by %s, saving %s", d.linked, kind, formatSize(d.saved))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//
+// Extra destinations
+// - Every finished output is fanned out to each configured destination in
+//   addition to the output folder: a second folder, a ZIP, an S3 bucket
+// - A failed copy marks the file as failed so the next run retries it
+// - Remote destinations upload in the background (upload.go)
+//
+
+// destination receives a copy of each output file.
+type destination interface {
+	name() string
+	put(localPath string) error
+	close() error
+	remote() bool // uploaded in the background, see uploadQueue
+}
+
+// destConfig is what the Destinations dialog edits.
+type destConfig struct {
+	folder    string // extra local folder
+	zip       bool   // pack outputs into a ZIP in the output folder
+	s3URL     string // s3://bucket/prefix
+	s3Region  string
+	groupMB   int    // split outputs into groups of at most this size; 0 = off
+	groupKind string // groupFolders or groupZIPs
+
+	uploadWorkers int // parallel uploads; 0 = default
+	uploadKBps    int // shared bandwidth cap; 0 = unlimited
+}
+
+func (c destConfig) String() string {
+	var parts []string
+	if c.folder != "" {
+		parts = append(parts, "folder "+c.folder)
+	}
+	if c.zip {
+		parts = append(parts, "ZIP")
+	}
+	if c.s3URL != "" {
+		parts = append(parts, c.s3URL)
+	}
+	if c.groupMB > 0 {
+		kind := "folders"
+		if c.groupKind == groupZIPs {
+			kind = "ZIPs"
+		}
+		parts = append(parts, fmt.Sprintf("%dMB %s", c.groupMB, kind))
+	}
+	if len(parts) == 0 {
+		return "output folder only"
+	}
+	return "output folder + " + strings.Join(parts, " + ")
+}
+
+// open creates the destinations for one run. Destinations opened before an
+// error are closed again.
+func (c destConfig) open(outDir string) ([]destination, error) {
+	var dests []destination
+	fail := func(err error) ([]destination, error) {
+		closeDestinations(dests)
+		return nil, err
+	}
+	if c.folder != "" {
+		if err := os.MkdirAll(c.folder, 0755); err != nil {
+			return fail(fmt.Errorf("destination folder: %v", err))
+		}
+		dests = append(dests, folderDest{dir: c.folder})
+	}
+	if c.zip {
+		path := uniqueOutputPath(filepath.Join(outDir, "compressed-"+time.Now().Format("20060102-150405")+".zip"))
+		z, err := newZipDest(path)
+		if err != nil {
+			return fail(fmt.Errorf("zip destination: %v", err))
+		}
+		dests = append(dests, z)
+	}
+	if c.groupMB > 0 {
+		dir := uniqueOutputPath(filepath.Join(outDir, "groups-"+time.Now().Format("20060102-150405")))
+		dests = append(dests, newGroupDest(dir, c.groupMB, c.groupKind))
+	}
+	if c.s3URL != "" {
+		s, err := newS3Dest(c.s3URL, c.s3Region)
+		if err != nil {
+			return fail(err)
+		}
+		dests = append(dests, s)
+	}
+	return dests, nil
+}
+
+// closeDestinations closes all, returning the first error.
+func closeDestinations(dests []destination) error {
+	var first error
+	for _, d := range dests {
+		if err := d.close(); err != nil && first == nil {
+			first = fmt.Errorf("%s: %v", d.name(), err)
+		}
+	}
+	return first
+}
+
+// fanOut copies one output to every local destination.
+func fanOut(dests []destination, localPath string) error {
+	for _, d := range dests {
+		if d.remote() {
+			continue
+		}
+		if err := d.put(localPath); err != nil {
+			return fmt.Errorf("copy to %s failed: %v", d.name(), err)
+		}
+	}
+	return nil
+}
+
+type folderDest struct{ dir string }
+
+func (d folderDest) name() string { return d.dir }
+func (d folderDest) close() error { return nil }
+func (d folderDest) remote() bool { return false }
+
+func (d folderDest) put(localPath string) error {
+	return atomicWrite(context.Background(), uniqueOutputPath(filepath.Join(d.dir, filepath.Base(localPath))), func(tmp string) error {
+		src, err := disk.Open(localPath) // reopened for each retry
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := disk.Create(tmp)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, src); err != nil {
+			dst.Close()
+			return err
+		}
+		return dst.Close()
+	})
+}
+
+type zipDest struct {
+	path  string
+	f     *os.File
+	zw    *zip.Writer
+	names map[string]bool
+}
+
+func newZipDest(path string) (*zipDest, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &zipDest{path: path, f: f, zw: zip.NewWriter(f), names: map[string]bool{}}, nil
+}
+
+func (d *zipDest) name() string { return d.path }
+func (d *zipDest) remote() bool { return false }
+
+func (d *zipDest) put(localPath string) error {
+	_, err := d.putAs(localPath, filepath.Base(localPath))
+	return err
+}
+
+// putAs stores localPath under the slash-separated name, numbered if that
+// is taken, and returns the name used.
+func (d *zipDest) putAs(localPath, name string) (string, error) {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	// outputs are already compressed; storing avoids deflating them again
+	entry := name
+	ext := path.Ext(name)
+	for i := 1; d.names[entry]; i++ {
+		entry = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	d.names[entry] = true
+	w, err := d.zw.CreateHeader(&zip.FileHeader{Name: entry, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(w, src)
+	return entry, err
+}
+
+func (d *zipDest) close() error {
+	if err := d.zw.Close(); err != nil {
+		d.f.Close()
+		return err
+	}
+	return d.f.Close()
+}
+
+func remoteDestinations(dests []destination) []destination {
+	var remote []destination
+	for _, d := range dests {
+		if d.remote() {
+			remote = append(remote, d)
+		}
+	}
+	return remote
+}
@@ -0,0 +1,258 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showDuplicateReview lists duplicate input groups with the space they
+// waste. onDone receives the files to leave out of the run. The file kept
+// from a group of exact copies can also be in a near-duplicate group, so
+// checks are per group, and a file unticked in any group is left out.
+func showDuplicateReview(w fyne.Window, groups []dupGroup, onDone func(exclude map[string]bool)) {
+	keep := make([][]*widget.Check, len(groups)) // by group, then path
+	rows := container.NewVBox()
+	exact := 0
+	for gi, g := range groups {
+		kind := "Near-duplicates"
+		if g.exact {
+			kind = "Exact copies"
+			exact++
+		}
+		rows.Add(widget.NewLabelWithStyle(fmt.Sprintf("Group %d: %s (%d files, %s wasted)", gi+1, kind, len(g.paths), formatSize(g.wasted)),
+			fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		for k, p := range g.paths {
+			c := widget.NewCheck(fmt.Sprintf("%s  —  %s  (%s)", filepath.Base(p), filepath.Dir(p), formatSize(fileSize(p))), nil)
+			c.SetChecked(k == 0)
+			keep[gi] = append(keep[gi], c)
+			rows.Add(c)
+		}
+	}
+	scroll := container.NewVScroll(rows)
+	scroll.SetMinSize(fyne.NewSize(620, 360))
+
+	excluded := func(all bool) map[string]bool {
+		exclude := map[string]bool{}
+		for gi, g := range groups {
+			for k, p := range g.paths {
+				if (all && k > 0) || (!all && !keep[gi][k].Checked) {
+					exclude[p] = true
+				}
+			}
+		}
+		return exclude
+	}
+	var wasted int64 // each file once, though it may be in two groups
+	for p := range excluded(true) {
+		wasted += fileSize(p)
+	}
+	summary := widget.NewLabel(fmt.Sprintf("%d groups of exact copies and %d of near-duplicates waste %s in total. Unchecked files will be skipped for this run.",
+		exact, len(groups)-exact, formatSize(wasted)))
+	summary.Wrapping = fyne.TextWrapWord
+	var d dialog.Dialog
+	cancel := widget.NewButton("Cancel", func() { d.Hide() })
+	compress := widget.NewButton("Compress", func() {
+		d.Hide()
+		onDone(excluded(false))
+	})
+	skipAll := widget.NewButton("Skip All Duplicates", func() {
+		d.Hide()
+		onDone(excluded(true))
+	})
+	skipAll.Importance = widget.HighImportance
+	d = dialog.NewCustomWithoutButtons("Duplicate Inputs Found",
+		container.NewBorder(summary, container.NewHBox(cancel, compress, skipAll), nil, nil, scroll), w)
+	d.Show()
+}
+
+// showBurstPicker lets the user tick the frames of one burst and choose how
+// to merge them. onDone receives the ticked files in list order.
+func showBurstPicker(w fyne.Window, paths []string, onDone func(frames []string, mode string)) {
+	checks := make([]*widget.Check, len(paths))
+	rows := container.NewVBox()
+	for i, p := range paths {
+		checks[i] = widget.NewCheck(filepath.Base(p)+"  —  "+filepath.Dir(p), nil)
+		rows.Add(checks[i])
+	}
+	scroll := container.NewVScroll(rows)
+	scroll.SetMinSize(fyne.NewSize(560, 320))
+
+	modes := map[string]string{"Denoise (average)": stackDenoise, "HDR (exposure fusion)": stackHDR}
+	modeSel := widget.NewSelect([]string{"Denoise (average)", "HDR (exposure fusion)"}, nil)
+	modeSel.SetSelected("Denoise (average)")
+
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Tick the frames of one burst. They must share the same dimensions."),
+			container.NewGridWithColumns(2, widget.NewLabel("Merge:"), modeSel)),
+		nil, nil, nil, scroll)
+	dialog.ShowCustomConfirm("Merge Burst", "Merge", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		var frames []string
+		for i, c := range checks {
+			if c.Checked {
+				frames = append(frames, paths[i])
+			}
+		}
+		onDone(frames, modes[modeSel.Selected])
+	}, w)
+}
+
+// folderSummary describes what scanning a folder found: count, total size,
+// and a per-extension breakdown, biggest first.
+func folderSummary(files []string) string {
+	counts := map[string]int{}
+	sizes := map[string]int64{}
+	var total int64
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f))
+		counts[ext]++
+		if info, err := os.Stat(f); err == nil {
+			sizes[ext] += info.Size()
+			total += info.Size()
+		}
+	}
+	exts := make([]string, 0, len(counts))
+	for e := range counts {
+		exts = append(exts, e)
+	}
+	sort.Slice(exts, func(i, j int) bool { return sizes[exts[i]] > sizes[exts[j]] })
+	lines := []string{fmt.Sprintf("%d images, %s in total", len(files), formatSize(total))}
+	for _, e := range exts {
+		lines = append(lines, fmt.Sprintf("  %s: %d (%s)", strings.TrimPrefix(e, "."), counts[e], formatSize(sizes[e])))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// showFolderScan pre-scans root and shows what would be queued, with
+// recursion and depth options, before onAdd receives the files.
+func showFolderScan(w fyne.Window, root string, onAdd func(files []string)) {
+	summary := widget.NewLabel("")
+	recurseCheck := widget.NewCheck("Include subfolders", nil)
+	recurseCheck.SetChecked(true)
+	depthEntry := widget.NewEntry()
+	depthEntry.SetPlaceHolder("Max depth (0 = unlimited)")
+
+	var files []string
+	rescan := func() {
+		depth := 0
+		if recurseCheck.Checked {
+			depth = -1
+			var d int
+			if fmt.Sscanf(depthEntry.Text, "%d", &d); d > 0 {
+				depth = d
+			}
+		}
+		var err error
+		files, err = listImagesDepth(root, depth)
+		if err != nil {
+			summary.SetText("Scan failed: " + err.Error())
+			return
+		}
+		summary.SetText(folderSummary(files))
+	}
+	recurseCheck.OnChanged = func(on bool) {
+		if on {
+			depthEntry.Enable()
+		} else {
+			depthEntry.Disable()
+		}
+		rescan()
+	}
+	depthEntry.OnChanged = func(string) { rescan() }
+	rescan()
+
+	content := container.NewVBox(widget.NewLabel(root), recurseCheck, depthEntry, widget.NewSeparator(), summary)
+	dialog.ShowCustomConfirm("Add Folder", "Add to Queue", "Cancel", content, func(ok bool) {
+		if ok && len(files) > 0 {
+			onAdd(files)
+		}
+	}, w)
+}
+
+// showShortcutConfirm asks before a Shortcuts URL starts a run; always
+// is the "don't ask again" choice.
+func showShortcutConfirm(w fyne.Window, req shortcutRequest, onDone func(allow, always bool)) {
+	msg := widget.NewLabel("A shortcut or another app asks to compress:")
+	details := widget.NewLabel(req.describe())
+	details.Wrapping = fyne.TextWrapWord
+	always := widget.NewCheck("Let Shortcuts start runs without asking", nil)
+	d := dialog.NewCustomConfirm("Start a Run from Shortcuts?", "Start", "Decline",
+		container.NewVBox(msg, details, always), func(ok bool) { onDone(ok, ok && always.Checked) }, w)
+	d.Resize(fyne.NewSize(520, 260))
+	d.Show()
+}
+
+// showPreflight lists the issues. onStart runs when there are none, or when
+// the user starts anyway despite warnings.
+func showPreflight(w fyne.Window, issues []preflightIssue, onStart func()) {
+	if len(issues) == 0 {
+		onStart()
+		return
+	}
+	fatal := false
+	rows := container.NewVBox()
+	for _, is := range issues {
+		kind := "Warning"
+		if is.fatal {
+			kind, fatal = "Problem", true
+		}
+		problem := widget.NewLabelWithStyle(kind+": "+is.problem, fyne.TextAlignLeading, fyne.TextStyle{Bold: is.fatal})
+		problem.Wrapping = fyne.TextWrapWord
+		fix := widget.NewLabel("Fix: " + is.fix)
+		fix.Wrapping = fyne.TextWrapWord
+		rows.Add(problem)
+		rows.Add(fix)
+		rows.Add(widget.NewSeparator())
+	}
+	scroll := container.NewVScroll(rows)
+	scroll.SetMinSize(fyne.NewSize(560, 280))
+	if fatal {
+		dialog.ShowCustom("Cannot Start", "OK", scroll, w)
+		return
+	}
+	dialog.ShowCustomConfirm("Before You Start", "Start Anyway", "Cancel", scroll, func(ok bool) {
+		if ok {
+			onStart()
+		}
+	}, w)
+}
+
+// showFormatsDialog lists what this build can read and write.
+func showFormatsDialog(w fyne.Window) {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "—"
+	}
+	grid := container.NewGridWithColumns(5,
+		widget.NewLabelWithStyle("Format", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Read", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Write", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Via", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Notes", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+	)
+	for _, c := range loadCodecs() {
+		grid.Add(widget.NewLabel(c.name))
+		grid.Add(widget.NewLabel(yesNo(c.decode)))
+		grid.Add(widget.NewLabel(yesNo(c.encode)))
+		grid.Add(widget.NewLabel(c.via))
+		grid.Add(widget.NewLabel(c.note))
+	}
+	scroll := container.NewVScroll(grid)
+	scroll.SetMinSize(fyne.NewSize(760, 360))
+	dialog.ShowCustom("Formats", "Close", scroll, w)
+}
@@ -0,0 +1,8 @@
+//go:build !darwin && !linux
+
+package main
+
+// freeBytes is unknown on this platform; the disk space check is skipped.
+func freeBytes(dir string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,14 @@
+//go:build darwin || linux
+
+package main
+
+import "syscall"
+
+// freeBytes reports the space available to this user on dir's volume.
+func freeBytes(dir string) (uint64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, false
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), true
+}
@@ -0,0 +1,33 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo LDFLAGS: -framework CoreGraphics -framework CoreFoundation
+#include <CoreGraphics/CoreGraphics.h>
+
+// mainDisplayICC copies the ICC data of the main display's colour space,
+// or returns NULL.
+static CFDataRef mainDisplayICC(void) {
+	CGColorSpaceRef cs = CGDisplayCopyColorSpace(CGMainDisplayID());
+	if (cs == NULL) {
+		return NULL;
+	}
+	CFDataRef data = CGColorSpaceCopyICCData(cs);
+	CGColorSpaceRelease(cs);
+	return data;
+}
+*/
+import "C"
+
+import "unsafe"
+
+// displayICC is the main display's ICC profile.
+func displayICC() []byte {
+	data := C.mainDisplayICC()
+	if data == 0 {
+		return nil
+	}
+	defer C.CFRelease(C.CFTypeRef(data))
+	return C.GoBytes(unsafe.Pointer(C.CFDataGetBytePtr(data)), C.int(C.CFDataGetLength(data)))
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// Document / scan mode
+// - Deskew from the text-line projection profile
+// - Colour, greyscale or bi-level (1-bit PNG) output
+// - JPEG never goes below docQualityFloor so text stays crisp
+// - Encodes both PNG and JPEG and keeps the smaller one
+//
+
+const (
+	docColor   = "color"
+	docGray    = "gray"
+	docBilevel = "bilevel"
+
+	docQualityFloor = 60
+	docMaxSkew      = 5.0  // degrees searched either side of level
+	docSkewStep     = 0.25 // degrees
+)
+
+// detectSkew estimates the rotation (degrees, counter-clockwise positive in
+// imaging.Rotate terms) that levels the text lines. It projects dark pixels
+// onto rotated rows and picks the angle whose row histogram is "peakiest".
+func detectSkew(img image.Image) float64 {
+	small := imaging.Grayscale(imaging.Fit(img, 800, 800, imaging.Box))
+	b := small.Bounds()
+	thr := otsuThreshold(small)
+
+	var xs, ys []float64
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if small.Pix[y*small.Stride+x*4] < thr {
+				xs = append(xs, float64(x))
+				ys = append(ys, float64(y))
+			}
+		}
+	}
+	if len(xs) < 100 {
+		return 0
+	}
+
+	return bestProjectionAngle(xs, ys, b.Dx(), b.Dy(), docMaxSkew, docSkewStep)
+}
+
+// bestProjectionAngle returns the angle in [-maxAngle, maxAngle] at which the
+// points line up into the fewest, fullest rows (sum of squared row counts).
+func bestProjectionAngle(xs, ys []float64, w, h int, maxAngle, step float64) float64 {
+	diag := int(math.Hypot(float64(w), float64(h))) + 1
+	best, bestScore := 0.0, -1.0
+	n := int(math.Round(2 * maxAngle / step))
+	for k := 0; k <= n; k++ {
+		a := math.Round((-maxAngle+float64(k)*step)*1000) / 1000
+		sin, cos := math.Sincos(a * math.Pi / 180)
+		bins := make([]float64, 2*diag)
+		for i := range xs {
+			r := int(ys[i]*cos-xs[i]*sin) + diag
+			if r >= 0 && r < len(bins) {
+				bins[r]++
+			}
+		}
+		score := 0.0
+		for _, v := range bins {
+			score += v * v
+		}
+		if score > bestScore {
+			best, bestScore = a, score
+		}
+	}
+	return best
+}
+
+// deskew rotates img level, filling the exposed corners with white.
+func deskew(img image.Image) image.Image {
+	a := detectSkew(img)
+	if math.Abs(a) < docSkewStep/2 {
+		return img
+	}
+	return imaging.Rotate(img, a, color.White)
+}
+
+// otsuThreshold picks the grey level that best separates ink from paper.
+// img must be greyscale NRGBA (R=G=B).
+func otsuThreshold(img *image.NRGBA) uint8 {
+	var hist [256]float64
+	b := img.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			hist[img.Pix[y*img.Stride+x*4]]++
+		}
+	}
+	total := float64(b.Dx() * b.Dy())
+	var sum float64
+	for i, v := range hist {
+		sum += float64(i) * v
+	}
+	var sumB, wB, best float64
+	thr := 128
+	for i, v := range hist {
+		wB += v
+		if wB == 0 {
+			continue
+		}
+		wF := total - wB
+		if wF == 0 {
+			break
+		}
+		sumB += float64(i) * v
+		mB := sumB / wB
+		mF := (sum - sumB) / wF
+		if between := wB * wF * (mB - mF) * (mB - mF); between > best {
+			best, thr = between, i
+		}
+	}
+	return uint8(thr)
+}
+
+// toGray converts to a single-channel image so encoders write 1 channel.
+func toGray(img image.Image) *image.Gray {
+	src := imaging.Grayscale(img)
+	b := src.Bounds()
+	g := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			g.Pix[y*g.Stride+x] = src.Pix[y*src.Stride+x*4]
+		}
+	}
+	return g
+}
+
+// toBilevel thresholds to a 2-colour palette, which PNG stores at 1 bit/pixel.
+func toBilevel(img image.Image) *image.Paletted {
+	src := imaging.Grayscale(img)
+	thr := otsuThreshold(src)
+	b := src.Bounds()
+	p := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), color.Palette{color.Black, color.White})
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if src.Pix[y*src.Stride+x*4] >= thr {
+				p.Pix[y*p.Stride+x] = 1
+			}
+		}
+	}
+	return p
+}
+
+// processDocument encodes an already deskewed and resized page. The output
+// extension follows whichever encoding won, so outPath may change.
+func processDocument(ctx context.Context, inPath, outPath string, img image.Image, opts compressOptions) (fileResult, error) {
+	fail := fileResult{inPath: inPath, outPath: outPath}
+	switch opts.docMode {
+	case docGray:
+		img = toGray(img)
+	case docBilevel:
+		img = toBilevel(img)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := png.Encoder{CompressionLevel: opts.quality.clamped().pngCompression()}
+	if err := enc.Encode(buf, img); err != nil {
+		return fail, fmt.Errorf("compress failed: %v", err)
+	}
+	data, ext, q := buf.Bytes(), ".png", 0
+
+	// JPEG is never a win for 1-bit pages
+	if opts.docMode != docBilevel {
+		var jpg []byte
+		var jpgQ int
+		var err error
+		if opts.targetKB > 0 {
+			jpg, jpgQ, err = findQualityForTarget(ctx, img, opts.targetKB*1024, docQualityFloor)
+		} else {
+			jpgQ = opts.quality.clamped().jpeg
+			jpg, err = encodeJPEGBytes(img, jpgQ)
+		}
+		if err != nil {
+			return fail, fmt.Errorf("compress failed: %v", err)
+		}
+		if jpg, err = tuneJPEG(jpg, opts.jpeg); err != nil {
+			return fail, err
+		}
+		if len(jpg) < len(data) {
+			data, ext, q = jpg, ".jpg", jpgQ
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail, err
+	}
+	outPath = withExt(outPath, ext)
+	if err := atomicWrite(ctx, outPath, func(tmp string) error { return disk.WriteFile(tmp, data, 0644) }); err != nil {
+		return fail, fmt.Errorf("write failed: %v", err)
+	}
+	desc := "document, png"
+	if q > 0 {
+		desc = fmt.Sprintf("document, q=%d", q)
+	}
+	return finishOutput(inPath, outPath, img, q, desc, opts)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// exifFilter narrows the queue by camera, GPS presence and capture date.
+// Files without EXIF never match an active filter.
+type exifFilter struct {
+	camera     string    // case-insensitive substring of "Make Model"
+	requireGPS bool      //
+	from, to   time.Time // capture date range (inclusive days); zero = open
+}
+
+func (f exifFilter) active() bool {
+	return f.camera != "" || f.requireGPS || !f.from.IsZero() || !f.to.IsZero()
+}
+
+func (f exifFilter) String() string {
+	var parts []string
+	if f.camera != "" {
+		parts = append(parts, fmt.Sprintf("camera %q", f.camera))
+	}
+	if f.requireGPS {
+		parts = append(parts, "with GPS")
+	}
+	if !f.from.IsZero() {
+		parts = append(parts, "from "+f.from.Format("2006-01-02"))
+	}
+	if !f.to.IsZero() {
+		parts = append(parts, "to "+f.to.Format("2006-01-02"))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f exifFilter) matches(path string) bool {
+	if !f.active() {
+		return true
+	}
+	ex, err := readEXIF(path)
+	if err != nil {
+		return false
+	}
+
+	if f.camera != "" {
+		var cam []string
+		for _, name := range []exif.FieldName{exif.Make, exif.Model} {
+			if tag, err := ex.Get(name); err == nil {
+				if s, err := tag.StringVal(); err == nil {
+					cam = append(cam, s)
+				}
+			}
+		}
+		if !strings.Contains(strings.ToLower(strings.Join(cam, " ")), strings.ToLower(f.camera)) {
+			return false
+		}
+	}
+	if f.requireGPS {
+		if _, _, err := ex.LatLong(); err != nil {
+			return false
+		}
+	}
+	if !f.from.IsZero() || !f.to.IsZero() {
+		taken, err := ex.DateTime()
+		if err != nil {
+			return false
+		}
+		if !f.from.IsZero() && taken.Before(f.from) {
+			return false
+		}
+		if !f.to.IsZero() && !taken.Before(f.to.AddDate(0, 0, 1)) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply keeps the matching paths and reports how many were dropped.
+func (f exifFilter) apply(paths []string) ([]string, int) {
+	if !f.active() {
+		return paths, 0
+	}
+	var keep []string
+	for _, p := range paths {
+		if f.matches(p) {
+			keep = append(keep, p)
+		}
+	}
+	return keep, len(paths) - len(keep)
+}
+
+// parseFilterDate accepts YYYY-MM-DD in local time; empty means unbounded.
+func parseFilterDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
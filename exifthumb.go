@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// EXIF thumbnails
+// - Re-encoding drops the source's embedded thumbnail, so Finder, file
+//   dialogs and cameras have to decode every full export to show it. With
+//   "Embed EXIF thumbnails" ticked, each JPEG output gets a fresh one
+// - The thumbnail is made from the written output, not the source, so it
+//   shows exactly what was exported: at most 160×160 (the DCF size), upright
+// - Outputs that already carry EXIF (copied-through JPEGs) keep theirs
+//
+
+const (
+	exifThumbMaxSide = 160
+	exifThumbQuality = 75
+)
+
+// exifWithThumbnail is an APP1 EXIF segment body holding only an upright
+// orientation and thumb, a JPEG.
+func exifWithThumbnail(thumb []byte) []byte {
+	const (
+		ifd0At   = 8
+		ifd1At   = ifd0At + 2 + 12 + 4
+		thumbAt  = ifd1At + 2 + 3*12 + 4
+		tagShort = 3
+		tagLong  = 4
+	)
+	var b bytes.Buffer
+	be := binary.BigEndian
+	put := func(v any) { binary.Write(&b, be, v) }
+	entry := func(tag, typ uint16, value uint32) {
+		put(tag)
+		put(typ)
+		put(uint32(1))
+		if typ == tagShort {
+			value <<= 16 // a SHORT sits in the value field's first half
+		}
+		put(value)
+	}
+	b.WriteString("Exif\x00\x00")
+	b.WriteString("MM\x00\x2A")
+	put(uint32(ifd0At))
+	put(uint16(1))
+	entry(0x0112, tagShort, 1) // Orientation: the output is already upright
+	put(uint32(ifd1At))
+	put(uint16(3))
+	entry(0x0103, tagShort, 6) // Compression: JPEG
+	entry(0x0201, tagLong, thumbAt)
+	entry(0x0202, tagLong, uint32(len(thumb)))
+	put(uint32(0))
+	b.Write(thumb)
+	return b.Bytes()
+}
+
+// embedThumbnail adds an EXIF thumbnail to the JPEG at outPath, made from
+// the output itself. Anything but a JPEG without EXIF is left alone.
+func embedThumbnail(outPath string) error {
+	if sniffFormat(outPath) != ".jpg" {
+		return nil
+	}
+	data, err := disk.ReadFile(outPath)
+	if err != nil {
+		return fmt.Errorf("thumbnail failed: %v", err)
+	}
+	if bytes.Contains(data[:min(len(data), xmpScanLimit)], []byte("Exif\x00\x00")) {
+		return nil
+	}
+	img, err := imaging.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("thumbnail failed: %v", err)
+	}
+	thumb, err := encodeJPEGBytes(imaging.Fit(img, exifThumbMaxSide, exifThumbMaxSide, imaging.Lanczos), exifThumbQuality)
+	if err != nil {
+		return fmt.Errorf("thumbnail failed: %v", err)
+	}
+	out, err := withJPEGAPP1(data, exifWithThumbnail(thumb))
+	if err != nil {
+		return fmt.Errorf("thumbnail failed: %v", err)
+	}
+	return atomicWrite(context.Background(), outPath, func(tmp string) error { return disk.WriteFile(tmp, out, 0644) })
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+//
+// File info panel
+// - Shown under the preview for the selected image: dimensions,
+//   megapixels, format, bit depth, colour profile and file size, all read
+//   from the header without decoding
+// - The estimated output size needs the decoded image, so it is worked out
+//   in the background with the same bits-per-pixel model as the target
+//   search, after folder overrides and rules
+//
+
+// bitDepth describes the per-channel depth of a decoder's colour model.
+func bitDepth(m color.Model) string {
+	switch m {
+	case color.GrayModel:
+		return "8-bit grey"
+	case color.Gray16Model:
+		return "16-bit grey"
+	case color.RGBA64Model, color.NRGBA64Model:
+		return "16-bit"
+	case color.CMYKModel:
+		return "8-bit CMYK"
+	case nil:
+		return ""
+	}
+	if _, ok := m.(color.Palette); ok {
+		return "8-bit palette"
+	}
+	return "8-bit"
+}
+
+// describeFile is the header line of the info panel.
+func describeFile(path string) string {
+	f := factsFor(path)
+	if f.width == 0 {
+		return fmt.Sprintf("%s · %s", strings.ToUpper(f.format), formatSize(int64(f.sizeKB*1024)))
+	}
+	parts := []string{
+		fmt.Sprintf("%d×%d (%.1f MP)", f.width, f.height, float64(f.width*f.height)/1e6),
+		strings.ToUpper(f.format),
+	}
+	if d := bitDepth(f.model); d != "" {
+		parts = append(parts, d)
+	}
+	if f.alpha {
+		parts = append(parts, "alpha")
+	}
+	if p := profileName(path); p != "" {
+		parts = append(parts, p)
+	}
+	parts = append(parts, formatSize(int64(f.sizeKB*1024)))
+	return strings.Join(parts, " · ")
+}
+
+// outputSize is the size processImageSync resizes a w×h input to.
+func outputSize(w, h int, o compressOptions) (int, int) {
+	if o.fillW > 0 && o.fillH > 0 {
+		return o.fillW, o.fillH
+	}
+	scale := 1.0
+	if o.maxW > 0 && w > o.maxW {
+		scale = float64(o.maxW) / float64(w)
+	}
+	if o.maxH > 0 && float64(h)*scale > float64(o.maxH) {
+		scale = float64(o.maxH) / float64(h)
+	}
+	return max(1, int(float64(w)*scale+0.5)), max(1, int(float64(h)*scale+0.5))
+}
+
+// estimateOutput predicts what o will make of img, which was decoded from
+// a file of inBytes.
+func estimateOutput(img image.Image, inBytes int64, o compressOptions) string {
+	b := img.Bounds()
+	w, h := outputSize(b.Dx(), b.Dy(), o)
+	dims := ""
+	if w != b.Dx() || h != b.Dy() {
+		dims = fmt.Sprintf(", resized to %d×%d", w, h)
+	}
+	if o.format == "png" {
+		return "Estimated output: lossless PNG" + dims + ", size depends on content"
+	}
+	quality := o.quality.clamped()
+	if o.targetKB > 0 {
+		target := o.targetKB * 1024
+		if floor := estimateJPEGBytes(img, w, h, 10); floor > target {
+			if o.shrinkToFit {
+				return fmt.Sprintf("Estimated output: ≈ %s, likely downscaled to meet the target", formatSize(int64(target))) + dims
+			}
+			return fmt.Sprintf("Estimated output: ≈ %s, over the %s target even at q10", formatSize(int64(floor)), formatSize(int64(target))) + dims
+		}
+		return fmt.Sprintf("Estimated output: ≈ %s (target)", formatSize(int64(target))) + dims
+	}
+	est := estimateJPEGBytes(img, w, h, quality.jpeg)
+	saving := ""
+	if inBytes > 0 {
+		saving = fmt.Sprintf(", %.0f%% of the original", 100*float64(est)/float64(inBytes))
+	}
+	return fmt.Sprintf("Estimated output: ≈ %s as JPEG q%d%s", formatSize(int64(est)), quality.jpeg, saving) + dims
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// File system seam
+// - The core's file I/O (opening sources, naming outputs, writing and
+//   renaming them into place, metadata passes) goes through disk, a small
+//   interface, rather than the os package directly
+// - osFS is the real disk. memFS keeps files in memory, so naming,
+//   collisions and the encode/write path can be exercised without touching
+//   it, and is the basis for dry runs that show what a batch would write
+// - JPEG outputs are streamed through disk.Create, jpegtran included, and
+//   so are rebuilt PDFs, contact sheets, the pass-through copy and copies
+//   to a second folder: a temp
+//   file and its rename always go through the same file system. Encoders
+//   that work on paths (ffmpeg, pdftoppm) still use the real disk
+//
+
+// fileSystem is the file I/O the core needs.
+type fileSystem interface {
+	Open(name string) (fs.File, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	Create(name string) (io.WriteCloser, error)
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	MkdirAll(path string, perm fs.FileMode) error
+}
+
+// disk is the file system the core uses; osFS outside of tests.
+var disk fileSystem = osFS{}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error)            { return os.Open(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (osFS) ReadFile(name string) ([]byte, error)         { return os.ReadFile(name) }
+func (osFS) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+func (osFS) Remove(name string) error                     { return os.Remove(name) }
+func (osFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+func (osFS) Create(name string) (io.WriteCloser, error) { return os.Create(name) }
+
+// memFS is an in-memory fileSystem. Paths are cleaned; a file's parent
+// folders exist implicitly.
+type memFS struct {
+	mu    sync.Mutex
+	files map[string]memEntry
+	dirs  map[string]bool
+}
+
+type memEntry struct {
+	data    []byte
+	perm    fs.FileMode
+	modTime time.Time
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: map[string]memEntry{}, dirs: map[string]bool{}}
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	e, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memFile{Reader: bytes.NewReader(e.data), info: memInfo{name: filepath.Base(name), entry: e}}, nil
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if e, ok := m.files[name]; ok {
+		return memInfo{name: filepath.Base(name), entry: e}, nil
+	}
+	if m.isDir(name) {
+		return memInfo{name: filepath.Base(name), dir: true}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// isDir reports whether name was made with MkdirAll or holds files.
+func (m *memFS) isDir(name string) bool {
+	if m.dirs[name] {
+		return true
+	}
+	prefix := name + string(filepath.Separator)
+	for p := range m.files {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(e.data), nil
+}
+
+func (m *memFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[filepath.Clean(name)] = memEntry{data: bytes.Clone(data), perm: perm, modTime: time.Now()}
+	return nil
+}
+
+// Create starts an empty file; what is written appears on Close.
+func (m *memFS) Create(name string) (io.WriteCloser, error) {
+	if err := m.WriteFile(name, nil, 0644); err != nil {
+		return nil, err
+	}
+	return &memWriter{fs: m, name: name}, nil
+}
+
+type memWriter struct {
+	fs   *memFS
+	name string
+	buf  bytes.Buffer
+}
+
+func (w *memWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+func (w *memWriter) Close() error                { return w.fs.WriteFile(w.name, w.buf.Bytes(), 0644) }
+
+func (m *memFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	e, ok := m.files[oldpath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	delete(m.files, oldpath)
+	m.files[newpath] = e
+	return nil
+}
+
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+func (m *memFS) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for p := filepath.Clean(path); !m.dirs[p]; p = filepath.Dir(p) {
+		m.dirs[p] = true
+	}
+	return nil
+}
+
+// paths lists the files held, sorted.
+func (m *memFS) paths() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]string, 0, len(m.files))
+	for p := range m.files {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+type memFile struct {
+	*bytes.Reader
+	info memInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memInfo struct {
+	name  string
+	entry memEntry
+	dir   bool
+}
+
+func (i memInfo) Name() string { return i.name }
+func (i memInfo) Size() int64  { return int64(len(i.entry.data)) }
+func (i memInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return i.entry.perm
+}
+func (i memInfo) ModTime() time.Time { return i.entry.modTime }
+func (i memInfo) IsDir() bool        { return i.dir }
+func (i memInfo) Sys() any           { return nil }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useMemFS points disk at a fresh memFS for the test.
+func useMemFS(t *testing.T) *memFS {
+	t.Helper()
+	m := newMemFS()
+	disk = m
+	t.Cleanup(func() { disk = osFS{} })
+	return m
+}
+
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x * 255 / w), uint8(y * 255 / h), 128, 255})
+		}
+	}
+	return img
+}
+
+func TestUniqueOutputPathMemFS(t *testing.T) {
+	m := useMemFS(t)
+	dir := filepath.Join("out", "photos")
+	want := filepath.Join(dir, "a.jpg")
+	if got := uniqueOutputPath(want); got != want {
+		t.Fatalf("free name: got %q, want %q", got, want)
+	}
+	m.WriteFile(want, []byte("x"), 0644)
+	m.WriteFile(filepath.Join(dir, "a (1).jpg"), []byte("x"), 0644)
+	if got := uniqueOutputPath(want); got != filepath.Join(dir, "a (2).jpg") {
+		t.Fatalf("taken name: got %q", got)
+	}
+}
+
+func TestWithExtMemFS(t *testing.T) {
+	m := useMemFS(t)
+	m.WriteFile(filepath.Join("out", "a.png"), []byte("x"), 0644)
+	if got := withExt(filepath.Join("out", "a.JPG"), ".jpg"); got != filepath.Join("out", "a.JPG") {
+		t.Fatalf("same extension: got %q", got)
+	}
+	if got := withExt(filepath.Join("out", "a.jpg"), ".png"); got != filepath.Join("out", "a (1).png") {
+		t.Fatalf("taken name: got %q", got)
+	}
+}
+
+// TestJPEGRoundTripMemFS encodes, writes through the temp file and renames
+// into place without touching the real disk.
+func TestJPEGRoundTripMemFS(t *testing.T) {
+	m := useMemFS(t)
+	out := filepath.Join("out", "photo.png") // misnamed: settled to .jpg
+	err := atomicWrite(context.Background(), out, func(tmp string) error {
+		return writeJPEGFile(context.Background(), tmp, testImage(64, 48), 80, jpegTuning{})
+	})
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := m.paths(); !reflect.DeepEqual(got, []string{out}) {
+		t.Fatalf("after write: files %q, want only %q", got, out)
+	}
+	settled, err := settleOutputExt(out)
+	if err != nil {
+		t.Fatalf("settle: %v", err)
+	}
+	want := filepath.Join("out", "photo.jpg")
+	if settled != want {
+		t.Fatalf("settled to %q, want %q", settled, want)
+	}
+	if got := m.paths(); !reflect.DeepEqual(got, []string{want}) {
+		t.Fatalf("after settle: files %q", got)
+	}
+	f, err := disk.Open(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := jpeg.Decode(f)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 48 {
+		t.Fatalf("decoded %v, want 64x48", b)
+	}
+}
+
+func TestCancelledWriteLeavesNothing(t *testing.T) {
+	m := useMemFS(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	out := filepath.Join("out", "late.jpg")
+	if err := atomicWrite(ctx, out, func(tmp string) error {
+		return writeJPEGFile(ctx, tmp, testImage(8, 8), 80, jpegTuning{})
+	}); err == nil {
+		t.Fatal("cancelled write succeeded")
+	}
+	if got := m.paths(); len(got) != 0 {
+		t.Fatalf("cancelled write left %q", got)
+	}
+}
+
+// TestAbandonedWriteNotRenamed cancels while the temp file is written, as
+// a per-file timeout does, and expects nothing put in place.
+func TestAbandonedWriteNotRenamed(t *testing.T) {
+	m := useMemFS(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	out := filepath.Join("out", "late.png")
+	err := atomicWrite(ctx, out, func(tmp string) error {
+		cancel()
+		return disk.WriteFile(tmp, []byte("late"), 0644)
+	})
+	if err == nil {
+		t.Fatal("abandoned write succeeded")
+	}
+	if got := m.paths(); len(got) != 0 {
+		t.Fatalf("abandoned write left %q", got)
+	}
+}
+
+// TestCopiesStayInMemFS copies an output to a second folder, the way the
+// pass-through copy and folder destinations do, without the real disk.
+func TestCopiesStayInMemFS(t *testing.T) {
+	m := useMemFS(t)
+	src := filepath.Join("out", "a.jpg")
+	m.WriteFile(src, magic[".jpg"], 0644)
+	m.WriteFile(filepath.Join("backup", "a.jpg"), []byte("x"), 0644)
+	if err := (folderDest{dir: "backup"}).put(src); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	copied := filepath.Join("backup", "a (1).jpg")
+	want := []string{copied, filepath.Join("backup", "a.jpg"), src} // sorted
+	if got := m.paths(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after put: files %q, want %q", got, want)
+	}
+	data, err := disk.ReadFile(copied)
+	if err != nil || string(data) != string(magic[".jpg"]) {
+		t.Fatalf("copy holds %q, %v", data, err)
+	}
+}
+
+// TestContactSheetMemFS writes a contact sheet PDF through the temp file;
+// the missing input only gets an empty cell.
+func TestContactSheetMemFS(t *testing.T) {
+	m := useMemFS(t)
+	if _, err := writeContactSheet([]string{filepath.Join("in", "gone.jpg")}, "out", "pdf", 2, 2); err != nil {
+		t.Fatalf("contact sheet: %v", err)
+	}
+	want := filepath.Join("out", "contact-sheet.pdf")
+	if got := m.paths(); !reflect.DeepEqual(got, []string{want}) {
+		t.Fatalf("files %q, want only %q", got, want)
+	}
+	data, _ := disk.ReadFile(want)
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Fatalf("not a PDF: %q", data[:min(len(data), 8)])
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"image"
+)
+
+//
+// Generation-loss advisory
+// - A JPEG that has been saved several times shows its 8×8 blocks: the
+//   luminance steps across block edges grow larger than those inside
+//   blocks
+// - Such sources are measured when they are loaded; encoding one below its
+//   own quality makes the blocking visible, so that output is flagged with
+//   the quality floor to use instead
+//
+
+const (
+	blockyRatio       = 2.0 // edge/interior step ratio from which a source counts as blocky; one save at q=50 is about 1.6
+	blockSampleStride = 4   // rows (and columns) sampled, to bound the cost on large images
+)
+
+// blockiness is the mean luminance step across 8-pixel block edges
+// divided by the mean step inside blocks; about 1 for clean images.
+func blockiness(img image.Image) float64 {
+	b := img.Bounds()
+	if b.Dx() < 32 || b.Dy() < 32 {
+		return 0
+	}
+	luma := func(x, y int) int {
+		r, g, bl, _ := img.At(x, y).RGBA()
+		return int(299*r+587*g+114*bl) / 1000 >> 8
+	}
+	var edge, inner, edges, inners int
+	step := func(d, pos int) {
+		if pos%8 == 0 {
+			edge += max(d, -d)
+			edges++
+		} else {
+			inner += max(d, -d)
+			inners++
+		}
+	}
+	for y := b.Min.Y; y < b.Max.Y; y += blockSampleStride {
+		prev := luma(b.Min.X, y)
+		for x := b.Min.X + 1; x < b.Max.X; x++ {
+			v := luma(x, y)
+			step(v-prev, x-b.Min.X)
+			prev = v
+		}
+	}
+	for x := b.Min.X; x < b.Max.X; x += blockSampleStride {
+		prev := luma(x, b.Min.Y)
+		for y := b.Min.Y + 1; y < b.Max.Y; y++ {
+			v := luma(x, y)
+			step(v-prev, y-b.Min.Y)
+			prev = v
+		}
+	}
+	if edges == 0 || inners == 0 {
+		return 0
+	}
+	// one level added to both means keeps smooth gradients, where steps
+	// inside blocks are near zero, from reading as blocky
+	return (float64(edge)/float64(edges) + 1) / (float64(inner)/float64(inners) + 1)
+}
+
+// generationAdvice is the quality floor for a blocky JPEG source, or 0
+// when the source looks clean or its quality cannot be read.
+func generationAdvice(path string, img image.Image) int {
+	if blockiness(img) < blockyRatio {
+		return 0
+	}
+	q, err := estimateJPEGQuality(path)
+	if err != nil {
+		return 0
+	}
+	return q
+}
+
+// adviseFloor flags res when it was encoded below floor.
+func adviseFloor(res fileResult, floor int) fileResult {
+	if floor == 0 || res.outPath == "" || res.quality <= 0 || res.quality >= floor {
+		return res
+	}
+	res.suggestQ = floor
+	res.msg += fmt.Sprintf(" WARNING: the source looks re-saved (blocky); q=%d will show it, use a quality floor of q≥%d", res.quality, floor)
+	return res
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// GPU resize
+// - Lanczos on a 150MP image costs seconds of CPU per file and dominates a
+//   run without a target size; with "Resize on the GPU" ticked, large
+//   opaque images are scaled by Metal Performance Shaders on macOS
+// - Small images stay on the CPU, where copying to and from the GPU would
+//   cost more than it saves; so do images with transparency, which the
+//   shader would scale without weighting by alpha
+// - Anything the GPU cannot do (no Metal device, over its texture size
+//   limit, other platforms) silently falls back to imaging.Lanczos
+//
+
+// gpuResizeMinPixels is the smallest source worth sending to the GPU.
+const gpuResizeMinPixels = 12_000_000
+
+// lanczosResize scales img to exactly w×h.
+func lanczosResize(img image.Image, w, h int, gpu bool) image.Image {
+	b := img.Bounds()
+	if gpu && b.Dx()*b.Dy() >= gpuResizeMinPixels && opaqueImage(img) {
+		src, ok := img.(*image.RGBA)
+		if !ok {
+			src = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+			draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+		}
+		if out, ok := gpuLanczos(src, w, h); ok {
+			return out
+		}
+	}
+	return imaging.Resize(img, w, h, imaging.Lanczos)
+}
+
+// opaqueImage reports whether every pixel of img is fully opaque.
+func opaqueImage(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// fitImage is imaging.Fit with Lanczos, on the GPU when asked.
+func fitImage(img image.Image, maxW, maxH int, gpu bool) image.Image {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if !gpu || maxW <= 0 || maxH <= 0 || srcW <= 0 || srcH <= 0 || srcW <= maxW && srcH <= maxH {
+		return imaging.Fit(img, maxW, maxH, imaging.Lanczos)
+	}
+	srcAspect := float64(srcW) / float64(srcH)
+	w, h := maxW, int(float64(maxW)/srcAspect)
+	if srcAspect <= float64(maxW)/float64(maxH) {
+		w, h = int(float64(maxH)*srcAspect), maxH
+	}
+	return lanczosResize(img, max(1, w), max(1, h), gpu)
+}
+
+// fillImage is imaging.Fill with Lanczos, on the GPU when asked.
+func fillImage(img image.Image, w, h int, anchor imaging.Anchor, gpu bool) image.Image {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if !gpu || w <= 0 || h <= 0 || srcW <= 0 || srcH <= 0 {
+		return imaging.Fill(img, w, h, anchor, imaging.Lanczos)
+	}
+	srcAspect := float64(srcW) / float64(srcH)
+	tmpW, tmpH := w, int(float64(w)/srcAspect+0.5)
+	if srcAspect > float64(w)/float64(h) {
+		tmpW, tmpH = int(float64(h)*srcAspect+0.5), h
+	}
+	return imaging.CropAnchor(lanczosResize(img, max(w, tmpW), max(h, tmpH), gpu), w, h, anchor)
+}
@@ -0,0 +1,94 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Foundation -framework Metal -framework MetalPerformanceShaders
+#import <Foundation/Foundation.h>
+#import <Metal/Metal.h>
+#import <MetalPerformanceShaders/MetalPerformanceShaders.h>
+
+// gpuDevice and gpuQueue are made once and kept for the life of the
+// process; both are nil when there is no suitable GPU.
+static id<MTLDevice> gpuDevice;
+static id<MTLCommandQueue> gpuQueue;
+
+static void gpuSetup(void) {
+	static dispatch_once_t once;
+	dispatch_once(&once, ^{
+		id<MTLDevice> dev = MTLCreateSystemDefaultDevice();
+		if (dev != nil && !MPSSupportsMTLDevice(dev)) {
+			[dev release];
+			dev = nil;
+		}
+		gpuDevice = dev;
+		gpuQueue = [dev newCommandQueue];
+	});
+}
+
+// lanczosScale scales the RGBA8 image src into dst with MPSImageLanczosScale
+// and returns 0, or non-zero when the GPU cannot do it. The objects it makes
+// are released on every path (no ARC here).
+static int lanczosScale(const void *src, int sw, int sh, int sstride, void *dst, int dw, int dh, int dstride) {
+	@autoreleasepool {
+		gpuSetup();
+		if (gpuDevice == nil || gpuQueue == nil) {
+			return 1;
+		}
+		MTLTextureDescriptor *in = [MTLTextureDescriptor texture2DDescriptorWithPixelFormat:MTLPixelFormatRGBA8Unorm
+			width:sw height:sh mipmapped:NO];
+		in.usage = MTLTextureUsageShaderRead;
+		in.storageMode = MTLStorageModeManaged;
+		MTLTextureDescriptor *out = [MTLTextureDescriptor texture2DDescriptorWithPixelFormat:MTLPixelFormatRGBA8Unorm
+			width:dw height:dh mipmapped:NO];
+		out.usage = MTLTextureUsageShaderRead | MTLTextureUsageShaderWrite;
+		out.storageMode = MTLStorageModeManaged;
+		id<MTLTexture> srcTex = [gpuDevice newTextureWithDescriptor:in];
+		id<MTLTexture> dstTex = [gpuDevice newTextureWithDescriptor:out];
+		if (srcTex == nil || dstTex == nil) {
+			[srcTex release];
+			[dstTex release];
+			return 2; // e.g. over the texture size limit
+		}
+		[srcTex replaceRegion:MTLRegionMake2D(0, 0, sw, sh) mipmapLevel:0 withBytes:src bytesPerRow:sstride];
+
+		id<MTLCommandBuffer> cmd = [gpuQueue commandBuffer]; // autoreleased
+		MPSImageLanczosScale *scale = [[MPSImageLanczosScale alloc] initWithDevice:gpuDevice];
+		[scale encodeToCommandBuffer:cmd sourceTexture:srcTex destinationTexture:dstTex];
+		id<MTLBlitCommandEncoder> blit = [cmd blitCommandEncoder];
+		[blit synchronizeResource:dstTex]; // managed storage: copy back for getBytes
+		[blit endEncoding];
+		[cmd commit];
+		[cmd waitUntilCompleted];
+		int rc = 0;
+		if (cmd.error != nil) {
+			rc = 3;
+		} else {
+			[dstTex getBytes:dst bytesPerRow:dstride fromRegion:MTLRegionMake2D(0, 0, dw, dh) mipmapLevel:0];
+		}
+		[scale release];
+		[srcTex release];
+		[dstTex release];
+		return rc;
+	}
+}
+*/
+import "C"
+
+import (
+	"image"
+	"unsafe"
+)
+
+// gpuLanczos scales src to w×h on the GPU; ok is false when it cannot.
+func gpuLanczos(src *image.RGBA, w, h int) (*image.RGBA, bool) {
+	b := src.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 || w <= 0 || h <= 0 {
+		return nil, false
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	rc := C.lanczosScale(unsafe.Pointer(&src.Pix[src.PixOffset(b.Min.X, b.Min.Y)]), C.int(b.Dx()), C.int(b.Dy()), C.int(src.Stride),
+		unsafe.Pointer(&dst.Pix[0]), C.int(w), C.int(h), C.int(dst.Stride))
+	return dst, rc == 0
+}
@@ -0,0 +1,11 @@
+//go:build !darwin
+
+package main
+
+import "image"
+
+// There is no GPU resize path here yet; resizing stays on the CPU.
+
+func gpuLanczos(src *image.RGBA, w, h int) (*image.RGBA, bool) {
+	return nil, false
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//
+// Size-capped groups
+// - Splits a run's outputs into numbered folders or ZIPs of at most N MB
+//   each, e.g. 25MB for email or 4.7GB for DVDs
+// - Filled greedily in processing order: an output that would overflow
+//   the current group starts the next one; one larger than the cap gets a
+//   group to itself
+//
+
+const (
+	groupFolders = "folders"
+	groupZIPs    = "zips"
+)
+
+// zipEntryOverhead approximates the local header, central directory record
+// and end record a stored entry adds, so ZIP groups stay under the cap.
+func zipEntryOverhead(name string) int64 {
+	return 30 + 46 + 2*int64(len(name)) + 22
+}
+
+type groupDest struct {
+	dir   string // groups are created here as part-001, part-002, ...
+	cap   int64
+	asZip bool
+
+	n    int   // current group number, 0 before the first put
+	used int64 // bytes in the current group
+	zip  *zipDest
+}
+
+func newGroupDest(dir string, capMB int, kind string) *groupDest {
+	return &groupDest{dir: dir, cap: int64(capMB) << 20, asZip: kind == groupZIPs}
+}
+
+func (d *groupDest) partName(n int) string {
+	name := fmt.Sprintf("part-%03d", n)
+	if d.asZip {
+		name += ".zip"
+	}
+	return filepath.Join(d.dir, name)
+}
+
+func (d *groupDest) name() string {
+	kind := "folders"
+	if d.asZip {
+		kind = "ZIPs"
+	}
+	return fmt.Sprintf("%dMB %s in %s", d.cap>>20, kind, d.dir)
+}
+
+func (d *groupDest) remote() bool { return false }
+
+// next closes the current group and starts a new, empty one.
+func (d *groupDest) next() error {
+	if d.zip != nil {
+		if err := d.zip.close(); err != nil {
+			return err
+		}
+		d.zip = nil
+	}
+	d.n++
+	d.used = 0
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return err
+	}
+	if !d.asZip {
+		return os.MkdirAll(d.partName(d.n), 0755)
+	}
+	z, err := newZipDest(uniqueOutputPath(d.partName(d.n)))
+	if err != nil {
+		return err
+	}
+	d.zip = z
+	return nil
+}
+
+func (d *groupDest) put(localPath string) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if d.asZip {
+		size += zipEntryOverhead(filepath.Base(localPath))
+	}
+	if d.n == 0 || (d.used > 0 && d.used+size > d.cap) {
+		if err := d.next(); err != nil {
+			return err
+		}
+	}
+	d.used += size
+	if d.asZip {
+		return d.zip.put(localPath)
+	}
+	return folderDest{dir: d.partName(d.n)}.put(localPath)
+}
+
+func (d *groupDest) close() error {
+	if d.zip != nil {
+		return d.zip.close()
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build headless
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//
+// Headless build
+// - `go build -tags headless` leaves out the window and everything that
+//   links Fyne and OpenGL, for servers and containers with no display
+// - The binary compresses the files and folders given on the command line
+//   into -out with the same core as the app: target size, formats, resize
+//   and the per-format quality defaults
+// - Settings made in the app (rules, presets, uploads) are not read; each
+//   run is described by its flags alone
+//
+
+func main() {
+	out := flag.String("out", "compressed", "output folder")
+	targetKB := flag.Int("kb", 0, "target size per file in KB; 0 uses -quality")
+	quality := flag.Int("quality", builtinQuality.jpeg, "JPEG quality when there is no target")
+	format := flag.String("format", "jpeg", "output format: jpeg, png or heic")
+	maxW := flag.Int("max-width", 0, "longest width in pixels; 0 keeps it")
+	maxH := flag.Int("max-height", 0, "longest height in pixels; 0 keeps it")
+	shrink := flag.Bool("shrink", false, "downscale when -kb is out of reach at minimum quality")
+	version := flag.Bool("version", false, "print the version and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] file-or-folder...\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *version {
+		fmt.Println(appVersion())
+		return
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	switch *format {
+	case "jpeg", "png":
+	case "heic":
+		if err := checkHEIC(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		os.Exit(2)
+	}
+
+	q := builtinQuality
+	q.jpeg = *quality
+	opts := compressOptions{
+		targetKB: *targetKB, shrinkToFit: *shrink, maxW: *maxW, maxH: *maxH,
+		format: *format, quality: q.clamped(),
+	}
+	loadCodecs()
+	failed := 0
+	for _, f := range expandItems(flag.Args()) {
+		base := filepath.Base(f)
+		name := opts.naming.outputStem(strings.TrimSuffix(base, filepath.Ext(base)), outputExt(opts.format))
+		outPath := uniqueOutputPath(filepath.Join(*out, name+outputExt(opts.format)))
+		res, err := processImageSync(context.Background(), f, outPath, opts)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "ERROR %s: %v\n", f, err)
+			continue
+		}
+		fmt.Println(res.msg)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
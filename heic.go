@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+//
+// HEIC output
+// - For batches whose every consumer is an Apple device: HEIC is about half
+//   the size of a JPEG that looks the same, and Photos, Preview and iOS open
+//   it natively
+// - Encoded by ImageIO, so it is only offered on macOS, and only when this
+//   Mac has a HEVC encoder (see Formats...)
+// - Without a target size the "HEIC quality" default is used; with one, the
+//   quality is bisected like JPEG's, each candidate a full encode
+//
+
+// heicTargetFloor is the lowest quality the HEIC target search goes to.
+const heicTargetFloor = 10
+
+// heicForTarget finds the highest quality whose encode fits targetBytes and
+// returns that encode. At the floor it returns the floor's encode even when
+// it is over.
+func heicForTarget(ctx context.Context, img image.Image, targetBytes int) ([]byte, int, error) {
+	lo, hi := heicTargetFloor, 95
+	var best []byte
+	bestQ := 0
+	for lo <= hi {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		q := (lo + hi) / 2
+		data, err := encodeHEIC(img, q)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(data) <= targetBytes {
+			best, bestQ = data, q
+			lo = q + 1
+		} else {
+			hi = q - 1
+		}
+	}
+	if best == nil {
+		data, err := encodeHEIC(img, heicTargetFloor)
+		if err != nil {
+			return nil, 0, err
+		}
+		return data, heicTargetFloor, nil
+	}
+	return best, bestQ, nil
+}
+
+// checkHEIC reports why HEIC output cannot be written here, if it cannot.
+func checkHEIC() error {
+	if !heicSupported() {
+		return fmt.Errorf("HEIC output needs macOS with a HEVC encoder")
+	}
+	return nil
+}
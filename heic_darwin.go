@@ -0,0 +1,98 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Foundation -framework CoreGraphics -framework ImageIO
+#include <stdlib.h>
+#include <string.h>
+#import <Foundation/Foundation.h>
+#import <CoreGraphics/CoreGraphics.h>
+#import <ImageIO/ImageIO.h>
+
+// heicAvailable reports whether ImageIO can write HEIC on this Mac.
+static int heicAvailable(void) {
+	@autoreleasepool {
+		NSArray *types = CFBridgingRelease(CGImageDestinationCopyTypeIdentifiers());
+		return [types containsObject:@"public.heic"] ? 1 : 0;
+	}
+}
+
+// heicEncode encodes the premultiplied RGBA8 pixels pix and returns the
+// malloc'd file, or NULL with a malloc'd message in *errOut.
+static void *heicEncode(const void *pix, int w, int h, int stride, int opaque, double quality, int *n, char **errOut) {
+	@autoreleasepool {
+		CGColorSpaceRef space = CGColorSpaceCreateWithName(kCGColorSpaceSRGB);
+		CGDataProviderRef provider = CGDataProviderCreateWithData(NULL, pix, (size_t)stride * h, NULL);
+		CGBitmapInfo info = kCGBitmapByteOrderDefault | (opaque ? kCGImageAlphaNoneSkipLast : kCGImageAlphaPremultipliedLast);
+		CGImageRef img = CGImageCreate(w, h, 8, 32, stride, space, info, provider, NULL, false, kCGRenderingIntentDefault);
+		CGDataProviderRelease(provider);
+		CGColorSpaceRelease(space);
+		if (img == NULL) {
+			*errOut = strdup("could not read the pixels");
+			return NULL;
+		}
+		NSMutableData *data = [NSMutableData data];
+		CGImageDestinationRef dest = CGImageDestinationCreateWithData((__bridge CFMutableDataRef)data, CFSTR("public.heic"), 1, NULL);
+		if (dest == NULL) {
+			CGImageRelease(img);
+			*errOut = strdup("this Mac has no HEIC encoder");
+			return NULL;
+		}
+		NSDictionary *props = @{(__bridge NSString *)kCGImageDestinationLossyCompressionQuality: @(quality)};
+		CGImageDestinationAddImage(dest, img, (__bridge CFDictionaryRef)props);
+		BOOL ok = CGImageDestinationFinalize(dest);
+		CFRelease(dest);
+		CGImageRelease(img);
+		if (!ok) {
+			*errOut = strdup("ImageIO could not write the image");
+			return NULL;
+		}
+		*n = (int)[data length];
+		void *out = malloc(*n);
+		memcpy(out, [data bytes], *n);
+		return out;
+	}
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"unsafe"
+)
+
+func heicSupported() bool {
+	return C.heicAvailable() != 0
+}
+
+// encodeHEIC encodes img at quality q (1..100) with ImageIO.
+func encodeHEIC(img image.Image, q int) ([]byte, error) {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return nil, errors.New("empty image")
+	}
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		src = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	}
+	opaque := 0
+	if opaqueImage(img) {
+		opaque = 1
+	}
+	sb := src.Bounds()
+	var n C.int
+	var cerr *C.char
+	data := C.heicEncode(unsafe.Pointer(&src.Pix[src.PixOffset(sb.Min.X, sb.Min.Y)]), C.int(sb.Dx()), C.int(sb.Dy()), C.int(src.Stride),
+		C.int(opaque), C.double(float64(q)/100), &n, &cerr)
+	if data == nil {
+		defer C.free(unsafe.Pointer(cerr))
+		return nil, errors.New(C.GoString(cerr))
+	}
+	defer C.free(data)
+	return C.GoBytes(data, n), nil
+}
@@ -0,0 +1,16 @@
+//go:build !darwin
+
+package main
+
+import (
+	"errors"
+	"image"
+)
+
+// HEIC is encoded by ImageIO, which only macOS has.
+
+func heicSupported() bool { return false }
+
+func encodeHEIC(img image.Image, q int) ([]byte, error) {
+	return nil, errors.New("HEIC output needs macOS")
+}
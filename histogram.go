@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// Histogram and clipping
+// - RGB histograms of the selected image before and after the batch's
+//   adjustments (auto white balance/exposure, sliders, styles), drawn
+//   under the preview
+// - Clipped pixels are counted and can be highlighted on the preview:
+//   red where a channel is blown out, blue where all channels are crushed
+//
+
+// clipHigh and clipLow are the channel values counted as clipped.
+const (
+	clipHigh = 255
+	clipLow  = 0
+)
+
+type histogram struct {
+	r, g, b   [256]int
+	pixels    int
+	highlight int // pixels with any channel at clipHigh
+	shadow    int // pixels with every channel at clipLow
+}
+
+func computeHistogram(img image.Image) histogram {
+	var h histogram
+	src := imaging.Clone(img)
+	for i := 0; i+3 < len(src.Pix); i += 4 {
+		r, g, b := src.Pix[i], src.Pix[i+1], src.Pix[i+2]
+		h.r[r]++
+		h.g[g]++
+		h.b[b]++
+		if r >= clipHigh || g >= clipHigh || b >= clipHigh {
+			h.highlight++
+		}
+		if r <= clipLow && g <= clipLow && b <= clipLow {
+			h.shadow++
+		}
+		h.pixels++
+	}
+	return h
+}
+
+func (h histogram) highlightPct() float64 {
+	if h.pixels == 0 {
+		return 0
+	}
+	return 100 * float64(h.highlight) / float64(h.pixels)
+}
+
+func (h histogram) shadowPct() float64 {
+	if h.pixels == 0 {
+		return 0
+	}
+	return 100 * float64(h.shadow) / float64(h.pixels)
+}
+
+// render draws the three channels additively on a dark background, so
+// where they overlap the bars turn white. Heights are scaled to the
+// tallest bin ignoring the two end bins, whose clipped spikes would
+// otherwise flatten everything else.
+func (h histogram) render(height int) *image.NRGBA {
+	out := image.NewNRGBA(image.Rect(0, 0, 256, height))
+	for i := 0; i < len(out.Pix); i += 4 {
+		out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3] = 32, 32, 32, 255
+	}
+	peak := 1
+	for v := 1; v < 255; v++ {
+		peak = max(peak, h.r[v], h.g[v], h.b[v])
+	}
+	for x := 0; x < 256; x++ {
+		bars := [3]int{h.r[x], h.g[x], h.b[x]}
+		for ch, n := range bars {
+			top := height - min(height, n*height/peak)
+			for y := top; y < height; y++ {
+				out.Pix[out.PixOffset(x, y)+ch] = 230
+			}
+		}
+	}
+	return out
+}
+
+// clippingOverlay paints clipped pixels of img so they stand out.
+func clippingOverlay(img image.Image) *image.NRGBA {
+	out := imaging.Clone(img)
+	for i := 0; i+3 < len(out.Pix); i += 4 {
+		r, g, b := out.Pix[i], out.Pix[i+1], out.Pix[i+2]
+		switch {
+		case r >= clipHigh || g >= clipHigh || b >= clipHigh:
+			out.Pix[i], out.Pix[i+1], out.Pix[i+2] = 255, 0, 0
+		case r <= clipLow && g <= clipLow && b <= clipLow:
+			out.Pix[i], out.Pix[i+1], out.Pix[i+2] = 0, 64, 255
+		}
+	}
+	return out
+}
+
+// clipSummary compares clipping before and after the adjustments.
+func clipSummary(before, after histogram) string {
+	return fmt.Sprintf("Clipped highlights %.1f%% → %.1f%%, shadows %.1f%% → %.1f%%",
+		before.highlightPct(), after.highlightPct(), before.shadowPct(), after.shadowPct())
+}
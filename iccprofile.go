@@ -0,0 +1,480 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"unicode/utf16"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// Embedded colour profiles
+// - Only read, never written: outputs carry no ICC profile, so viewers
+//   show their pixel values as sRGB
+// - Reads the profile from JPEG APP2 and PNG iCCP, and parses RGB
+//   matrix/TRC profiles (sRGB, Display P3, Adobe RGB and most camera
+//   profiles); LUT-based profiles are reported as unsupported
+// - The preview uses it to soft-proof the original colours against the
+//   exported ones
+// - On a display that is not sRGB (a wide-gamut Mac screen), the preview
+//   is converted to the display's profile as well, since Fyne draws raw
+//   pixel values; it then looks as it does in Preview.app
+//
+
+// iccProfile is a parsed matrix/TRC RGB profile.
+type iccProfile struct {
+	desc  string
+	toXYZ [3][3]float64 // linear RGB -> D50 XYZ; columns are the colorants
+	trc   [3]iccCurve
+}
+
+// iccCurve maps an encoded channel value (0..1) to linear light.
+type iccCurve struct {
+	gamma  float64   // used when table and params are empty
+	table  []float64 // sampled curve, 0..1
+	kind   int       // parametric function type, see ICC 10.18
+	params []float64
+}
+
+func (c iccCurve) eval(x float64) float64 {
+	switch {
+	case len(c.table) > 0:
+		pos := x * float64(len(c.table)-1)
+		i := int(pos)
+		if i >= len(c.table)-1 {
+			return c.table[len(c.table)-1]
+		}
+		f := pos - float64(i)
+		return c.table[i]*(1-f) + c.table[i+1]*f
+	case c.params != nil:
+		p := c.params
+		g := p[0]
+		switch c.kind {
+		case 1:
+			if x >= -p[2]/p[1] {
+				return math.Pow(p[1]*x+p[2], g)
+			}
+			return 0
+		case 2:
+			if x >= -p[2]/p[1] {
+				return math.Pow(p[1]*x+p[2], g) + p[3]
+			}
+			return p[3]
+		case 3:
+			if x >= p[4] {
+				return math.Pow(p[1]*x+p[2], g)
+			}
+			return p[3] * x
+		case 4:
+			if x >= p[4] {
+				return math.Pow(p[1]*x+p[2], g) + p[5]
+			}
+			return p[3]*x + p[6]
+		}
+		return math.Pow(x, g)
+	}
+	return math.Pow(x, c.gamma)
+}
+
+// srgbD50 are the Bradford-adapted sRGB colorants, as in the standard
+// sRGB ICC profile.
+var srgbD50 = [3][3]float64{
+	{0.4361, 0.3851, 0.1431},
+	{0.2225, 0.7169, 0.0606},
+	{0.0139, 0.0971, 0.7141},
+}
+
+// srgbProfile is the sRGB profile, for converting exported colours.
+var srgbProfile = &iccProfile{desc: "sRGB", toXYZ: srgbD50, trc: [3]iccCurve{srgbCurve, srgbCurve, srgbCurve}}
+
+var srgbCurve = iccCurve{kind: 3, params: []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045}}
+
+// readICCProfile returns the raw profile embedded in a JPEG or PNG, or nil
+// when there is none.
+func readICCProfile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch inputFormat(path) {
+	case ".jpg":
+		return jpegICC(f)
+	case ".png":
+		return pngICC(f)
+	}
+	return nil, nil
+}
+
+// jpegICC joins the APP2 ICC_PROFILE chunks found before the first scan.
+func jpegICC(r io.Reader) ([]byte, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil {
+		return nil, err
+	}
+	chunks := map[byte][]byte{}
+	var count byte
+	for {
+		var hdr [4]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			break
+		}
+		if hdr[0] != 0xFF || hdr[1] == 0xDA || hdr[1] == 0xD9 {
+			break
+		}
+		n := int(binary.BigEndian.Uint16(hdr[2:])) - 2
+		if n < 0 {
+			break
+		}
+		seg := make([]byte, n)
+		if _, err := io.ReadFull(r, seg); err != nil {
+			return nil, err
+		}
+		const sig = "ICC_PROFILE\x00"
+		if hdr[1] == 0xE2 && len(seg) > len(sig)+2 && string(seg[:len(sig)]) == sig {
+			chunks[seg[len(sig)]] = seg[len(sig)+2:]
+			count = seg[len(sig)+1]
+		}
+	}
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	var out []byte
+	for i := byte(1); i <= count; i++ {
+		c, ok := chunks[i]
+		if !ok {
+			return nil, fmt.Errorf("ICC profile chunk %d of %d missing", i, count)
+		}
+		out = append(out, c...)
+	}
+	return out, nil
+}
+
+// pngICC inflates the iCCP chunk, which must come before the image data.
+func pngICC(r io.Reader) ([]byte, error) {
+	var sig [8]byte
+	if _, err := io.ReadFull(r, sig[:]); err != nil {
+		return nil, err
+	}
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, nil
+		}
+		n := binary.BigEndian.Uint32(hdr[:4])
+		typ := string(hdr[4:])
+		if typ == "IDAT" || typ == "IEND" {
+			return nil, nil
+		}
+		if typ != "iCCP" {
+			if _, err := io.CopyN(io.Discard, r, int64(n)+4); err != nil {
+				return nil, nil
+			}
+			continue
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		name := bytes.IndexByte(data, 0)
+		if name < 0 || name+2 > len(data) {
+			return nil, fmt.Errorf("malformed iCCP chunk")
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(data[name+2:]))
+		if err != nil {
+			return nil, fmt.Errorf("iCCP: %v", err)
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	}
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// parseICC reads the description, colorants and tone curves of an RGB
+// matrix/TRC profile.
+func parseICC(data []byte) (*iccProfile, error) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		return nil, fmt.Errorf("not an ICC profile")
+	}
+	if string(data[16:20]) != "RGB " {
+		return nil, fmt.Errorf("%q colour space profiles are not supported", string(data[16:20]))
+	}
+	tags := iccTags(data)
+	p := &iccProfile{desc: iccDescription(tags["desc"])}
+	for col, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		t := tags[sig]
+		if len(t) < 20 || string(t[:4]) != "XYZ " {
+			return nil, fmt.Errorf("profile %q is not a matrix/TRC profile", p.desc)
+		}
+		for row := 0; row < 3; row++ {
+			p.toXYZ[row][col] = s15Fixed16(t[8+4*row:])
+		}
+	}
+	for ch, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		c, err := iccParseCurve(tags[sig])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", sig, err)
+		}
+		p.trc[ch] = c
+	}
+	return p, nil
+}
+
+// iccTags indexes the tag table of a profile by signature.
+func iccTags(data []byte) map[string][]byte {
+	tags := map[string][]byte{}
+	if len(data) < 132 {
+		return tags
+	}
+	count := int(binary.BigEndian.Uint32(data[128:]))
+	for i := 0; i < count && 132+12*(i+1) <= len(data); i++ {
+		e := data[132+12*i:]
+		off, size := int(binary.BigEndian.Uint32(e[4:])), int(binary.BigEndian.Uint32(e[8:]))
+		if off < 0 || size < 8 || off+size > len(data) {
+			continue
+		}
+		tags[string(e[:4])] = data[off : off+size]
+	}
+	return tags
+}
+
+func iccParseCurve(t []byte) (iccCurve, error) {
+	if len(t) < 12 {
+		return iccCurve{}, fmt.Errorf("missing tone curve")
+	}
+	switch string(t[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(t[8:]))
+		if len(t) < 12+2*n {
+			return iccCurve{}, fmt.Errorf("truncated curve")
+		}
+		switch n {
+		case 0:
+			return iccCurve{gamma: 1}, nil
+		case 1:
+			return iccCurve{gamma: float64(binary.BigEndian.Uint16(t[12:])) / 256}, nil
+		}
+		c := iccCurve{table: make([]float64, n)}
+		for i := range c.table {
+			c.table[i] = float64(binary.BigEndian.Uint16(t[12+2*i:])) / 65535
+		}
+		return c, nil
+	case "para":
+		kind := int(binary.BigEndian.Uint16(t[8:]))
+		nParams := []int{1, 3, 4, 5, 7}
+		if kind >= len(nParams) || len(t) < 12+4*nParams[kind] {
+			return iccCurve{}, fmt.Errorf("unknown parametric curve type %d", kind)
+		}
+		c := iccCurve{kind: kind, params: make([]float64, nParams[kind])}
+		for i := range c.params {
+			c.params[i] = s15Fixed16(t[12+4*i:])
+		}
+		return c, nil
+	}
+	return iccCurve{}, fmt.Errorf("unsupported curve type %q", string(t[:4]))
+}
+
+// iccDescription reads a v2 "desc" or v4 "mluc" tag.
+func iccDescription(t []byte) string {
+	if len(t) < 12 {
+		return "unnamed profile"
+	}
+	switch string(t[:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(t[8:]))
+		if n > 0 && 12+n <= len(t) {
+			return string(bytes.TrimRight(t[12:12+n], "\x00"))
+		}
+	case "mluc":
+		if len(t) >= 28 && binary.BigEndian.Uint32(t[8:]) > 0 {
+			n, off := int(binary.BigEndian.Uint32(t[20:])), int(binary.BigEndian.Uint32(t[24:]))
+			if off+n <= len(t) {
+				u := make([]uint16, n/2)
+				for i := range u {
+					u[i] = binary.BigEndian.Uint16(t[off+2*i:])
+				}
+				return string(utf16.Decode(u))
+			}
+		}
+	}
+	return "unnamed profile"
+}
+
+// isSRGB reports whether the profile is close enough to sRGB that dropping
+// it makes no visible difference.
+func (p *iccProfile) isSRGB() bool {
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			if math.Abs(p.toXYZ[r][c]-srgbD50[r][c]) > 0.01 {
+				return false
+			}
+		}
+	}
+	for _, c := range p.trc {
+		for _, x := range []float64{0.1, 0.5, 0.9} {
+			if math.Abs(c.eval(x)-srgbToLinear(uint8(x*255+0.5))) > 0.01 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func invert3(m [3][3]float64) [3][3]float64 {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	var inv [3][3]float64
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			// cofactor of the transposed position
+			r1, r2 := (c+1)%3, (c+2)%3
+			c1, c2 := (r+1)%3, (r+2)%3
+			inv[r][c] = (m[r1][c1]*m[r2][c2] - m[r1][c2]*m[r2][c1]) / det
+		}
+	}
+	return inv
+}
+
+// toSRGB converts img from the profile's colours to sRGB, which is how the
+// original looks in a colour-managed viewer.
+func (p *iccProfile) toSRGB(img image.Image) *image.NRGBA {
+	return p.convert(img, srgbProfile)
+}
+
+// encodeTable inverts the curve: entry i is the 8-bit value whose linear
+// light is i/steps. Tone curves only ever rise, so one sweep does.
+func (c iccCurve) encodeTable(steps int) []uint8 {
+	const samples = 4096
+	fwd := make([]float64, samples)
+	for j := range fwd {
+		fwd[j] = c.eval(float64(j) / (samples - 1))
+	}
+	enc := make([]uint8, steps+1)
+	j := 0
+	for i := range enc {
+		for j < samples-1 && fwd[j] < float64(i)/float64(steps) {
+			j++
+		}
+		enc[i] = uint8(float64(j)/(samples-1)*255 + 0.5)
+	}
+	return enc
+}
+
+// convert maps img from the profile's colours to dst's.
+func (p *iccProfile) convert(img image.Image, dst *iccProfile) *image.NRGBA {
+	var lin [3][256]float64
+	for ch := range lin {
+		for v := range lin[ch] {
+			lin[ch][v] = p.trc[ch].eval(float64(v) / 255)
+		}
+	}
+	inv := invert3(dst.toXYZ)
+	var m [3][3]float64
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			m[r][c] = inv[r][0]*p.toXYZ[0][c] + inv[r][1]*p.toXYZ[1][c] + inv[r][2]*p.toXYZ[2][c]
+		}
+	}
+	const steps = 4096
+	var enc [3][]uint8
+	for ch := range enc {
+		enc[ch] = dst.trc[ch].encodeTable(steps)
+	}
+	quant := func(ch int, v float64) uint8 {
+		return enc[ch][int(math.Max(0, math.Min(1, v))*steps+0.5)]
+	}
+
+	src := imaging.Clone(img)
+	out := image.NewNRGBA(src.Rect)
+	for i := 0; i+3 < len(src.Pix); i += 4 {
+		r, g, b := lin[0][src.Pix[i]], lin[1][src.Pix[i+1]], lin[2][src.Pix[i+2]]
+		out.Pix[i] = quant(0, m[0][0]*r+m[0][1]*g+m[0][2]*b)
+		out.Pix[i+1] = quant(1, m[1][0]*r+m[1][1]*g+m[1][2]*b)
+		out.Pix[i+2] = quant(2, m[2][0]*r+m[2][1]*g+m[2][2]*b)
+		out.Pix[i+3] = src.Pix[i+3]
+	}
+	return out
+}
+
+// displayProfile is the main display's profile, or nil when it is sRGB
+// (or unknown) and the preview needs no conversion.
+func displayProfile() *iccProfile {
+	data := displayICC()
+	if data == nil {
+		return nil
+	}
+	p, err := parseICC(data)
+	if err != nil || p.isSRGB() {
+		return nil
+	}
+	return p
+}
+
+// meanDifference is the average per-channel difference of two same-sized
+// images as a fraction of full scale.
+func meanDifference(a, b *image.NRGBA) float64 {
+	var sum float64
+	n := 0
+	for i := 0; i+3 < len(a.Pix) && i+3 < len(b.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			sum += math.Abs(float64(a.Pix[i+c]) - float64(b.Pix[i+c]))
+		}
+		n += 3
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n) / 255
+}
+
+// profileName is the description of path's embedded profile, or "" when
+// it has none.
+func profileName(path string) string {
+	data, err := readICCProfile(path)
+	if err != nil || data == nil {
+		return ""
+	}
+	// LUT-based profiles cannot be parsed but still have a name
+	return iccDescription(iccTags(data)["desc"])
+}
+
+// previewProfile loads path's embedded profile for soft-proofing. It returns
+// nil when the exported colours would look the same, plus a line for the
+// preview pane saying whether the profile matters.
+func previewProfile(path string) (*iccProfile, string) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return nil, ""
+	}
+	data, err := readICCProfile(path)
+	if err != nil {
+		return nil, "Colour profile unreadable: " + err.Error()
+	}
+	if data == nil {
+		return nil, "No embedded colour profile: exported colours match"
+	}
+	p, err := parseICC(data)
+	if err != nil {
+		return nil, "Colour profile cannot be previewed: " + err.Error()
+	}
+	if p.isSRGB() {
+		return nil, fmt.Sprintf("Profile %s: exported colours match", p.desc)
+	}
+	src, err := openImage(path)
+	if err != nil {
+		return p, fmt.Sprintf("Profile %s is dropped on export", p.desc)
+	}
+	thumb := imaging.Fit(src, 256, 256, imaging.Box)
+	diff := meanDifference(thumb, p.toSRGB(thumb))
+	return p, fmt.Sprintf("Profile %s is dropped on export: colours shift %.1f%% on average", p.desc, diff*100)
+}
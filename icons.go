@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// App icon set generation
+// - One source image → macOS .iconset + .icns, iOS AppIcon.appiconset, Android mipmaps
+// - Every size is rendered from the source with the same Lanczos resize as compression
+//
+
+// macOS iconset members and the ICNS chunk type that carries each of them.
+var macIconSizes = []struct {
+	file     string
+	px       int
+	icnsType string
+}{
+	{"icon_16x16.png", 16, "icp4"},
+	{"icon_16x16@2x.png", 32, "ic11"},
+	{"icon_32x32.png", 32, "icp5"},
+	{"icon_32x32@2x.png", 64, "ic12"},
+	{"icon_128x128.png", 128, "ic07"},
+	{"icon_128x128@2x.png", 256, "ic13"},
+	{"icon_256x256.png", 256, "ic08"},
+	{"icon_256x256@2x.png", 512, "ic14"},
+	{"icon_512x512.png", 512, "ic09"},
+	{"icon_512x512@2x.png", 1024, "ic10"},
+}
+
+// iOS icon slots: point size, scale, idiom (as Xcode writes them in Contents.json).
+var iosIconSizes = []struct {
+	size  float64
+	scale int
+	idiom string
+}{
+	{20, 2, "iphone"}, {20, 3, "iphone"},
+	{29, 2, "iphone"}, {29, 3, "iphone"},
+	{40, 2, "iphone"}, {40, 3, "iphone"},
+	{60, 2, "iphone"}, {60, 3, "iphone"},
+	{20, 1, "ipad"}, {20, 2, "ipad"},
+	{29, 1, "ipad"}, {29, 2, "ipad"},
+	{40, 1, "ipad"}, {40, 2, "ipad"},
+	{76, 1, "ipad"}, {76, 2, "ipad"},
+	{83.5, 2, "ipad"},
+	{1024, 1, "ios-marketing"},
+}
+
+// Android legacy launcher icon densities.
+var androidIconSizes = []struct {
+	dir string
+	px  int
+}{
+	{"mipmap-mdpi", 48},
+	{"mipmap-hdpi", 72},
+	{"mipmap-xhdpi", 96},
+	{"mipmap-xxhdpi", 144},
+	{"mipmap-xxxhdpi", 192},
+}
+
+// Padding per platform as a fraction of the icon edge.
+// macOS follows the Big Sur grid (824px artwork on a 1024px canvas);
+// iOS masks icons itself, so artwork goes edge to edge.
+const (
+	macIconPadding     = 100.0 / 1024.0
+	iosIconPadding     = 0
+	androidIconPadding = 4.0 / 48.0
+)
+
+// iconCanvas fits img into a px×px square, leaving pad on every side.
+func iconCanvas(img image.Image, px int, pad float64, bg color.Color) image.Image {
+	inner := px - 2*int(float64(px)*pad+0.5)
+	if inner < 1 {
+		inner = 1
+	}
+	fitted := imaging.Fit(img, inner, inner, imaging.Lanczos)
+	return imaging.PasteCenter(imaging.New(px, px, bg), fitted)
+}
+
+func encodePNGBytes(img image.Image) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	err := enc.Encode(buf, img)
+	return buf.Bytes(), err
+}
+
+type icnsEntry struct {
+	typ  string
+	data []byte
+}
+
+// writeICNS writes an Apple icon container: "icns" + total length, then
+// one (type, length, payload) chunk per entry. Payloads here are PNG.
+func writeICNS(w io.Writer, entries []icnsEntry) error {
+	total := 8
+	for _, e := range entries {
+		total += 8 + len(e.data)
+	}
+	hdr := make([]byte, 8)
+	copy(hdr, "icns")
+	binary.BigEndian.PutUint32(hdr[4:], uint32(total))
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if len(e.typ) != 4 {
+			return fmt.Errorf("invalid icns type %q", e.typ)
+		}
+		copy(hdr, e.typ)
+		binary.BigEndian.PutUint32(hdr[4:], uint32(8+len(e.data)))
+		if _, err := w.Write(hdr); err != nil {
+			return err
+		}
+		if _, err := w.Write(e.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePNGFile(path string, img image.Image) ([]byte, error) {
+	data, err := encodePNGBytes(img)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return data, os.WriteFile(path, data, 0644)
+}
+
+// generateIconSet renders every icon size for inPath under outDir/<name>-icons.
+func generateIconSet(inPath, outDir string) (string, error) {
+	img, err := loadImageApplyEXIF(inPath)
+	if err != nil {
+		return "", fmt.Errorf("load failed: %v", err)
+	}
+
+	base := filepath.Base(inPath)
+	name := base[:len(base)-len(filepath.Ext(base))]
+	root := uniqueOutputPath(filepath.Join(outDir, name+"-icons"))
+
+	// macOS
+	var entries []icnsEntry
+	for _, s := range macIconSizes {
+		data, err := writePNGFile(filepath.Join(root, name+".iconset", s.file),
+			iconCanvas(img, s.px, macIconPadding, color.Transparent))
+		if err != nil {
+			return "", fmt.Errorf("write failed: %v", err)
+		}
+		entries = append(entries, icnsEntry{s.icnsType, data})
+	}
+	icns := &bytes.Buffer{}
+	if err := writeICNS(icns, entries); err != nil {
+		return "", fmt.Errorf("icns failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, name+".icns"), icns.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("write failed: %v", err)
+	}
+
+	// iOS: App Store rejects icons with alpha, so flatten onto white.
+	type iosImage struct {
+		Filename string `json:"filename"`
+		Idiom    string `json:"idiom"`
+		Scale    string `json:"scale"`
+		Size     string `json:"size"`
+	}
+	var contents struct {
+		Images []iosImage `json:"images"`
+		Info   struct {
+			Author  string `json:"author"`
+			Version int    `json:"version"`
+		} `json:"info"`
+	}
+	iosDir := filepath.Join(root, "AppIcon.appiconset")
+	for _, s := range iosIconSizes {
+		px := int(s.size*float64(s.scale) + 0.5)
+		pt := fmt.Sprintf("%g", s.size)
+		file := fmt.Sprintf("AppIcon-%s@%dx.png", pt, s.scale)
+		if _, err := writePNGFile(filepath.Join(iosDir, file),
+			iconCanvas(img, px, iosIconPadding, color.White)); err != nil {
+			return "", fmt.Errorf("write failed: %v", err)
+		}
+		contents.Images = append(contents.Images, iosImage{
+			Filename: file,
+			Idiom:    s.idiom,
+			Scale:    fmt.Sprintf("%dx", s.scale),
+			Size:     pt + "x" + pt,
+		})
+	}
+	contents.Info.Author = "xcode"
+	contents.Info.Version = 1
+	js, err := json.MarshalIndent(contents, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(iosDir, "Contents.json"), js, 0644); err != nil {
+		return "", fmt.Errorf("write failed: %v", err)
+	}
+
+	// Android, plus the 512px Play Store listing icon.
+	for _, s := range androidIconSizes {
+		if _, err := writePNGFile(filepath.Join(root, "android", s.dir, "ic_launcher.png"),
+			iconCanvas(img, s.px, androidIconPadding, color.Transparent)); err != nil {
+			return "", fmt.Errorf("write failed: %v", err)
+		}
+	}
+	if _, err := writePNGFile(filepath.Join(root, "android", "playstore-icon.png"),
+		iconCanvas(img, 512, androidIconPadding, color.Transparent)); err != nil {
+		return "", fmt.Errorf("write failed: %v", err)
+	}
+
+	return fmt.Sprintf("OK %s -> %s (icon set)", inPath, root), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+)
+
+//
+// Duplicate inputs
+// - Before a run, "Review duplicate inputs" finds exact copies (same
+//   SHA-256) and near-duplicates (perceptual hash within dupThreshold:
+//   burst shots, re-saves, resized copies); unreadable files never match
+// - Each group keeps one file ticked: the first of a set of copies, the
+//   largest of a near-duplicate group (usually the best quality). The rest
+//   is what the report counts as wasted space
+// - "Skip All Duplicates" drops every unticked file from the run in one
+//   click; "Compress" honours the ticks as edited
+//
+
+// dupGroup is a set of inputs that are copies or near-copies of each other.
+type dupGroup struct {
+	paths  []string // kept first
+	exact  bool
+	wasted int64 // bytes of all but the kept file
+}
+
+// findInputDuplicates groups paths into exact copies, then groups one file
+// of each distinct content into near-duplicates.
+func findInputDuplicates(paths []string) []dupGroup {
+	sizes := make(map[string]int64, len(paths))
+	bySum := map[string][]string{}
+	var sums []string // first-seen order
+	var unique []string
+	for _, p := range paths {
+		sum, size, err := hashFile(p)
+		if err != nil {
+			continue
+		}
+		sizes[p] = size
+		if _, seen := bySum[sum]; !seen {
+			sums = append(sums, sum)
+			unique = append(unique, p)
+		}
+		bySum[sum] = append(bySum[sum], p)
+	}
+	var groups []dupGroup
+	for _, sum := range sums {
+		if copies := bySum[sum]; len(copies) > 1 {
+			groups = append(groups, dupGroup{paths: copies, exact: true, wasted: sizes[copies[0]] * int64(len(copies)-1)})
+		}
+	}
+	// near-duplicates are looked for among one file of each exact group,
+	// so no extra copy is listed twice
+	hashes, ok := hashImages(unique)
+	for _, idx := range groupNearDuplicates(hashes, ok, dupThreshold) {
+		g := dupGroup{}
+		keep := idx[0]
+		for _, i := range idx {
+			if sizes[unique[i]] > sizes[unique[keep]] {
+				keep = i
+			}
+		}
+		g.paths = append(g.paths, unique[keep])
+		for _, i := range idx {
+			if i != keep {
+				g.paths = append(g.paths, unique[i])
+				g.wasted += sizes[unique[i]]
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// fileSize is path's size, or 0 when it cannot be read.
+func fileSize(path string) int64 {
+	if info, err := os.Stat(path); err == nil {
+		return info.Size()
+	}
+	return 0
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+//
+// Integrity manifests
+// - For archives that have to stay provably complete, "Write a signed
+//   integrity manifest" puts batch-<time>.manifest.json in each output
+//   folder a run writes to: every output's path, size and SHA-256, the
+//   run's settings and the app version
+// - A detached Ed25519 signature goes next to it (.sig, base64). The key is
+//   made on first use and kept in the OS secret store, or in a 0600 file in
+//   the app's storage where there is none; the manifest carries the public
+//   key and the UI shows its fingerprint
+// - "Verify Archive..." checks every manifest in a folder: the signature,
+//   whether it is this app's key, and each file's presence, size and hash
+//
+
+const (
+	integritySuffix   = ".manifest.json"
+	signingKeyAccount = "manifest-signing-key"
+	signingKeyFile    = "manifest-signing.key"
+)
+
+type integrityFile struct {
+	Path   string `json:"path"` // relative to the manifest, with forward slashes
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type integrityManifest struct {
+	App       string            `json:"app"`
+	Version   string            `json:"version"`
+	Created   time.Time         `json:"created"`
+	Settings  map[string]string `json:"settings"`
+	PublicKey string            `json:"publicKey"` // base64 Ed25519
+	Files     []integrityFile   `json:"files"`
+}
+
+// packagedVersion is the app bundle's version, set by the window at start.
+var packagedVersion string
+
+// appVersion is the packaged version, else the module's build version.
+func appVersion() string {
+	if packagedVersion != "" {
+		return packagedVersion
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// keyFromHex reads a stored key seed.
+func keyFromHex(s string) (ed25519.PrivateKey, error) {
+	seed, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key is corrupt")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// loadSigningKey loads the manifest key without making one; key is nil
+// when none was made yet. A keychain or file error is returned, never
+// taken for a missing key.
+func loadSigningKey(storageDir string) (ed25519.PrivateKey, error) {
+	s, err := getSecret(signingKeyAccount)
+	if err != nil {
+		return nil, fmt.Errorf("signing key unreadable: %v", err)
+	}
+	if s != "" {
+		return keyFromHex(s)
+	}
+	data, err := os.ReadFile(filepath.Join(storageDir, signingKeyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signing key unreadable: %v", err)
+	}
+	return keyFromHex(string(data))
+}
+
+// signingKey loads the manifest key, making it on first use: only once
+// both the keychain and the key file say there is none.
+func signingKey(storageDir string) (ed25519.PrivateKey, error) {
+	if key, err := loadSigningKey(storageDir); key != nil || err != nil {
+		return key, err
+	}
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("signing key failed: %v", err)
+	}
+	if err := setSecret(signingKeyAccount, hex.EncodeToString(seed)); err != nil {
+		if err := os.MkdirAll(storageDir, 0700); err != nil {
+			return nil, fmt.Errorf("signing key failed: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(storageDir, signingKeyFile), []byte(hex.EncodeToString(seed)), 0600); err != nil {
+			return nil, fmt.Errorf("signing key failed: %v", err)
+		}
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// keyFingerprint is a short, readable id for a public key.
+func keyFingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return fmt.Sprintf("%X", sum[:8])
+}
+
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// writeIntegrityManifest hashes outputs, which lie under dir, and writes
+// the signed manifest there. It returns the manifest's path.
+func writeIntegrityManifest(dir string, outputs []string, settings map[string]string, key ed25519.PrivateKey, created time.Time) (string, error) {
+	m := integrityManifest{
+		App: "Image Compressor", Version: appVersion(), Created: created.UTC().Truncate(time.Second),
+		Settings: settings, PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+	}
+	seen := map[string]bool{}
+	for _, p := range outputs {
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		sum, size, err := hashFile(p)
+		if err != nil {
+			return "", fmt.Errorf("integrity manifest failed: %v", err)
+		}
+		m.Files = append(m.Files, integrityFile{Path: filepath.ToSlash(rel), Size: size, SHA256: sum})
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := uniqueOutputPath(filepath.Join(dir, "batch-"+created.Format("20060102-150405")+integritySuffix))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("integrity manifest failed: %v", err)
+	}
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+	if err := os.WriteFile(path+".sig", []byte(sig+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("integrity signature failed: %v", err)
+	}
+	return path, nil
+}
+
+// integrityReport is the outcome of checking one manifest.
+type integrityReport struct {
+	manifest string
+	signed   bool // the signature matches the manifest's key
+	ours     bool // and that key is this app's
+	files    int
+	problems []string
+}
+
+func (r integrityReport) String() string {
+	trust := "signature INVALID"
+	switch {
+	case r.signed && r.ours:
+		trust = "signed by this app"
+	case r.signed:
+		trust = "signed by another key"
+	}
+	s := fmt.Sprintf("%s: %d files, %s", filepath.Base(r.manifest), r.files, trust)
+	if len(r.problems) == 0 {
+		return s + ", all intact"
+	}
+	return s + fmt.Sprintf(", %d problems:\n  ", len(r.problems)) + strings.Join(r.problems, "\n  ")
+}
+
+// verifyIntegrity checks the manifest at path against its signature and
+// the files it lists; trusted is this app's public key.
+func verifyIntegrity(path string, trusted ed25519.PublicKey) (integrityReport, error) {
+	r := integrityReport{manifest: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	var m integrityManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return r, fmt.Errorf("%s is not a manifest: %v", filepath.Base(path), err)
+	}
+	pub, _ := base64.StdEncoding.DecodeString(m.PublicKey)
+	sigText, _ := os.ReadFile(path + ".sig")
+	sig, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigText)))
+	r.signed = len(pub) == ed25519.PublicKeySize && ed25519.Verify(pub, data, sig)
+	r.ours = r.signed && trusted != nil && trusted.Equal(ed25519.PublicKey(pub))
+	r.files = len(m.Files)
+	dir := filepath.Dir(path)
+	for _, f := range m.Files {
+		p := filepath.Join(dir, filepath.FromSlash(f.Path))
+		sum, size, err := hashFile(p)
+		switch {
+		case os.IsNotExist(err):
+			r.problems = append(r.problems, f.Path+": missing")
+		case err != nil:
+			r.problems = append(r.problems, f.Path+": "+err.Error())
+		case size != f.Size:
+			r.problems = append(r.problems, fmt.Sprintf("%s: size %d, expected %d", f.Path, size, f.Size))
+		case sum != f.SHA256:
+			r.problems = append(r.problems, f.Path+": contents changed")
+		}
+	}
+	return r, nil
+}
+
+// findIntegrityManifests lists the manifests in dir and below it.
+func findIntegrityManifests(dir string) []string {
+	var found []string
+	filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(d.Name(), integritySuffix) {
+			found = append(found, p)
+		}
+		return nil
+	})
+	return found
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+//
+// Already-compressed JPEGs
+// - The quantization tables in a JPEG give away the quality it was saved
+//   at: libjpeg-style encoders scale the standard tables by quality, so the
+//   closest scaled table is the estimate
+// - Re-encoding a JPEG at the same or a higher quality only adds generation
+//   loss and often makes it bigger; with "Copy already-compressed JPEGs" on,
+//   such a source is copied through unchanged instead (metadata included)
+// - Only sources whose pixels would not change otherwise qualify: no
+//   resize, rotation or colour work
+//
+
+// stdLuminanceQuant is the baseline luminance table from the JPEG spec
+// (Annex K), in zig-zag order as stored in DQT segments.
+var stdLuminanceQuant = [64]int{
+	16, 11, 12, 14, 12, 10, 16, 14, 13, 14, 18, 17, 16, 19, 24, 40,
+	26, 24, 22, 22, 24, 49, 35, 37, 29, 40, 58, 51, 61, 60, 57, 51,
+	56, 55, 64, 72, 92, 78, 64, 68, 87, 69, 55, 56, 80, 109, 81, 87,
+	95, 98, 103, 104, 103, 62, 77, 113, 121, 112, 100, 120, 92, 101, 103, 99,
+}
+
+// readLuminanceQuant returns the first quantization table of a JPEG file.
+func readLuminanceQuant(path string) ([64]int, error) {
+	var table [64]int
+	f, err := os.Open(path)
+	if err != nil {
+		return table, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xFF, 0xD8} {
+		return table, fmt.Errorf("not a JPEG")
+	}
+	for {
+		var hdr [4]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return table, fmt.Errorf("no quantization table")
+		}
+		if hdr[0] != 0xFF {
+			return table, fmt.Errorf("corrupt JPEG marker")
+		}
+		marker, n := hdr[1], int(binary.BigEndian.Uint16(hdr[2:]))-2
+		if marker == 0xDA || n < 0 { // start of scan: tables come before it
+			return table, fmt.Errorf("no quantization table")
+		}
+		seg := make([]byte, n)
+		if _, err := io.ReadFull(r, seg); err != nil {
+			return table, err
+		}
+		if marker != 0xDB || len(seg) < 1 {
+			continue
+		}
+		precision := seg[0] >> 4 // 0: 8-bit entries, 1: 16-bit
+		for i := range table {
+			if precision == 0 && 1+i < len(seg) {
+				table[i] = int(seg[1+i])
+			} else if precision == 1 && 2+2*i < len(seg) {
+				table[i] = int(binary.BigEndian.Uint16(seg[1+2*i:]))
+			}
+		}
+		return table, nil
+	}
+}
+
+// scaledQuant is libjpeg's table for quality q.
+func scaledQuant(q int) [64]int {
+	scale := 200 - 2*q
+	if q < 50 {
+		scale = 5000 / q
+	}
+	var t [64]int
+	for i, v := range stdLuminanceQuant {
+		t[i] = min(255, max(1, (v*scale+50)/100))
+	}
+	return t
+}
+
+// estimateJPEGQuality is the libjpeg quality whose luminance table is
+// closest to the file's.
+func estimateJPEGQuality(path string) (int, error) {
+	table, err := readLuminanceQuant(path)
+	if err != nil {
+		return 0, err
+	}
+	best, bestDiff := 0, -1
+	for q := 1; q <= 100; q++ {
+		diff := 0
+		for i, v := range scaledQuant(q) {
+			d := v - table[i]
+			diff += max(d, -d)
+		}
+		if bestDiff < 0 || diff < bestDiff {
+			best, bestDiff = q, diff
+		}
+	}
+	return best, nil
+}
+
+// alreadyCompressed reports whether re-encoding path would only lose
+// quality: it is saved at or below quality, or already fits the target.
+// q is the source's estimated quality.
+func alreadyCompressed(path string, quality, targetKB int) (q int, ok bool, why string) {
+	q, err := estimateJPEGQuality(path)
+	if err != nil {
+		return 0, false, ""
+	}
+	if targetKB > 0 {
+		info, err := os.Stat(path)
+		if err != nil || info.Size() > int64(targetKB)*1024 {
+			return q, false, ""
+		}
+		return q, true, fmt.Sprintf("copied: already under %dKB, source q≈%d", targetKB, q)
+	}
+	if q <= quality {
+		return q, true, fmt.Sprintf("copied: source q≈%d is at or below q=%d", q, quality)
+	}
+	return q, false, ""
+}
+
+// copyFile copies src to dst, replacing dst.
+func copyFile(src, dst string) error {
+	in, err := disk.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := disk.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		disk.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
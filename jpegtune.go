@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+//
+// Advanced JPEG options
+// - Go's encoder writes baseline JPEGs with the standard Huffman tables, so
+//   these knobs are applied afterwards by jpegtran (libjpeg-turbo/mozjpeg),
+//   which rewrites the file losslessly
+// - Restart markers let CDNs and range-request clients resume decoding
+//   mid-file; a scan script controls the progressive passes
+//
+
+type jpegTuning struct {
+	optimize    bool   // optimized Huffman tables
+	progressive bool   //
+	restart     int    // restart interval in MCU rows; 0 = none
+	scans       string // jpegtran scan script file, progressive only
+}
+
+func (t jpegTuning) identity() bool {
+	return t == jpegTuning{}
+}
+
+func (t jpegTuning) args() []string {
+	args := []string{"-copy", "all"}
+	if t.optimize {
+		args = append(args, "-optimize")
+	}
+	if t.progressive {
+		args = append(args, "-progressive")
+		if t.scans != "" {
+			args = append(args, "-scans", t.scans)
+		}
+	}
+	if t.restart > 0 {
+		args = append(args, "-restart", strconv.Itoa(t.restart))
+	}
+	return args
+}
+
+func (t jpegTuning) String() string {
+	var parts []string
+	if t.optimize {
+		parts = append(parts, "optimized")
+	}
+	if t.progressive {
+		parts = append(parts, "progressive")
+	}
+	if t.restart > 0 {
+		parts = append(parts, fmt.Sprintf("restart %d", t.restart))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// validate checks the options before a run rather than failing every file.
+func (t jpegTuning) validate() error {
+	if t.identity() {
+		return nil
+	}
+	if _, err := exec.LookPath("jpegtran"); err != nil {
+		return fmt.Errorf("advanced JPEG options need jpegtran in PATH (from libjpeg-turbo or mozjpeg)")
+	}
+	if t.scans != "" {
+		if !t.progressive {
+			return fmt.Errorf("a scan script only applies to progressive JPEGs")
+		}
+		if _, err := os.Stat(t.scans); err != nil {
+			return fmt.Errorf("scan script: %v", err)
+		}
+	}
+	if t.restart < 0 {
+		return fmt.Errorf("restart interval must be 0 or more")
+	}
+	return nil
+}
+
+// tuneJPEG rewrites data through jpegtran; identity options return it unchanged.
+func tuneJPEG(data []byte, t jpegTuning) ([]byte, error) {
+	if t.identity() {
+		return data, nil
+	}
+	cmd := exec.Command("jpegtran", t.args()...)
+	cmd.Stdin = bytes.NewReader(data)
+	var out, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("jpegtran failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), nil
+}
+
+// encodeTunedJPEG encodes img and pipes it through jpegtran into w, so
+// neither the plain nor the tuned JPEG is held in memory.
+func encodeTunedJPEG(w io.Writer, img image.Image, q int, t jpegTuning) error {
+	pr, pw := io.Pipe()
+	cmd := exec.Command("jpegtran", t.args()...)
+	var stderr bytes.Buffer
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = pr, w, &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("jpegtran failed: %v", err)
+	}
+	encoded := make(chan error, 1)
+	go func() {
+		err := jpeg.Encode(pw, img, &jpeg.Options{Quality: q})
+		pw.CloseWithError(err)
+		encoded <- err
+	}()
+	err := cmd.Wait()
+	pr.Close() // unblocks the encoder if jpegtran quit early
+	if eerr := <-encoded; eerr != nil && eerr != io.ErrClosedPipe {
+		return fmt.Errorf("compress failed: %v", eerr)
+	}
+	if err != nil {
+		return fmt.Errorf("jpegtran failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+//
+// Run log
+// - One line per processed file, appended under the progress bar instead of
+//   overwriting the status label, so earlier results stay readable
+// - Copy and Save export the whole log as plain text
+//
+
+type logPane struct {
+	lines []string
+	list  *widget.List
+}
+
+func newLogPane() *logPane {
+	l := &logPane{}
+	l.list = widget.NewList(
+		func() int { return len(l.lines) },
+		func() fyne.CanvasObject { return widget.NewLabel("template") },
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(l.lines[id])
+		},
+	)
+	return l
+}
+
+// add appends a timestamped line and keeps the newest one in view.
+func (l *logPane) add(format string, args ...any) {
+	l.lines = append(l.lines, time.Now().Format("15:04:05 ")+fmt.Sprintf(format, args...))
+	l.list.Refresh()
+	l.list.ScrollToBottom()
+}
+
+func (l *logPane) text() string {
+	return strings.Join(l.lines, "\n") + "\n"
+}
+
+// view is the log with its Copy/Save/Clear buttons.
+func (l *logPane) view(w fyne.Window) fyne.CanvasObject {
+	copyBtn := widget.NewButton("Copy Log", func() {
+		fyne.CurrentApp().Clipboard().SetContent(l.text())
+	})
+	saveBtn := widget.NewButton("Save Log...", func() {
+		d := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+			if err != nil || wc == nil {
+				return
+			}
+			defer wc.Close()
+			if _, err := io.WriteString(wc, l.text()); err != nil {
+				dialog.ShowError(err, w)
+			}
+		}, w)
+		d.SetFileName("compression-log.txt")
+		d.Show()
+	})
+	clearBtn := widget.NewButton("Clear Log", func() {
+		l.lines = nil
+		l.list.Refresh()
+	})
+	// the list has no height of its own inside the options VBox
+	height := canvas.NewRectangle(color.Transparent)
+	height.SetMinSize(fyne.NewSize(0, 180))
+	return container.NewBorder(nil, container.NewHBox(copyBtn, saveBtn, clearBtn), nil, nil,
+		container.NewStack(height, l.list))
+}
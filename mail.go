@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+//
+// Share via Mail
+// - Compresses to a temp folder so the whole set fits in one message
+// - Opens a new Apple Mail message with the results attached (macOS only)
+//
+
+const (
+	mailBudgetKB  = 18 * 1024 // 25MB provider limits shrink by a third once base64-encoded
+	mailMaxFileKB = 2 * 1024
+	mailMaxEdge   = 2048
+)
+
+// mailOptions spreads the message budget across n images.
+func mailOptions(n int) compressOptions {
+	per := mailBudgetKB / n
+	if per > mailMaxFileKB {
+		per = mailMaxFileKB
+	}
+	return compressOptions{targetKB: per, maxW: mailMaxEdge, maxH: mailMaxEdge, format: "jpeg"}
+}
+
+// mailScript attaches every argument to a new visible outgoing message.
+const mailScript = `on run argv
+	tell application "Mail"
+		set msg to make new outgoing message with properties {visible:true}
+		tell msg
+			repeat with p in argv
+				make new attachment with properties {file name:(POSIX file p)} at after the last paragraph
+			end repeat
+		end tell
+		activate
+	end tell
+end run`
+
+func composeMail(paths []string) error {
+	if runtime.GOOS != "darwin" {
+		return fmt.Errorf("sharing via Mail needs Apple Mail on macOS; the files are in %s", filepath.Dir(paths[0]))
+	}
+	args := append([]string{"-e", mailScript}, paths...)
+	if out, err := exec.Command("osascript", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("could not open Mail: %v %s", err, out)
+	}
+	return nil
+}
+
+// shareViaMail compresses images for email and opens a message with them.
+// transforms holds the per-item rotate/flip fixes.
+func shareViaMail(images []string, transforms map[string]itemTransform) (string, error) {
+	dir, err := scratch.dir("mail")
+	if err != nil {
+		return "", err
+	}
+	base := mailOptions(len(images))
+	var attachments []string
+	var total int64
+	for _, f := range images {
+		opts := base
+		opts.transform = transforms[f]
+		name := filepath.Base(f)
+		name = name[:len(name)-len(filepath.Ext(name))]
+		res, err := processImageSync(context.Background(), f, uniqueOutputPath(filepath.Join(dir, name+".jpg")), opts)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", f, err)
+		}
+		attachments = append(attachments, res.outPath)
+		total += res.outSize
+	}
+	if err := composeMail(attachments); err != nil {
+		return "", err
+	}
+	msg := fmt.Sprintf("Mail message opened with %d attachments (%.1fMB)", len(attachments), float64(total)/(1024*1024))
+	if total > mailBudgetKB*1024 {
+		msg += fmt.Sprintf(" — over the %dMB email budget, some providers may reject it", mailBudgetKB/1024)
+	}
+	return msg, nil
+}
@@ -1,15 +1,19 @@
+//go:build !headless
+
 package main
 
 import (
-	"bytes"
+	"context"
+	"crypto/ed25519"
 	"fmt"
 	"image"
-	"image/jpeg"
-	"io/fs"
-	"io/ioutil"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -17,9 +21,6 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
-
-	"github.com/disintegration/imaging"
-	"github.com/rwcarlsen/goexif/exif"
 )
 
 //
@@ -29,187 +30,174 @@ import (
 // - Compiles across Fyne versions because no RunOnMain/CallOnMain/Invoke used
 //
 
-// Prevent overwrite: if "name.jpg" exists → use "name (1).jpg", etc.
-func uniqueOutputPath(path string) string {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return path
-	}
-
-	dir := filepath.Dir(path)
-	base := filepath.Base(path)
-	ext := filepath.Ext(base)
-	name := base[:len(base)-len(ext)]
-
-	counter := 1
-	for {
-		newName := fmt.Sprintf("%s (%d)%s", name, counter, ext)
-		newPath := filepath.Join(dir, newName)
-		if _, err := os.Stat(newPath); os.IsNotExist(err) {
-			return newPath
-		}
-		counter++
-	}
-}
-
-// Load image and correct EXIF rotation
-func loadImageApplyEXIF(path string) (image.Image, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	img, err := imaging.Decode(f)
-	f.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	// Read EXIF again for orientation
-	ef, err := os.Open(path)
-	if err != nil {
-		return img, nil
-	}
-	ex, err := exif.Decode(ef)
-	ef.Close()
-	if err != nil {
-		return img, nil // no EXIF → fine
-	}
-
-	orientTag, err := ex.Get(exif.Orientation)
-	if err != nil {
-		return img, nil
-	}
-	orient, err := orientTag.Int(0)
-	if err != nil {
-		return img, nil
-	}
-
-	switch orient {
-	case 3:
-		img = imaging.Rotate180(img)
-	case 6:
-		img = imaging.Rotate270(img)
-	case 8:
-		img = imaging.Rotate90(img)
-	}
-
-	return img, nil
-}
-
-// Encode to JPEG with a given quality
-func encodeJPEGBytes(img image.Image, q int) ([]byte, error) {
-	buf := &bytes.Buffer{}
-	err := jpeg.Encode(buf, img, &jpeg.Options{Quality: q})
-	return buf.Bytes(), err
-}
-
-// Binary-search quality for target size
-func findQualityForTarget(img image.Image, targetBytes int) ([]byte, int, error) {
-	lo, hi := 10, 95
-	var best []byte
-	var bestQ int
-
-	for lo <= hi {
-		mid := (lo + hi) / 2
-		data, err := encodeJPEGBytes(img, mid)
-		if err != nil {
-			return nil, 0, err
-		}
-		if len(data) <= targetBytes {
-			best = data
-			bestQ = mid
-			lo = mid + 1
-		} else {
-			hi = mid - 1
-		}
-	}
-
-	if best == nil {
-		data, err := encodeJPEGBytes(img, 10)
-		return data, 10, err
-	}
-
-	return best, bestQ, nil
-}
-
-func listImages(root string) ([]string, error) {
-	var files []string
-	exts := map[string]bool{
-		".jpg": true, ".jpeg": true, ".png": true, ".webp": true,
-		".bmp": true, ".tiff": true,
-	}
-
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if !d.IsDir() && exts[filepath.Ext(path)] {
-			files = append(files, path)
-		}
-		return nil
-	})
-	sort.Strings(files)
-	return files, err
-}
-
-// processImageSync does the actual work synchronously on the main thread.
-func processImageSync(inPath, outPath string, targetKB, maxW, maxH int) (string, error) {
-	img, err := loadImageApplyEXIF(inPath)
-	if err != nil {
-		return "", fmt.Errorf("load failed: %v", err)
-	}
-
-	// resize
-	if maxW > 0 || maxH > 0 {
-		img = imaging.Fit(img, maxW, maxH, imaging.Lanczos)
-	}
-
-	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
-		return "", fmt.Errorf("mkdir failed: %v", err)
-	}
-
-	if targetKB <= 0 {
-		// save jpeg with quality 85
-		if err := imaging.Save(img, outPath, imaging.JPEGQuality(85)); err != nil {
-			return "", fmt.Errorf("save failed: %v", err)
-		}
-		info, _ := os.Stat(outPath)
-		return fmt.Sprintf("OK %s -> %s (%dKB)", inPath, outPath, info.Size()/1024), nil
-	}
-
-	// target mode
-	targetBytes := targetKB * 1024
-	data, q, err := findQualityForTarget(img, targetBytes)
-	if err != nil {
-		return "", fmt.Errorf("compress failed: %v", err)
-	}
-	if err := ioutil.WriteFile(outPath, data, 0644); err != nil {
-		return "", fmt.Errorf("write failed: %v", err)
-	}
-	return fmt.Sprintf("OK %s -> %s (q=%d, %dKB)", inPath, outPath, q, len(data)/1024), nil
-}
-
 func main() {
+	defer reportPanic()
 	a := app.NewWithID("com.sanyam.imagecompressor")
+	packagedVersion = a.Metadata().Version
+	loadCodecs() // detect formats and external tools once, up front
 	w := a.NewWindow("Image Compressor (macOS) — Simple")
 	w.Resize(fyne.NewSize(1000, 650))
 
 	var items []string
 	selectedIndex := -1
+	selection := newQueueSelection()     // rows picked for Start (Selected Only)
+	perItem := map[string]itemSettings{} // manual edits per file
+	statuses := map[string]string{}      // last run's resultStatus per input file
+	queueDir := a.Storage().RootURI().Path()
+	crashDir = filepath.Join(queueDir, "crashes")
+	var showPreview func(path string)
+
+	// statsLabel heads the list with the queue's file count and sizes
+	statsLabel := widget.NewLabel("Queue is empty")
+	var results []fileResult
+	var runTimings *stepTimer // the last run's time by step
+	queueIdx := newQueueIndex()
+	stats := &coalescer{fn: func() { statsLabel.SetText(queueStats(queueIdx, items, perItem, results)) }}
 
 	// List widget
 	list := widget.NewList(
 		func() int { return len(items) },
-		func() fyne.CanvasObject { return widget.NewLabel("template") },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewCheck("", nil), nil, widget.NewLabel("template"))
+		},
 		func(i widget.ListItemID, o fyne.CanvasObject) {
 			if i >= 0 && i < len(items) {
-				o.(*widget.Label).SetText(filepath.Base(items[i]))
+				row := o.(*fyne.Container)
+				p := items[i]
+				check := row.Objects[1].(*widget.Check)
+				check.OnChanged = nil // SetChecked below must not count as a click
+				check.SetChecked(!perItem[p].excluded)
+				check.OnChanged = func(on bool) {
+					st := perItem[p]
+					st.excluded = !on
+					perItem[p] = st
+					stats.request()
+				}
+				label := filepath.Base(p)
+				if desc := perItem[p].String(); desc != "" {
+					label += " — " + desc
+				}
+				if selection.multiple() && selection.has(p) {
+					label = "● " + label
+				}
+				text := row.Objects[0].(*widget.Label)
+				text.Importance = widget.MediumImportance
+				if s, ok := statuses[items[i]]; ok {
+					if strings.HasPrefix(s, "error") {
+						s = "failed"
+					}
+					if s == resultStatus(fileResult{lowQuality: true}) || s == resultStatus(fileResult{suggestQ: 1}) {
+						label = "⚠ " + label
+						text.Importance = widget.WarningImportance
+					}
+					label += "  [" + s + "]"
+				}
+				text.SetText(label)
 			}
 		},
 	)
 
+	emptyHint := emptyQueueHint()
+	listRefresh := &coalescer{fn: func() {
+		list.Refresh()
+		stats.fn()
+		if len(items) == 0 {
+			emptyHint.Show()
+		} else {
+			emptyHint.Hide()
+		}
+	}}
+	refreshList := listRefresh.request
+	// tickAll sets every entry's checkbox through pick(currently ticked)
+	tickAll := func(pick func(bool) bool) {
+		for _, p := range items {
+			st := perItem[p]
+			st.excluded = !pick(!st.excluded)
+			perItem[p] = st
+		}
+		refreshList()
+	}
+	selectAllBtn := widget.NewButton("All", func() { tickAll(func(bool) bool { return true }) })
+	selectNoneBtn := widget.NewButton("None", func() { tickAll(func(bool) bool { return false }) })
+	invertBtn := widget.NewButton("Invert", func() { tickAll(func(on bool) bool { return !on }) })
+
 	preview := canvas.NewText("No preview selected", nil)
 	previewContainer := container.NewCenter(preview)
 
+	outEntry := widget.NewEntry()
+	outEntry.SetPlaceHolder("Output folder, e.g. ~/Pictures/Exports/{date} or {input_folder}/compressed")
+
+	// recent folders: a dropdown for inputs, quick-pick buttons for outputs
+	prefs := a.Preferences()
+	recentInSelect := widget.NewSelect(nil, nil)
+	recentInSelect.PlaceHolder = "Recent input folders"
+	recentOutBox := container.NewHBox()
+	refreshRecent := func() {
+		recentInSelect.Options = recentFolders(prefs, recentInputsKey)
+		recentInSelect.Refresh()
+		recentOutBox.RemoveAll()
+		for _, d := range recentFolders(prefs, recentOutputsKey) {
+			btn := widget.NewButton(filepath.Base(d), func() { outEntry.SetText(d) })
+			btn.Importance = widget.LowImportance
+			recentOutBox.Add(btn)
+		}
+	}
+	refreshRecent()
+
+	addFolder := func(root string) {
+		showFolderScan(w, root, func(files []string) {
+			for _, f := range files {
+				queueIdx.forget(f)
+			}
+			items = append(items, files...)
+			grantAccess(prefs, root)
+			rememberFolder(prefs, recentInputsKey, root)
+			refreshRecent()
+			refreshList()
+		})
+	}
+	analyzeBtn := widget.NewButton("Analyze Folder...", func() {
+		d := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			root := uri.Path()
+			grantAccess(prefs, root)
+			showFolderAnalyzer(a, root, func(paths []string) {
+				if len(paths) == 1 && paths[0] == root {
+					addFolder(root)
+					return
+				}
+				for _, p := range paths {
+					queueIdx.forget(p)
+				}
+				items = append(items, paths...)
+				rememberFolder(prefs, recentInputsKey, root)
+				refreshRecent()
+				refreshList()
+			})
+		}, w)
+		d.SetLocation(recentLocation(prefs, recentInputsKey))
+		d.Show()
+	})
+	recentInSelect.OnChanged = func(dir string) {
+		if dir == "" {
+			return
+		}
+		recentInSelect.ClearSelected()
+		addFolder(dir)
+	}
+	addFolderBtn := widget.NewButton("Add Folder...", func() {
+		d := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			addFolder(uri.Path())
+		}, w)
+		d.SetLocation(recentLocation(prefs, recentInputsKey))
+		d.Show()
+	})
+
 	addBtn := widget.NewButton("Add Files/Folders", func() {
 		fd := dialog.NewFileOpen(func(r fyne.URIReadCloser, err error) {
 			if err != nil || r == nil {
@@ -217,161 +205,2120 @@ func main() {
 			}
 			path := r.URI().Path()
 			if info, err := os.Stat(path); err == nil && info.IsDir() {
-				imgs, err := listImages(path)
-				if err == nil {
-					items = append(items, imgs...)
-					list.Refresh()
-				}
+				addFolder(path)
 			} else {
+				queueIdx.forget(path)
 				items = append(items, path)
-				list.Refresh()
+				grantAccess(prefs, path)
+				rememberFolder(prefs, recentInputsKey, filepath.Dir(path))
+				refreshRecent()
+				refreshList()
 			}
 		}, w)
+		fd.SetLocation(recentLocation(prefs, recentInputsKey))
 		fd.Show()
 	})
 
-	outEntry := widget.NewEntry()
-	outEntry.SetPlaceHolder("Select output folder (use Browse...)")
+	// files and folders dropped on the window join the queue like Add Files/Folders
+	var tourDrop func(n int) // set while the first-run tour is open
+	w.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		for _, u := range uris {
+			path := u.Path()
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				addFolder(path)
+				continue
+			}
+			queueIdx.forget(path)
+			items = append(items, path)
+			grantAccess(prefs, path)
+			rememberFolder(prefs, recentInputsKey, filepath.Dir(path))
+		}
+		refreshRecent()
+		refreshList()
+		if tourDrop != nil {
+			tourDrop(len(uris))
+		}
+	})
 
 	browseOutBtn := widget.NewButton("Browse...", func() {
-		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+		d := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
 			if err != nil || uri == nil {
 				return
 			}
+			grantAccess(prefs, uri.Path())
 			outEntry.SetText(uri.Path())
 		}, w)
+		d.SetLocation(recentLocation(prefs, recentOutputsKey))
+		d.Show()
+	})
+
+	qualityPrefs := loadQualityDefaults(a.Preferences())
+	scratch = loadScratchSpace(a.Preferences())
+	perms := loadOutputPerms(a.Preferences())
+	schedules := loadSchedules(a.Preferences())
+	// the slider is the quick way to the JPEG default, e.g. "re-save at q70"
+	qualityLabel := widget.NewLabel(fmt.Sprintf("JPEG quality: %d", qualityPrefs.jpeg))
+	qualitySlider := widget.NewSlider(1, 100)
+	qualitySlider.SetValue(float64(qualityPrefs.jpeg))
+	qualitySlider.OnChanged = func(v float64) {
+		qualityPrefs.jpeg = int(v)
+		qualityLabel.SetText(fmt.Sprintf("JPEG quality: %d", qualityPrefs.jpeg))
+	}
+	qualitySlider.OnChangeEnded = func(float64) {
+		qualityPrefs.save(a.Preferences())
+	}
+	rulesText := prefs.String("rules")
+	rules, rerr := parseRules(rulesText)
+	if rerr != nil {
+		rules = ruleSet{} // saved text was validated; only a newer version's syntax fails
+	}
+	rulesLabel := widget.NewLabel("")
+	showRulesState := func() {
+		if rules.empty() {
+			rulesLabel.SetText("Rules: none")
+		} else {
+			rulesLabel.SetText(fmt.Sprintf("Rules: %d active", len(rules.rules)))
+		}
+	}
+	showRulesState()
+	rulesBtn := widget.NewButton("Rules...", func() {
+		editor := widget.NewMultiLineEntry()
+		editor.SetText(rulesText)
+		editor.SetPlaceHolder("if width > 4000: resize 2560\nif format == \"png\" and alpha: format png\nelse: format jpeg, quality 80")
+		editor.TextStyle = fyne.TextStyle{Monospace: true}
+		errLabel := widget.NewLabel("")
+		errLabel.Wrapping = fyne.TextWrapWord
+		help := widget.NewLabel("Facts: width, height, megapixels, size_kb, format, alpha, name, folder\n" +
+			"Actions: resize, max_width, max_height, format, quality, target_kb, mode, folder, skip")
+		content := container.NewBorder(nil, container.NewVBox(help, errLabel), nil, nil, editor)
+		var d dialog.Dialog
+		save := widget.NewButton("Save", func() {
+			rs, err := parseRules(editor.Text)
+			if err != nil {
+				errLabel.SetText("Error: " + err.Error())
+				return
+			}
+			rules, rulesText = rs, editor.Text
+			prefs.SetString("rules", rulesText)
+			showRulesState()
+			d.Hide()
+		})
+		cancel := widget.NewButton("Cancel", func() { d.Hide() })
+		d = dialog.NewCustomWithoutButtons("Rules", container.NewBorder(nil, container.NewHBox(save, cancel), nil, nil, content), w)
+		d.Resize(fyne.NewSize(640, 420))
+		d.Show()
+	})
+
+	qualityBtn := widget.NewButton("Quality Defaults...", func() {
+		entry := func(v int) *widget.Entry {
+			e := widget.NewEntry()
+			e.SetText(fmt.Sprintf("%d", v))
+			return e
+		}
+		jpegEntry, webpEntry, avifEntry, pngEntry := entry(qualityPrefs.jpeg), entry(qualityPrefs.webp), entry(qualityPrefs.avif), entry(qualityPrefs.pngLevel)
+		heicEntry := entry(qualityPrefs.heic)
+		dialog.ShowForm("Quality Defaults (no target size)", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("JPEG quality (1-100)", jpegEntry),
+			widget.NewFormItem("WebP quality (1-100)", webpEntry),
+			widget.NewFormItem("AVIF quality (1-100)", avifEntry),
+			widget.NewFormItem("HEIC quality (1-100, macOS)", heicEntry),
+			widget.NewFormItem("PNG level (0-9)", pngEntry),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			var d qualityDefaults
+			fmt.Sscanf(jpegEntry.Text, "%d", &d.jpeg)
+			fmt.Sscanf(webpEntry.Text, "%d", &d.webp)
+			fmt.Sscanf(avifEntry.Text, "%d", &d.avif)
+			fmt.Sscanf(pngEntry.Text, "%d", &d.pngLevel)
+			fmt.Sscanf(heicEntry.Text, "%d", &d.heic)
+			qualityPrefs = d.clamped()
+			qualityPrefs.save(a.Preferences())
+			qualitySlider.SetValue(float64(qualityPrefs.jpeg))
+		}, w)
 	})
 
 	targetEntry := widget.NewEntry()
-	targetEntry.SetPlaceHolder("Target size KB (0 = normal JPEG)")
+	targetEntry.SetPlaceHolder("Target size KB (0 = default quality)")
 	widthEntry := widget.NewEntry()
 	widthEntry.SetPlaceHolder("Max width (px)")
 	heightEntry := widget.NewEntry()
 	heightEntry.SetPlaceHolder("Max height (px)")
+	shrinkCheck := widget.NewCheck("Downscale when the target size can't be reached", nil)
+	minWEntry := widget.NewEntry()
+	minWEntry.SetPlaceHolder("Min width (px)")
+	minHEntry := widget.NewEntry()
+	minHEntry.SetPlaceHolder("Min height (px)")
+	placeholderCheck := widget.NewCheck("Write BlurHash/LQIP placeholder sidecars", nil)
+	modeSelect := widget.NewSelect([]string{"Photo", "Document (colour)", "Document (greyscale)", "Document (black & white)", "Auto (screenshots as PNG)"}, nil)
+	modeSelect.SetSelected("Photo")
+	formatNames, formats := []string{"JPEG", "PNG"}, []string{"jpeg", "png"}
+	if heicSupported() {
+		formatNames, formats = append(formatNames, "HEIC (Apple devices)"), append(formats, "heic")
+	}
+	formatSelect := widget.NewSelect(formatNames, nil)
+	formatSelect.SetSelectedIndex(0)
+	pngColorSelect := widget.NewSelect([]string{"Automatic (smallest lossless)", "Truecolor (RGB/RGBA)", "Palette (up to 256 colours)", "Grayscale"}, nil)
+	pngColorSelect.SetSelectedIndex(0)
+	animSelect := widget.NewSelect([]string{"First frame only", "Convert to MP4 (ffmpeg)", "Convert to WebM (ffmpeg)"}, nil)
+	animSelect.SetSelected("First frame only")
+	presetSelect := widget.NewSelect(presetNames(), nil)
+	presetSelect.SetSelected(presets[0].name)
+	stepsLabel := widget.NewLabel(loadPipeline(prefs, presets[0].name).String())
+	stepsLabel.Wrapping = fyne.TextWrapWord
+	presetSelect.OnChanged = func(name string) { stepsLabel.SetText(loadPipeline(prefs, name).String()) }
+	stepsBtn := widget.NewButton("Steps...", func() {
+		name := presetSelect.Selected
+		showStepsEditor(w, name, loadPipeline(prefs, name), func(p pipeline) {
+			savePipeline(prefs, name, p)
+			stepsLabel.SetText(p.String())
+		})
+	})
 
 	progressBar := widget.NewProgressBar()
 	progressBar.Hide()
 	statusLabel := widget.NewLabel("Idle")
+	runLog := newLogPane()
+	for _, p := range restoreAccess(prefs) {
+		runLog.add("Access to %s was not restored; add or browse to it again to grant access", p)
+	}
 
-	startBtn := widget.NewButton("Start Compress (blocking)", func() {
-		if len(items) == 0 {
-			dialog.ShowInformation("No Input", "Add files or folders first.", w)
-			return
+	addURLsBtn := widget.NewButton("Add URLs...", func() {
+		urlsEntry := widget.NewMultiLineEntry()
+		urlsEntry.SetPlaceHolder("One image URL per line")
+		urlsEntry.SetMinRowsVisible(8)
+		loadBtn := widget.NewButton("Load from Text File...", func() {
+			dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+				if err != nil || r == nil {
+					return
+				}
+				defer r.Close()
+				data, err := io.ReadAll(r)
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				urlsEntry.SetText(string(data))
+			}, w)
+		})
+		content := container.NewBorder(nil, loadBtn, nil, nil, urlsEntry)
+		d := dialog.NewCustomConfirm("Add from URLs", "Download", "Cancel", content, func(ok bool) {
+			if !ok {
+				return
+			}
+			urls, err := parseURLList(urlsEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			statusLabel.SetText(fmt.Sprintf("Downloading %d URLs...", len(urls)))
+			paths, errs := downloadImages(urls)
+			items = append(items, paths...)
+			refreshList()
+			statusLabel.SetText(fmt.Sprintf("Downloaded %d of %d URLs", len(paths), len(urls)))
+			if len(errs) > 0 {
+				var lines []string
+				for _, e := range errs {
+					lines = append(lines, e.Error())
+				}
+				dialog.ShowError(fmt.Errorf("%d downloads failed:\n%s", len(errs), strings.Join(lines, "\n")), w)
+			}
+		}, w)
+		d.Resize(fyne.NewSize(640, 360))
+		d.Show()
+	})
+
+	// batch colour adjustments, previewed live on the selected image
+	brightSlider := widget.NewSlider(-100, 100)
+	contrastSlider := widget.NewSlider(-100, 100)
+	saturationSlider := widget.NewSlider(-100, 100)
+	gammaSlider := widget.NewSlider(0.2, 3)
+	gammaSlider.Step = 0.05
+	gammaSlider.SetValue(1)
+	currentAdjust := func() colorAdjust {
+		return colorAdjust{
+			brightness: brightSlider.Value,
+			contrast:   contrastSlider.Value,
+			saturation: saturationSlider.Value,
+			gamma:      gammaSlider.Value,
 		}
-		outFolder := outEntry.Text
-		if outFolder == "" {
-			dialog.ShowInformation("No Output", "Select output folder.", w)
-			return
+	}
+	refreshPreview := func(float64) {
+		if selectedIndex >= 0 && selectedIndex < len(items) {
+			showPreview(items[selectedIndex])
+		}
+	}
+	for _, sl := range []*widget.Slider{brightSlider, contrastSlider, saturationSlider, gammaSlider} {
+		sl.OnChangeEnded = refreshPreview
+	}
+	toneSelect := widget.NewSelect([]string{"Original colour", "Black & white", "Sepia"}, func(string) { refreshPreview(0) })
+	toneSelect.SetSelected("Original colour")
+	vignetteCheck := widget.NewCheck("Vignette", func(bool) { refreshPreview(0) })
+	grainCheck := widget.NewCheck("Film grain", func(bool) { refreshPreview(0) })
+	currentStyle := func() styleFilter {
+		st := styleFilter{vignette: vignetteCheck.Checked, grain: grainCheck.Checked}
+		switch toneSelect.SelectedIndex() {
+		case 1:
+			st.tone = toneMono
+		case 2:
+			st.tone = toneSepia
 		}
+		return st
+	}
+	resetAdjustBtn := widget.NewButton("Reset Adjustments", func() {
+		brightSlider.SetValue(0)
+		contrastSlider.SetValue(0)
+		saturationSlider.SetValue(0)
+		gammaSlider.SetValue(1)
+		toneSelect.SetSelected("Original colour")
+		vignetteCheck.SetChecked(false)
+		grainCheck.SetChecked(false)
+		refreshPreview(0)
+	})
+	optimizeCheck := widget.NewCheck("Optimized Huffman tables", nil)
+	progressiveCheck := widget.NewCheck("Progressive", nil)
+	restartEntry := widget.NewEntry()
+	restartEntry.SetPlaceHolder("0 = none")
+	scansEntry := widget.NewEntry()
+	scansEntry.SetPlaceHolder("Scan script file (progressive only)")
+	currentJPEG := func() jpegTuning {
+		t := jpegTuning{optimize: optimizeCheck.Checked, progressive: progressiveCheck.Checked, scans: strings.TrimSpace(scansEntry.Text)}
+		fmt.Sscanf(restartEntry.Text, "%d", &t.restart)
+		return t
+	}
+	jpegPanel := widget.NewAccordion(widget.NewAccordionItem("Advanced JPEG (needs jpegtran)", widget.NewForm(
+		widget.NewFormItem("", container.NewHBox(optimizeCheck, progressiveCheck)),
+		widget.NewFormItem("Restart interval (MCU rows)", restartEntry),
+		widget.NewFormItem("Scan script", scansEntry),
+	)))
 
-		// parse options
-		targetKB := 0
-		fmt.Sscanf(targetEntry.Text, "%d", &targetKB)
-		maxW := 0
-		fmt.Sscanf(widthEntry.Text, "%d", &maxW)
-		maxH := 0
-		fmt.Sscanf(heightEntry.Text, "%d", &maxH)
+	adjustPanel := widget.NewAccordion(widget.NewAccordionItem("Colour and Style (whole batch)", container.NewVBox(
+		widget.NewForm(
+			widget.NewFormItem("Brightness", brightSlider),
+			widget.NewFormItem("Contrast", contrastSlider),
+			widget.NewFormItem("Saturation", saturationSlider),
+			widget.NewFormItem("Gamma", gammaSlider),
+			widget.NewFormItem("Style", toneSelect),
+			widget.NewFormItem("", container.NewHBox(vignetteCheck, grainCheck)),
+		),
+		resetAdjustBtn,
+	)))
 
-		// expand items
-		var images []string
-		for _, p := range items {
-			if info, err := os.Stat(p); err == nil && info.IsDir() {
-				imgs, err := listImages(p)
-				if err == nil {
-					images = append(images, imgs...)
-				}
-			} else {
-				images = append(images, p)
-			}
+	dupCheck := widget.NewCheck("Review duplicate inputs before compressing", nil)
+	metricsCheck := widget.NewCheck("Compute PSNR/SSIM for each output (slower)", nil)
+	levelCheck := widget.NewCheck("Auto-straighten horizon", nil)
+	wbCheck := widget.NewCheck("Auto white balance", nil)
+	exposureCheck := widget.NewCheck("Auto exposure", nil)
+	protectCheck := widget.NewCheck("Protect sources: never write inside source folders, verify and log every read", func(on bool) {
+		prefs.SetBool("protect_sources", on)
+	})
+	protectCheck.SetChecked(prefs.Bool("protect_sources"))
+	namesSelect := widget.NewSelect([]string{"Keep as they are", "Unicode NFC, Windows-safe", "ASCII only, Windows-safe"}, nil)
+	namesSelect.SetSelectedIndex(0)
+	nameLenEntry := widget.NewEntry()
+	nameLenEntry.SetPlaceHolder("Max name length in bytes (blank = no limit)")
+	timeoutEntry := widget.NewEntry()
+	timeoutEntry.SetText(fmt.Sprintf("%.0f", defaultFileTimeout.Seconds()))
+	stuckEntry := widget.NewEntry()
+	stuckEntry.SetText(fmt.Sprintf("%d", defaultStuckFactor))
+	stuckSkipCheck := widget.NewCheck("Skip stuck files at that point", nil)
+	skipDoneCheck := widget.NewCheck("Skip inputs with an up-to-date output from a previous run", nil)
+	skipDoneCheck.SetChecked(true)
+	repackCheck := widget.NewCheck("Write images from ZIP/TAR inputs into a new ZIP", nil)
+	gpuCheck := widget.NewCheck("Resize large images on the GPU (macOS)", nil)
+	powerCheck := widget.NewCheck("Pause on low battery or when overheating", nil)
+	batteryEntry := widget.NewEntry()
+	batteryEntry.SetText(fmt.Sprintf("%d", defaultBatteryFloor))
+	passThroughCheck := widget.NewCheck("Copy already-compressed JPEGs unchanged (keeps their metadata)", nil)
+	searchableCheck := widget.NewCheck("Keep capture date and keywords (for Spotlight)", nil)
+	infoSidecarCheck := widget.NewCheck("Write .info.json sidecars with processing details", nil)
+	softwareTagCheck := widget.NewCheck("Record software and settings in outputs (XMP)", nil)
+	exifThumbCheck := widget.NewCheck("Embed EXIF thumbnails in JPEG outputs", nil)
+	dpiEntry := widget.NewEntry()
+	dpiEntry.SetPlaceHolder("Print DPI, e.g. 300 (blank = none)")
+	bleedEntry := widget.NewEntry()
+	bleedEntry.SetPlaceHolder("Bleed, e.g. 3mm or 36px (blank = none)")
+	bleedSelect := widget.NewSelect([]string{"Mirror edges", "Solid colour"}, nil)
+	bleedSelect.SetSelectedIndex(0)
+	bleedColorEntry := widget.NewEntry()
+	bleedColorEntry.SetText("#FFFFFF")
+	// currentBleed reads the bleed settings; Start refuses ones it cannot read
+	currentBleed := func() (bleedOptions, error) {
+		b := bleedOptions{mirror: bleedSelect.SelectedIndex() == 0}
+		var err error
+		if b.size, b.unit, err = parseBleed(bleedEntry.Text); err != nil || b.size == 0 || b.mirror {
+			return b, err
 		}
-		if len(images) == 0 {
-			dialog.ShowInformation("No Images", "No image files found.", w)
+		b.fill, err = parseHexColor(bleedColorEntry.Text)
+		return b, err
+	}
+	trashCheck := widget.NewCheck("Move originals to the Trash once compressed", nil)
+	integrityCheck := widget.NewCheck("Write a signed integrity manifest per batch", nil)
+	dedupSelect := widget.NewSelect([]string{"Identical outputs: keep every copy", "Identical outputs: hard-link to the first", "Identical outputs: symlink to the first"}, nil)
+	dedupSelect.SetSelectedIndex(0)
+
+	resultsBtn := widget.NewButton("Results...", func() {
+		if len(results) == 0 {
+			dialog.ShowInformation("No Results", "Run a batch first.", w)
 			return
 		}
+		showResultsWindow(a, results, runTimings)
+	})
 
-		// Prepare UI
-		progressBar.SetValue(0)
-		progressBar.Show()
-		statusLabel.SetText("Starting...")
+	restoreBtn := widget.NewButton("Restore Originals...", func() {
+		trashed := loadTrashed(prefs)
+		if len(trashed) == 0 {
+			dialog.ShowInformation("Nothing to Restore", "The last run moved no originals to the Trash.", w)
+			return
+		}
+		dialog.ShowConfirm("Restore Originals", fmt.Sprintf("Put the %d originals the last run moved to the Trash back where they were?", len(trashed)), func(ok bool) {
+			if !ok {
+				return
+			}
+			left, errs := restoreOriginals(trashed)
+			saveTrashed(prefs, left)
+			for _, err := range errs {
+				runLog.add("ERROR restore: %v", err)
+			}
+			statusLabel.SetText(fmt.Sprintf("Restored %d of %d originals", len(trashed)-len(left), len(trashed)))
+			if len(errs) > 0 {
+				dialog.ShowError(fmt.Errorf("%d originals could not be restored, e.g. %v; see the log", len(errs), errs[0]), w)
+			}
+		}, w)
+	})
 
-		total := len(images)
-		for i, f := range images {
-			// compute output path and ensure unique
-			base := filepath.Base(f)
-			name := base[:len(base)-len(filepath.Ext(base))]
-			outPath := filepath.Join(outFolder, name+".jpg")
-			outPath = uniqueOutputPath(outPath)
-
-			msg, err := processImageSync(f, outPath, targetKB, maxW, maxH)
+	verifyBtn := widget.NewButton("Verify Archive...", func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			found := findIntegrityManifests(uri.Path())
+			if len(found) == 0 {
+				dialog.ShowInformation("No Manifests", "No integrity manifests in "+uri.Path()+".", w)
+				return
+			}
+			var trusted ed25519.PublicKey
+			var lines []string
+			key, err := loadSigningKey(queueDir)
 			if err != nil {
-				statusLabel.SetText("Error: " + err.Error())
-				// continue processing other images
-			} else {
-				statusLabel.SetText(msg)
+				lines = append(lines, err.Error()+"; signatures are checked but not matched to this computer")
+			} else if key != nil {
+				trusted = key.Public().(ed25519.PublicKey)
 			}
-			progressBar.SetValue(float64(i+1) / float64(total))
+			intact := true
+			for _, p := range found {
+				r, err := verifyIntegrity(p, trusted)
+				if err != nil {
+					lines = append(lines, err.Error())
+					intact = false
+					continue
+				}
+				lines = append(lines, r.String())
+				intact = intact && r.ours && len(r.problems) == 0
+			}
+			report := widget.NewLabel(strings.Join(lines, "\n"))
+			report.Wrapping = fyne.TextWrapWord
+			scroll := container.NewVScroll(report)
+			scroll.SetMinSize(fyne.NewSize(620, 320))
+			title := "Archive Intact"
+			if !intact {
+				title = "Archive Has Problems"
+			}
+			dialog.ShowCustom(title, "Close", scroll, w)
+		}, w)
+	})
+
+	var startRun func(queued []string)
+	reviewBtn := widget.NewButton("Review...", func() {
+		if len(results) == 0 {
+			dialog.ShowInformation("No Results", "Run a batch first.", w)
+			return
 		}
+		showReviewGallery(a, results, func(redos map[string]fileResult) {
+			var paths []string
+			for p, r := range redos {
+				st := perItem[p]
+				st.redoQuality, st.redoOf = redoQuality(r), r.outPath
+				perItem[p] = st
+				paths = append(paths, p)
+			}
+			sort.Strings(paths)
+			runLog.add("Redoing %d files from review", len(paths))
+			startRun(paths)
+		})
+	})
 
-		statusLabel.SetText("Done")
+	var dests destConfig
+	destLabel := widget.NewLabel("Destinations: output folder only")
+	destBtn := widget.NewButton("Destinations...", func() {
+		folderEntry := widget.NewEntry()
+		folderEntry.SetText(dests.folder)
+		folderEntry.SetPlaceHolder("Second folder (optional)")
+		zipCheck := widget.NewCheck("Also pack outputs into a ZIP in the output folder", nil)
+		zipCheck.SetChecked(dests.zip)
+		s3Entry := widget.NewEntry()
+		s3Entry.SetText(dests.s3URL)
+		s3Entry.SetPlaceHolder("s3://bucket/prefix (optional)")
+		regionEntry := widget.NewEntry()
+		regionEntry.SetText(dests.s3Region)
+		regionEntry.SetPlaceHolder("us-east-1")
+		workersEntry := widget.NewEntry()
+		workersEntry.SetPlaceHolder(fmt.Sprintf("%d", defaultUploadWorkers))
+		if dests.uploadWorkers > 0 {
+			workersEntry.SetText(fmt.Sprintf("%d", dests.uploadWorkers))
+		}
+		kbpsEntry := widget.NewEntry()
+		kbpsEntry.SetPlaceHolder("unlimited")
+		if dests.uploadKBps > 0 {
+			kbpsEntry.SetText(fmt.Sprintf("%d", dests.uploadKBps))
+		}
+		groupEntry := widget.NewEntry()
+		groupEntry.SetPlaceHolder("off, e.g. 25 for email")
+		if dests.groupMB > 0 {
+			groupEntry.SetText(fmt.Sprintf("%d", dests.groupMB))
+		}
+		groupKinds := []string{"Numbered folders", "Numbered ZIPs"}
+		groupSelect := widget.NewSelect(groupKinds, nil)
+		groupSelect.SetSelectedIndex(0)
+		if dests.groupKind == groupZIPs {
+			groupSelect.SetSelectedIndex(1)
+		}
+		dialog.ShowForm("Destinations", "Apply", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Copy to folder", folderEntry),
+			widget.NewFormItem("", zipCheck),
+			widget.NewFormItem("Upload to S3", s3Entry),
+			widget.NewFormItem("S3 region", regionEntry),
+			widget.NewFormItem("Parallel uploads", workersEntry),
+			widget.NewFormItem("Upload limit (KB/s)", kbpsEntry),
+			widget.NewFormItem("Split into groups of (MB)", groupEntry),
+			widget.NewFormItem("Groups as", groupSelect),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			dests = destConfig{folder: strings.TrimSpace(folderEntry.Text), zip: zipCheck.Checked,
+				s3URL: strings.TrimSpace(s3Entry.Text), s3Region: strings.TrimSpace(regionEntry.Text)}
+			fmt.Sscanf(workersEntry.Text, "%d", &dests.uploadWorkers)
+			fmt.Sscanf(kbpsEntry.Text, "%d", &dests.uploadKBps)
+			fmt.Sscanf(groupEntry.Text, "%d", &dests.groupMB)
+			dests.groupKind = groupFolders
+			if groupSelect.SelectedIndex() == 1 {
+				dests.groupKind = groupZIPs
+			}
+			destLabel.SetText("Destinations: " + dests.String())
+		}, w)
 	})
 
-	removeBtn := widget.NewButton("Remove Selected", func() {
-		if selectedIndex >= 0 && selectedIndex < len(items) {
-			items = append(items[:selectedIndex], items[selectedIndex+1:]...)
-			list.Unselect(widget.ListItemID(selectedIndex))
-			selectedIndex = -1
-			list.Refresh()
-			preview.Text = "No preview selected"
-			previewContainer.Refresh()
+	credBtn := widget.NewButton("Credentials...", func() {
+		entries := make([]*widget.Entry, len(credentials))
+		var form []*widget.FormItem
+		for i, c := range credentials {
+			entries[i] = widget.NewPasswordEntry()
+			if v, err := getSecret(c.account); err == nil && v != "" {
+				entries[i].SetPlaceHolder("stored — leave blank to keep")
+			} else {
+				entries[i].SetPlaceHolder("not stored")
+			}
+			form = append(form, widget.NewFormItem(c.label, entries[i]))
 		}
+		removeCheck := widget.NewCheck("Remove all stored credentials", nil)
+		form = append(form, widget.NewFormItem("", removeCheck))
+		dialog.ShowForm("Credentials", "Save", "Cancel", form, func(ok bool) {
+			if !ok {
+				return
+			}
+			for i, c := range credentials {
+				var err error
+				if removeCheck.Checked {
+					err = deleteSecret(c.account)
+				} else if v := strings.TrimSpace(entries[i].Text); v != "" {
+					err = setSecret(c.account, v)
+				}
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("%s: %v", c.label, err), w)
+					return
+				}
+			}
+		}, w)
 	})
 
-	clearBtn := widget.NewButton("Clear All", func() {
-		items = nil
-		selectedIndex = -1
-		list.Refresh()
-		preview.Text = "No preview selected"
-		previewContainer.Refresh()
+	uploadBar := widget.NewProgressBar()
+	uploadBar.Hide()
+	uploadLabel := widget.NewLabel("")
+	uploadLabel.Hide()
+	// a run paused for power waits off the UI thread behind this bar
+	pauseLabel := widget.NewLabel("")
+	var pausedRun func(cancel bool, how string) // set while a run is paused
+	resumeBtn := widget.NewButton("Resume Now", func() {
+		if pausedRun != nil {
+			pausedRun(false, "Resume Now")
+		}
 	})
+	cancelRunBtn := widget.NewButton("Cancel Run", func() {
+		if pausedRun != nil {
+			pausedRun(true, "")
+		}
+	})
+	pauseBar := container.NewHBox(pauseLabel, resumeBtn, cancelRunBtn)
+	pauseBar.Hide()
+	runID := 0 // guards late upload callbacks against a newer run's results
 
-	// preview on select
-	list.OnSelected = func(id widget.ListItemID) {
-		if id < 0 || int(id) >= len(items) {
-			selectedIndex = -1
+	var sessionFields map[string]sessionField // filled in once all widgets exist
+	persistQueue := func(cleanExit bool) error {
+		q := newSavedQueue(items, perItem, statuses)
+		q.Settings, q.CleanExit = captureSettings(sessionFields), cleanExit
+		return saveQueue(queueDir, q)
+	}
+
+	// runBatch writes each image into outTemplate (see expandOutputDir),
+	// resolved per file.
+	runBatch := func(images []string, outTemplate string, pr preset, opts compressOptions, fileTimeout time.Duration) {
+		// Prepare UI
+		progressBar.SetValue(0)
+		progressBar.Show()
+		statusLabel.SetText("Starting...")
+		runLog.add("Run started: %d files -> %s", len(images), outTemplate)
+
+		started := time.Now()
+		outDir := func(f string) string {
+			dir, _ := expandOutputDir(outTemplate, f, started) // validated before the run
+			return dir
+		}
+		// the ZIP destination goes with the first file's outputs
+		firstDir := outDir(images[0])
+		var outputs []destination
+		var derr error
+		if dests.zip || dests.groupMB > 0 {
+			derr = os.MkdirAll(firstDir, 0755)
+		}
+		if derr == nil {
+			outputs, derr = dests.open(firstDir)
+		}
+		if derr != nil {
+			progressBar.Hide()
+			statusLabel.SetText("Error: " + derr.Error())
+			runLog.add("ERROR %v", derr)
 			return
 		}
-		selectedIndex = int(id)
-		path := items[id]
-		img := canvas.NewImageFromFile(path)
-		img.FillMode = canvas.ImageFillContain
-		img.SetMinSize(fyne.NewSize(400, 400))
-		previewContainer.Objects = []fyne.CanvasObject{img}
-		previewContainer.Refresh()
-	}
+		var guard *sourceGuard
+		if protectCheck.Checked {
+			if guard, derr = newSourceGuard(firstDir); derr != nil {
+				closeDestinations(outputs)
+				progressBar.Hide()
+				statusLabel.SetText("Error: " + derr.Error())
+				runLog.add("ERROR %v", derr)
+				return
+			}
+			runLog.add("Protect sources: audit log in %s", filepath.Join(firstDir, auditLogName))
+		}
+		if !isOutputTemplate(outTemplate) {
+			rememberFolder(prefs, recentOutputsKey, firstDir)
+			refreshRecent()
+		}
 
-	left := container.NewBorder(
-		container.NewVBox(widget.NewLabel("Files to compress"), widget.NewLabel("Click an item to preview")),
-		nil, nil, nil,
-		container.NewVScroll(list),
-	)
+		var uploads *uploadQueue
+		if remote := remoteDestinations(outputs); len(remote) > 0 {
+			uploadBar.SetValue(0)
+			uploadBar.Show()
+			uploadLabel.SetText("Uploads starting...")
+			uploadLabel.Show()
+			uploads = newUploadQueue(remote, dests.uploadWorkers, dests.uploadKBps, func(sent, queued int64) {
+				if queued > 0 {
+					uploadBar.SetValue(float64(sent) / float64(queued))
+				}
+				uploadLabel.SetText(fmt.Sprintf("Uploaded %.1f of %.1fMB", float64(sent)/(1024*1024), float64(queued)/(1024*1024)))
+			})
+		}
 
-	opts := container.NewVBox(
+		runID++
+		run := runID
+		results = nil
+		runTimings = newStepTimer()
+		opts.timings = runTimings
+		if uploads != nil {
+			uploads.timings = runTimings
+		}
+		overrides := newOverrideResolver()
+		// fileOptions layers folder overrides, then rules, over the window's settings
+		fileOptions := func(f string, o compressOptions) (compressOptions, bool, error) {
+			o, err := overrides.optionsFor(f, o)
+			if err != nil {
+				return o, false, fmt.Errorf("settings override: %v", err)
+			}
+			return rules.apply(f, o)
+		}
+		// read once: a run paused for power may resume after they change
+		trashing, repack, skipDone, sealing := trashCheck.Checked, repackCheck.Checked, skipDoneCheck.Checked, integrityCheck.Checked
+		sealSettings := captureSettings(sessionFields)
+		sealed := map[string][]string{} // outputs by output folder, for integrity manifests
+		watchdog := &stuckWatchdog{skip: stuckSkipCheck.Checked}
+		watchdog.factor, _ = parseStuckFactor(stuckEntry.Text) // validated before the run
+		var trashed []trashedFile
+		// trashOriginal moves f to the Trash once its output is recorded
+		trashOriginal := func(f string) {
+			if !trashing {
+				return
+			}
+			if guard != nil {
+				runLog.add("KEPT %s: sources are protected", f)
+				return
+			}
+			t, err := moveToTrash(f)
+			if err != nil {
+				runLog.add("ERROR %s: %v; the original is kept", f, err)
+				return
+			}
+			trashed = append(trashed, t)
+			saveTrashed(prefs, trashed)
+		}
+		dedup := newOutputDeduper([]string{dedupKeep, dedupHardLink, dedupSymlink}[max(0, dedupSelect.SelectedIndex())])
+		manifests := newManifestSet(func(err error) {
+			dialog.ShowError(fmt.Errorf("manifest unreadable, starting a new one: %v", err), w)
+		})
+		power := powerPolicy{enabled: powerCheck.Checked, floor: defaultBatteryFloor}
+		fmt.Sscanf(batteryEntry.Text, "%d", &power.floor)
+		total := len(images)
+		lastSave := time.Now()
+		// finish closes the run once every file is done, or it is cancelled
+		finish := func() {
+			refreshList()
+			if err := closeDestinations(outputs); err != nil {
+				dialog.ShowError(err, w)
+			}
+			if sealing && len(sealed) > 0 {
+				if key, err := signingKey(queueDir); err != nil {
+					dialog.ShowError(err, w)
+				} else {
+					for dir, files := range sealed {
+						path, err := writeIntegrityManifest(dir, files, sealSettings, key, started)
+						if err != nil {
+							dialog.ShowError(err, w)
+							continue
+						}
+						runLog.add("Signed manifest of %d files: %s (key %s)", len(files), path, keyFingerprint(key.Public().(ed25519.PublicKey)))
+					}
+				}
+			}
+			if err := guard.close(); err != nil {
+				dialog.ShowError(fmt.Errorf("audit log: %v", err), w)
+			}
+			if err := persistQueue(false); err != nil {
+				dialog.ShowError(fmt.Errorf("could not save the queue: %v", err), w)
+			}
+			if err := manifests.save(); err != nil {
+				statusLabel.SetText("Done, but the manifest could not be saved: " + err.Error())
+				return
+			}
+			statusLabel.SetText("Done — see Results for details")
+			if sum := dedup.summary(); sum != "" {
+				statusLabel.SetText("Done — " + sum)
+				runLog.add("%s", sum)
+			}
+			low := 0
+			for _, r := range results {
+				if r.err == nil && r.lowQuality {
+					low++
+				}
+			}
+			if low > 0 {
+				statusLabel.SetText(fmt.Sprintf("Done — ⚠ %d files only met the target at the lowest quality; see Results", low))
+				runLog.add("WARNING %d files only met the target at the lowest quality", low)
+			}
+			blocky, floor := 0, 0
+			for _, r := range results {
+				if r.err == nil && r.suggestQ > 0 {
+					blocky++
+					floor = max(floor, r.suggestQ)
+				}
+			}
+			if blocky > 0 {
+				statusLabel.SetText(fmt.Sprintf("Done — ⚠ %d sources look re-saved several times; a quality floor of q≥%d avoids visible blocking", blocky, floor))
+				runLog.add("WARNING %d sources look re-saved several times; suggested quality floor q≥%d", blocky, floor)
+			}
+			slow := 0
+			for _, r := range results {
+				if r.stuckFor > 0 {
+					slow++
+				}
+			}
+			if slow > 0 {
+				statusLabel.SetText(fmt.Sprintf("Done — ⚠ %d files took over %g× the median time; see Results", slow, watchdog.factor))
+				runLog.add("WARNING %d files took over %g× the median time (%s)", slow, watchdog.factor, watchdog.median().Round(100*time.Millisecond))
+			}
+			if t := runTimings.report(); t != "" {
+				runLog.add("Time by step:\n%s", t)
+			}
+			runLog.add("Run finished")
+			announce(statusLabel.Text)
+			if uploads != nil {
+				statusLabel.SetText("Compression done — uploads continue in the background")
+				uploads.finish(func() {
+					uploadBar.SetValue(1)
+					uploadLabel.SetText("Uploads finished — see Results for details")
+					runLog.add("Uploads finished")
+					if t := runTimings.report(); t != "" {
+						runLog.add("Time by step, with uploads:\n%s", t)
+					}
+					refreshList()
+					if err := manifests.save(); err != nil {
+						uploadLabel.SetText("Uploads finished, but the manifest could not be saved: " + err.Error())
+					}
+				})
+			}
+		}
+		// loop processes images from start on; resumed skips the power check
+		// for the file a pause stopped at
+		var loop func(start int, resumed bool)
+		// pauseFor stops before file i and waits for power in the background
+		pauseFor := func(i int, why string) {
+			runLog.add("Pausing before %s: %s", filepath.Base(images[i]), why)
+			statusLabel.SetText("Paused: " + why)
+			pauseLabel.SetText(fmt.Sprintf("Paused: %s (%d of %d done, at most %s)", why, i, total, powerMaxPause))
+			pauseBar.Show()
+			paused := time.Now()
+			stop := make(chan struct{})
+			resume := func(cancel bool, how string) {
+				pausedRun = nil
+				close(stop)
+				pauseBar.Hide()
+				waited := time.Since(paused).Round(time.Second)
+				if cancel {
+					runLog.add("Run cancelled after a %s pause; %d of %d files done", waited, i, total)
+					finish()
+					return
+				}
+				runLog.add("Resumed after %s: %s", waited, how)
+				loop(i, true)
+			}
+			pausedRun = resume
+			power.waitForPower(stop, func(how string) {
+				fyne.Do(func() {
+					select {
+					case <-stop: // already resumed or cancelled
+					default:
+						resume(false, how)
+					}
+				})
+			})
+		}
+		loop = func(start int, resumed bool) {
+			for i := start; i < total; i++ {
+				f := images[i]
+				if time.Since(lastSave) > autosaveInterval {
+					persistQueue(false) // partial progress survives a crash mid-run
+					lastSave = time.Now()
+				}
+				if power.enabled {
+					st := readPowerState()
+					if uploads != nil {
+						uploads.setLimit(power.uploadLimit(st))
+					}
+					if why := power.pause(st, false); why != "" && !(resumed && i == start) {
+						pauseFor(i, why)
+						return
+					}
+				}
+				var res fileResult
+				var err error
+				outFolder := outDir(f)
+				manifest := manifests.get(outFolder)
+				itemOpts := opts
+				itemOpts.transform = perItem[f].transform
+				if perItem[f].skipAutoFix {
+					itemOpts.autoWB, itemOpts.autoExposure = false, false
+				}
+				if q := perItem[f].redoQuality; q > 0 {
+					itemOpts.targetKB = 0
+					itemOpts.quality.jpeg, itemOpts.quality.webp, itemOpts.quality.avif, itemOpts.quality.heic = q, q, q, q
+					itemOpts.passThrough = false
+				}
+				if isArchive(f) && !pr.iconSet {
+					// each entry gets its own result; the archive only a status
+					if err = guard.read(f); err == nil {
+						var entries []fileResult
+						var zipPath string
+						entries, zipPath, err = processArchive(f, archiveRun{
+							outDir: outFolder, opts: itemOpts, timeout: fileTimeout, repack: repack,
+							deliver: func(r fileResult) error {
+								guard.wrote(f, r.outPath)
+								if !repack {
+									if err := perms.apply(r.outPath, outFolder); err != nil {
+										return err
+									}
+									if _, err := dedup.add(r.outPath); err != nil {
+										return err
+									}
+								}
+								if err := fanOut(outputs, r.outPath); err != nil {
+									return err
+								}
+								if !repack {
+									sealed[outFolder] = append(sealed[outFolder], r.outPath)
+								}
+								if uploads != nil && !repack {
+									uploads.add(r.outPath, func(uerr error) {
+										if uerr != nil {
+											runLog.add("ERROR %s: upload: %v", r.inPath, uerr)
+										}
+									})
+								}
+								return nil
+							},
+						})
+						if err == nil && zipPath != "" {
+							err = perms.apply(zipPath, outFolder)
+							sealed[outFolder] = append(sealed[outFolder], zipPath)
+						}
+						if err == nil && uploads != nil && zipPath != "" {
+							uploads.add(zipPath, func(uerr error) {
+								if uerr != nil {
+									statuses[f] = "error: " + uerr.Error()
+									runLog.add("ERROR %s: upload: %v", zipPath, uerr)
+								}
+							})
+						}
+						failed := 0
+						for _, r := range entries {
+							if r.err != nil {
+								failed++
+								runLog.add("ERROR %s: %v", r.inPath, r.err)
+							} else {
+								runLog.add("%s", r.msg)
+							}
+						}
+						results = append(results, entries...)
+						if err == nil {
+							statusLabel.SetText(fmt.Sprintf("%s: %d images, %d failed", filepath.Base(f), len(entries), failed))
+							statuses[f] = fmt.Sprintf("ok (%d images, %d failed)", len(entries), failed)
+						}
+					}
+					if verr := guard.verify(f); err == nil {
+						err = verr
+					}
+					if err != nil {
+						statusLabel.SetText("Error: " + err.Error())
+						runLog.add("ERROR %s: %v", f, err)
+						statuses[f] = "error: " + err.Error()
+					}
+					progressBar.SetValue(float64(i+1) / float64(total))
+					continue
+				}
+				if pr.iconSet {
+					res = fileResult{inPath: f}
+					res.msg, err = generateIconSet(f, outFolder)
+				} else if fileOpts, skip, oerr := fileOptions(f, itemOpts); oerr != nil {
+					res, err = fileResult{inPath: f}, oerr
+				} else if skip {
+					res = fileResult{inPath: f, skipped: true, skipRule: true}
+					res.msg = fmt.Sprintf("SKIP %s (rule)", f)
+				} else if prev, ok := manifest.upToDate(f, fileOpts); ok && skipDone {
+					res = fileResult{inPath: f, outPath: prev, skipped: true}
+					res.msg = fmt.Sprintf("SKIP %s (up to date: %s)", f, prev)
+				} else {
+					// compute output path and ensure unique
+					base := filepath.Base(f)
+					name := fileOpts.naming.outputStem(base[:len(base)-len(filepath.Ext(base))], outputExt(fileOpts.format))
+					outPath := filepath.Join(outFolder, fileOpts.subfolder, name+outputExt(fileOpts.format))
+					outPath = uniqueOutputPath(outPath)
+
+					if err = guard.read(f); err == nil {
+						err = guard.checkWrite(outPath)
+					}
+					if err == nil {
+						res, err = watchdog.run(f, fileTimeout, func(ctx context.Context) (fileResult, error) {
+							return processImageSync(ctx, f, outPath, fileOpts)
+						})
+						if res.stuckFor > 0 && err == nil {
+							res.msg += fmt.Sprintf(" WARNING: slow, took %s", res.stuckFor)
+						}
+					}
+					if verr := guard.verify(f); err == nil {
+						err = verr
+					}
+					// rejected in review: a successful redo takes the old output's
+					// name when it landed beside it; the old one is kept otherwise
+					if redoOf := perItem[f].redoOf; err == nil && redoOf != "" && res.outPath != redoOf &&
+						filepath.Dir(res.outPath) == filepath.Dir(redoOf) && filepath.Ext(res.outPath) == filepath.Ext(redoOf) {
+						rerr := guard.checkWrite(redoOf)
+						if rerr == nil {
+							rerr = disk.Rename(res.outPath, redoOf)
+						}
+						if rerr != nil {
+							res.msg += fmt.Sprintf(" — kept beside %s: %v", filepath.Base(redoOf), rerr)
+						} else {
+							res.msg = strings.Replace(res.msg, res.outPath, redoOf, 1)
+							res.outPath = redoOf
+						}
+					}
+					deliverDone := opts.timings.start(stepDeliver)
+					if err == nil {
+						guard.wrote(f, res.outPath)
+						if err = perms.apply(res.outPath, outFolder); err == nil {
+							res.linkedTo, err = dedup.add(res.outPath)
+						}
+					}
+					if err == nil {
+						if res.linkedTo != "" {
+							res.msg += fmt.Sprintf(" — identical to %s, linked", filepath.Base(res.linkedTo))
+						}
+						err = fanOut(outputs, res.outPath)
+					}
+					deliverDone()
+					if err == nil {
+						sealed[outFolder] = append(sealed[outFolder], res.outPath)
+					}
+					if err == nil && uploads != nil {
+						// recorded once the upload lands, so a failed upload is retried next run
+						idx, done, recOpts := len(results), res, fileOpts
+						uploads.add(res.outPath, func(uerr error) {
+							if uerr != nil {
+								if run == runID {
+									results[idx].err = uerr
+								}
+								statuses[f] = "error: " + uerr.Error()
+								uploadLabel.SetText("Error: " + uerr.Error())
+								runLog.add("ERROR %s: upload: %v", f, uerr)
+								return
+							}
+							manifest.record(done, recOpts)
+							trashOriginal(f)
+						})
+					} else if err == nil {
+						manifest.record(res, fileOpts)
+						trashOriginal(f)
+					}
+				}
+				if err != nil {
+					res.err = err
+					statusLabel.SetText("Error: " + err.Error())
+					runLog.add("ERROR %s: %v", f, err)
+					// continue processing other images
+				} else {
+					statusLabel.SetText(res.msg)
+					runLog.add("%s", res.msg)
+				}
+				usage.file(f, res)
+				results = append(results, res)
+				statuses[f] = resultStatus(res)
+				if st := perItem[f]; st.redoQuality > 0 {
+					st.redoQuality, st.redoOf = 0, "" // one pass only
+					perItem[f] = st
+				}
+				progressBar.SetValue(float64(i+1) / float64(total))
+			}
+			finish()
+		}
+		loop(0, false)
+	}
+
+	var filter exifFilter
+	filterLabel := widget.NewLabel("EXIF filter: none")
+	filterBtn := widget.NewButton("EXIF Filter...", func() {
+		cameraEntry := widget.NewEntry()
+		cameraEntry.SetText(filter.camera)
+		cameraEntry.SetPlaceHolder("e.g. iPhone 15, X-T4")
+		gpsCheck := widget.NewCheck("Only photos with GPS location", nil)
+		gpsCheck.SetChecked(filter.requireGPS)
+		fromEntry := widget.NewEntry()
+		fromEntry.SetPlaceHolder("YYYY-MM-DD")
+		toEntry := widget.NewEntry()
+		toEntry.SetPlaceHolder("YYYY-MM-DD")
+		if !filter.from.IsZero() {
+			fromEntry.SetText(filter.from.Format("2006-01-02"))
+		}
+		if !filter.to.IsZero() {
+			toEntry.SetText(filter.to.Format("2006-01-02"))
+		}
+		dialog.ShowForm("EXIF Filter", "Apply", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Camera contains", cameraEntry),
+			widget.NewFormItem("", gpsCheck),
+			widget.NewFormItem("Captured from", fromEntry),
+			widget.NewFormItem("Captured to", toEntry),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			from, err := parseFilterDate(fromEntry.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("invalid start date: %v", err), w)
+				return
+			}
+			to, err := parseFilterDate(toEntry.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("invalid end date: %v", err), w)
+				return
+			}
+			filter = exifFilter{camera: strings.TrimSpace(cameraEntry.Text), requireGPS: gpsCheck.Checked, from: from, to: to}
+			filterLabel.SetText("EXIF filter: " + filter.String())
+		}, w)
+	})
+
+	// currentOptions reads the window's settings for a run or an estimate.
+	currentOptions := func() (compressOptions, preset) {
+		targetKB := 0
+		fmt.Sscanf(targetEntry.Text, "%d", &targetKB)
+		maxW := 0
+		fmt.Sscanf(widthEntry.Text, "%d", &maxW)
+		maxH := 0
+		fmt.Sscanf(heightEntry.Text, "%d", &maxH)
+		minW, minH := 0, 0
+		fmt.Sscanf(minWEntry.Text, "%d", &minW)
+		fmt.Sscanf(minHEntry.Text, "%d", &minH)
+		printDPI := 0
+		fmt.Sscanf(dpiEntry.Text, "%d", &printDPI)
+		naming := nameOptions{unicode: []string{namesKeep, namesNFC, namesASCII}[max(0, namesSelect.SelectedIndex())]}
+		fmt.Sscanf(nameLenEntry.Text, "%d", &naming.maxBytes)
+		pr := findPreset(presetSelect.Selected)
+		opts := pr.apply(compressOptions{format: formats[max(0, formatSelect.SelectedIndex())], targetKB: targetKB, shrinkToFit: shrinkCheck.Checked, minW: minW, minH: minH, maxW: maxW, maxH: maxH, placeholders: placeholderCheck.Checked, metrics: metricsCheck.Checked, autoLevel: levelCheck.Checked,
+			autoWB: wbCheck.Checked, autoExposure: exposureCheck.Checked, adjust: currentAdjust(), style: currentStyle(), jpeg: currentJPEG(), quality: qualityPrefs, naming: naming, gpuResize: gpuCheck.Checked,
+			passThrough: passThroughCheck.Checked, searchable: searchableCheck.Checked, infoSidecar: infoSidecarCheck.Checked,
+			softwareTag: softwareTagCheck.Checked, exifThumb: exifThumbCheck.Checked, printDPI: max(0, printDPI), pngColor: pngColorModes[max(0, pngColorSelect.SelectedIndex())]})
+		switch modeSelect.SelectedIndex() {
+		case 1:
+			opts.docMode = docColor
+		case 2:
+			opts.docMode = docGray
+		case 3:
+			opts.docMode = docBilevel
+		case 4:
+			opts.autoDetect = true
+		}
+		switch animSelect.SelectedIndex() {
+		case 1:
+			opts.animation = animationMP4
+		case 2:
+			opts.animation = animationWebM
+		}
+		opts.bleed, _ = currentBleed()
+		opts.pipeline = loadPipeline(prefs, pr.name)
+		return opts, pr
+	}
+
+	// startRun compresses queued, a subset of the queue, after pre-flight
+	startRun = func(queued []string) {
+		if pausedRun != nil {
+			dialog.ShowInformation("Run Paused", "A run is paused for power. Resume or cancel it first.", w)
+			return
+		}
+		if len(items) == 0 {
+			dialog.ShowInformation("No Input", "Add files or folders first.", w)
+			return
+		}
+		outFolder := strings.TrimSpace(outEntry.Text)
+		if outFolder == "" {
+			dialog.ShowInformation("No Output", "Select output folder.", w)
+			return
+		}
+		if _, err := expandOutputDir(outFolder, "", time.Now()); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		timeoutSecs := 0
+		fmt.Sscanf(timeoutEntry.Text, "%d", &timeoutSecs)
+		fileTimeout := time.Duration(timeoutSecs) * time.Second
+		opts, pr := currentOptions()
+		if err := opts.jpeg.validate(); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if _, err := currentBleed(); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if _, err := parseStuckFactor(stuckEntry.Text); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		images, filtered := filter.apply(expandItems(byPriority(queued, perItem)))
+		if len(images) == 0 {
+			msg := "No image files found."
+			if filtered > 0 {
+				msg = fmt.Sprintf("All %d images were excluded by the EXIF filter (%s).", filtered, filter)
+			}
+			dialog.ShowInformation("No Images", msg, w)
+			return
+		}
+
+		images, dropped := excludeOutputs(images, outFolder, time.Now())
+		if dropped > 0 {
+			runLog.add("Left out %d files that are inside the output folder", dropped)
+		}
+		if len(images) == 0 {
+			dialog.ShowInformation("No Images", "Every queued image is inside the output folder.", w)
+			return
+		}
+		issues := preflight(images, outFolder, opts)
+		if protectCheck.Checked {
+			var roots []string
+			for _, it := range queued {
+				if info, err := os.Stat(it); err == nil && info.IsDir() {
+					roots = append(roots, it)
+				}
+			}
+			issues = append(issues, sourceIssues(images, roots, outFolder, dests.folder, time.Now())...)
+		}
+		showPreflight(w, issues, func() {
+			if !dupCheck.Checked {
+				runBatch(images, outFolder, pr, opts, fileTimeout)
+				return
+			}
+			statusLabel.SetText("Checking for duplicate inputs...")
+			groups := findInputDuplicates(images)
+			if len(groups) == 0 {
+				runBatch(images, outFolder, pr, opts, fileTimeout)
+				return
+			}
+			showDuplicateReview(w, groups, func(exclude map[string]bool) {
+				var keep []string
+				for _, f := range images {
+					if !exclude[f] {
+						keep = append(keep, f)
+					}
+				}
+				runBatch(keep, outFolder, pr, opts, fileTimeout)
+			})
+		})
+	}
+	startBtn := widget.NewButton("Start Compress (blocking)", func() { startRun(activeItems(items, perItem)) })
+	schedulesBtn := widget.NewButton("Schedules...", func() {
+		selected := -1
+		list := widget.NewList(
+			func() int { return len(schedules) },
+			func() fyne.CanvasObject { return widget.NewLabel("template") },
+			func(i widget.ListItemID, o fyne.CanvasObject) { o.(*widget.Label).SetText(schedules[i].String()) },
+		)
+		list.OnSelected = func(id widget.ListItemID) { selected = id }
+		removeJob := widget.NewButton("Remove", func() {
+			if selected < 0 || selected >= len(schedules) {
+				return
+			}
+			schedules = append(schedules[:selected], schedules[selected+1:]...)
+			saveSchedules(prefs, schedules)
+			selected = -1
+			list.UnselectAll()
+			list.Refresh()
+		})
+		addJob := widget.NewButton("Add from Current Settings...", func() {
+			if strings.TrimSpace(outEntry.Text) == "" {
+				dialog.ShowInformation("No Output", "Select output folder first; the job writes there.", w)
+				return
+			}
+			nameEntry := widget.NewEntry()
+			nameEntry.SetPlaceHolder("Nightly inbox")
+			inEntry := widget.NewEntry()
+			inEntry.SetPlaceHolder("Folder to compress")
+			browse := widget.NewButton("Browse...", func() {
+				dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+					if err == nil && uri != nil {
+						grantAccess(prefs, uri.Path())
+						inEntry.SetText(uri.Path())
+					}
+				}, w)
+			})
+			repeat := []string{"Every day"}
+			for d := time.Sunday; d <= time.Saturday; d++ {
+				repeat = append(repeat, "Every "+d.String())
+			}
+			repeatSelect := widget.NewSelect(repeat, nil)
+			repeatSelect.SetSelectedIndex(0)
+			atEntry := widget.NewEntry()
+			atEntry.SetText("02:00")
+			dialog.ShowForm("Add Scheduled Job", "Add", "Cancel", []*widget.FormItem{
+				widget.NewFormItem("Name", nameEntry),
+				widget.NewFormItem("Input folder", container.NewBorder(nil, nil, nil, browse, inEntry)),
+				widget.NewFormItem("Repeat", repeatSelect),
+				widget.NewFormItem("At (24h)", atEntry),
+			}, func(ok bool) {
+				if !ok {
+					return
+				}
+				if _, _, err := parseClock(atEntry.Text); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				if info, err := os.Stat(inEntry.Text); err != nil || !info.IsDir() {
+					dialog.ShowError(fmt.Errorf("input folder %q not found", inEntry.Text), w)
+					return
+				}
+				job := scheduledJob{Name: strings.TrimSpace(nameEntry.Text), Input: inEntry.Text, At: strings.TrimSpace(atEntry.Text),
+					Settings: captureSettings(sessionFields), LastRun: time.Now()}
+				if job.Name == "" {
+					job.Name = filepath.Base(job.Input)
+				}
+				if i := repeatSelect.SelectedIndex(); i > 0 {
+					job.Weekly, job.Weekday = true, time.Weekday(i-1)
+				}
+				job.Settings["skip_done"] = "true" // each run only does what is new
+				schedules = append(schedules, job)
+				saveSchedules(prefs, schedules)
+				list.Refresh()
+			}, w)
+		})
+		box := container.NewBorder(widget.NewLabel("Jobs run while the app is open, with the settings they were added with."),
+			container.NewHBox(addJob, removeJob), nil, nil, list)
+		d := dialog.NewCustom("Scheduled Runs", "Close", box, w)
+		d.Resize(fyne.NewSize(640, 360))
+		d.Show()
+	})
+	startSelectedBtn := widget.NewButton("Start (Selected Only)", func() {
+		sel := selection.ordered(items)
+		if len(sel) == 0 {
+			dialog.ShowInformation("No Selection", "Select items in the list first; Cmd/Ctrl-click or Shift-click selects several.", w)
+			return
+		}
+		startRun(sel) // ticked or not: they were asked for
+	})
+
+	compareBtn := widget.NewButton("Compare A/B...", func() {
+		if selectedIndex < 0 || selectedIndex >= len(items) {
+			dialog.ShowInformation("No Selection", "Select an image in the list first.", w)
+			return
+		}
+		p := items[selectedIndex]
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			dialog.ShowInformation("Folder Selected", "Select a single image to compare.", w)
+			return
+		}
+		showCompareWindow(a, p)
+	})
+
+	mailBtn := widget.NewButton("Share via Mail", func() {
+		if selectedIndex < 0 || selectedIndex >= len(items) {
+			dialog.ShowInformation("No Selection", "Select an image or folder in the list first.", w)
+			return
+		}
+		images := expandItems([]string{items[selectedIndex]})
+		if len(images) == 0 {
+			dialog.ShowInformation("No Images", "The selected folder has no images.", w)
+			return
+		}
+		transforms := map[string]itemTransform{}
+		for p, st := range perItem {
+			transforms[p] = st.transform
+		}
+		statusLabel.SetText("Compressing for email...")
+		msg, err := shareViaMail(images, transforms)
+		if err != nil {
+			statusLabel.SetText("Error: " + err.Error())
+			return
+		}
+		statusLabel.SetText(msg)
+	})
+
+	auditBtn := widget.NewButton("Audit Page...", func() {
+		pageEntry := widget.NewEntry()
+		pageEntry.SetPlaceHolder("https://example.com/ or a sitemap.xml")
+		kbEntry := widget.NewEntry()
+		kbEntry.SetText(fmt.Sprintf("%d", auditDefaultKB))
+		maxWEntry := widget.NewEntry()
+		maxWEntry.SetText(fmt.Sprintf("%d", auditDefaultMaxW))
+		dialog.ShowForm("Audit Page Images", "Audit", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Page URL", pageEntry),
+			widget.NewFormItem("Flag images over (KB)", kbEntry),
+			widget.NewFormItem("Flag images wider than (px)", maxWEntry),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			pageURL := strings.TrimSpace(pageEntry.Text)
+			if _, err := parseURLList(pageURL); err != nil || pageURL == "" {
+				dialog.ShowError(fmt.Errorf("enter an http(s) page URL"), w)
+				return
+			}
+			maxKB, maxW := auditDefaultKB, auditDefaultMaxW
+			fmt.Sscanf(kbEntry.Text, "%d", &maxKB)
+			fmt.Sscanf(maxWEntry.Text, "%d", &maxW)
+			statusLabel.SetText("Auditing " + pageURL + "...")
+			entries, err := auditPage(pageURL, maxKB, maxW)
+			if err != nil {
+				statusLabel.SetText("Error: " + err.Error())
+				return
+			}
+			statusLabel.SetText(fmt.Sprintf("Found %d images on %s", len(entries), pageURL))
+			showAuditWindow(a, pageURL, entries, func(paths []string) {
+				items = append(items, paths...)
+				refreshList()
+				statusLabel.SetText(fmt.Sprintf("Queued %d oversized images", len(paths)))
+			})
+		}, w)
+	})
+
+	formatsBtn := widget.NewButton("Formats...", func() { showFormatsDialog(w) })
+	startTour := func() {
+		tourDrop = showOnboarding(w, prefs, func(c onboardingChoice) {
+			if c.outDir != "" {
+				if err := os.MkdirAll(c.outDir, 0755); err != nil {
+					dialog.ShowError(fmt.Errorf("could not create %s: %v", c.outDir, err), w)
+				} else {
+					outEntry.SetText(c.outDir)
+					rememberFolder(prefs, recentOutputsKey, c.outDir)
+					refreshRecent()
+				}
+			}
+			if c.usePreset {
+				presetSelect.SetSelected(examplePreset)
+			}
+		})
+	}
+	tourBtn := widget.NewButton("Getting Started...", startTour)
+	uploadsBusy := func() bool { return uploadBar.Visible() }
+	updatesBtn := widget.NewButton("Updates...", func() { showUpdates(a, w, prefs, uploadsBusy) })
+	privacyBtn := widget.NewButton("Privacy...", func() { showPrivacy(w, prefs) })
+
+	scratchBtn := widget.NewButton("Scratch Space...", func() {
+		dirEntry := widget.NewEntry()
+		dirEntry.SetText(scratch.root)
+		dirEntry.SetPlaceHolder(os.TempDir())
+		capEntry := widget.NewEntry()
+		capEntry.SetText(fmt.Sprintf("%d", scratch.capBytes>>20))
+		usageLabel := widget.NewLabel(fmt.Sprintf("%s used in %s", formatSize(scratch.usage()), scratch.base()))
+		browse := widget.NewButton("Browse...", func() {
+			dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+				if err == nil && uri != nil {
+					grantAccess(prefs, uri.Path())
+					dirEntry.SetText(uri.Path())
+				}
+			}, w)
+		})
+		clearNow := widget.NewButton("Clear Now", func() {
+			freed := scratch.trim(expandItems(items), true)
+			usageLabel.SetText(fmt.Sprintf("Freed %s; %s used in %s", formatSize(freed), formatSize(scratch.usage()), scratch.base()))
+		})
+		dialog.ShowForm("Scratch Space", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Folder (blank = system temp)", container.NewBorder(nil, nil, nil, browse, dirEntry)),
+			widget.NewFormItem("Size cap (MB, 0 = none)", capEntry),
+			widget.NewFormItem("", container.NewBorder(nil, nil, nil, clearNow, usageLabel)),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			capMB := 0
+			fmt.Sscanf(capEntry.Text, "%d", &capMB)
+			scratch = &scratchSpace{root: strings.TrimSpace(dirEntry.Text), capBytes: int64(max(0, capMB)) << 20}
+			scratch.save(prefs)
+		}, w)
+	})
+
+	permsBtn := widget.NewButton("Permissions...", func() {
+		fileEntry := widget.NewEntry()
+		fileEntry.SetText(formatMode(perms.fileMode))
+		fileEntry.SetPlaceHolder("0644 less umask")
+		dirEntry := widget.NewEntry()
+		dirEntry.SetText(formatMode(perms.dirMode))
+		dirEntry.SetPlaceHolder("0755 less umask")
+		groupEntry := widget.NewEntry()
+		groupEntry.SetText(perms.group)
+		groupEntry.SetPlaceHolder("your default group")
+		dialog.ShowForm("Output Permissions", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("File mode (octal)", fileEntry),
+			widget.NewFormItem("Folder mode (octal)", dirEntry),
+			widget.NewFormItem("Group (macOS/Linux)", groupEntry),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			fileMode, err := parseMode(fileEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			dirMode, err := parseMode(dirEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			perms = outputPerms{fileMode: fileMode, dirMode: dirMode, group: strings.TrimSpace(groupEntry.Text)}
+			perms.save(prefs)
+		}, w)
+	})
+
+	sheetBtn := widget.NewButton("Contact Sheet...", func() {
+		images := expandItems(activeItems(items, perItem))
+		if len(images) == 0 {
+			dialog.ShowInformation("No Images", "Add files or folders first.", w)
+			return
+		}
+		if outEntry.Text == "" {
+			dialog.ShowInformation("No Output", "Select output folder.", w)
+			return
+		}
+		formatSel := widget.NewSelect([]string{"JPEG pages", "PDF"}, nil)
+		formatSel.SetSelected("PDF")
+		gridSel := widget.NewSelect([]string{"3 x 4", "4 x 5", "5 x 6"}, nil)
+		gridSel.SetSelected("4 x 5")
+		dialog.ShowForm("Contact Sheet", "Create", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Format", formatSel),
+			widget.NewFormItem("Grid (columns x rows)", gridSel),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			cols, rows := 4, 5
+			fmt.Sscanf(gridSel.Selected, "%d x %d", &cols, &rows)
+			format := "jpeg"
+			if formatSel.Selected == "PDF" {
+				format = "pdf"
+			}
+			statusLabel.SetText("Rendering contact sheet...")
+			outDir, err := expandOutputDir(outEntry.Text, images[0], time.Now())
+			if err != nil {
+				statusLabel.SetText("Error: " + err.Error())
+				return
+			}
+			msg, err := writeContactSheet(images, outDir, format, cols, rows)
+			if err != nil {
+				statusLabel.SetText("Error: " + err.Error())
+				return
+			}
+			statusLabel.SetText(msg)
+		}, w)
+	})
+
+	stackBtn := widget.NewButton("Merge Burst...", func() {
+		images := expandItems(activeItems(items, perItem))
+		if len(images) < 2 {
+			dialog.ShowInformation("No Burst", "Add at least two frames first.", w)
+			return
+		}
+		showBurstPicker(w, images, func(frames []string, mode string) {
+			statusLabel.SetText("Merging burst...")
+			merged, err := writeMergedFrames(frames, mode)
+			if err != nil {
+				statusLabel.SetText("Error: " + err.Error())
+				return
+			}
+			// replace individually added frames; frames inside a queued folder stay
+			used := map[string]bool{}
+			for _, f := range frames {
+				used[f] = true
+			}
+			var keep []string
+			for _, it := range items {
+				if used[it] {
+					delete(perItem, it)
+					continue
+				}
+				keep = append(keep, it)
+			}
+			items = append(keep, merged)
+			list.UnselectAll()
+			selectedIndex = -1
+			refreshList()
+			statusLabel.SetText(fmt.Sprintf("Merged %d frames into %s", len(frames), merged))
+		})
+	})
+
+	// decoded preview source, kept while the same file stays selected; the
+	// EXIF thumbnail stands in while previewLoading
+	var previewSrcPath string
+	var previewSrc image.Image
+	var previewLoading bool
+	previews := newPreviewCache()
+	previewSpinner := widget.NewActivity()
+
+	// soft-proofing: outputs drop the embedded profile, so "As exported" shows
+	// the raw values and "Original profile" converts them to sRGB first
+	const proofExported, proofOriginal = "As exported", "Original profile"
+	var profilePath string
+	var profile *iccProfile
+	profileLabel := widget.NewLabel("")
+	profileLabel.Wrapping = fyne.TextWrapWord
+	proofRadio := widget.NewRadioGroup([]string{proofExported, proofOriginal}, nil)
+	proofRadio.Horizontal = true
+	proofRadio.Required = true
+	proofRadio.SetSelected(proofExported)
+	display := displayProfile() // nil on sRGB displays, where raw values are right
+
+	// before/after histograms of the selected image and its clipped areas
+	histBefore, histAfter := canvas.NewImageFromImage(histogram{}.render(80)), canvas.NewImageFromImage(histogram{}.render(80))
+	for _, h := range []*canvas.Image{histBefore, histAfter} {
+		h.FillMode = canvas.ImageFillStretch
+		h.SetMinSize(fyne.NewSize(200, 80))
+	}
+	clipLabel := widget.NewLabel("")
+	clipOverlayCheck := widget.NewCheck("Highlight clipped areas", nil)
+	histBox := container.NewVBox(
+		container.NewGridWithColumns(2,
+			container.NewBorder(widget.NewLabel("Before"), nil, nil, nil, histBefore),
+			container.NewBorder(widget.NewLabel("After adjustments"), nil, nil, nil, histAfter)),
+		clipLabel, clipOverlayCheck)
+	histBox.Hide()
+	histCheck := widget.NewCheck("Histogram and clipping", nil)
+
+	// info panel; the output estimate needs a decode, so it fills in later
+	infoLabel := widget.NewLabel("")
+	infoLabel.Wrapping = fyne.TextWrapWord
+	estimateLabel := widget.NewLabel("")
+	estimateLabel.Wrapping = fyne.TextWrapWord
+	showInfo := func(path string) {
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			infoLabel.SetText("")
+			estimateLabel.SetText("")
+			return
+		}
+		infoLabel.SetText(describeFile(path))
+		opts, _ := currentOptions()
+		opts, err := newOverrideResolver().optionsFor(path, opts)
+		skip := false
+		if err == nil {
+			opts, skip, err = rules.apply(path, opts)
+		}
+		switch {
+		case err != nil:
+			estimateLabel.SetText("Estimated output: " + err.Error())
+			return
+		case skip:
+			estimateLabel.SetText("Estimated output: skipped by a rule")
+			return
+		}
+		estimateLabel.SetText("Estimated output: working...")
+		go func() {
+			msg := "Estimated output: unavailable"
+			if img, err := loadImageApplyEXIF(path); err == nil {
+				var size int64
+				if info, err := os.Stat(path); err == nil {
+					size = info.Size()
+				}
+				msg = estimateOutput(img, size, opts)
+			}
+			fyne.Do(func() {
+				if profilePath == path {
+					estimateLabel.SetText(msg)
+				}
+			})
+		}()
+	}
+
+	// detached preview window; nil while closed
+	var detached *previewWindow
+	detachBtn := widget.NewButton("Preview Window", func() {
+		if detached != nil {
+			detached.win.RequestFocus()
+			return
+		}
+		detached = newPreviewWindow(a, func(delta int) {
+			next := selectedIndex + delta
+			if next >= 0 && next < len(items) {
+				list.Select(widget.ListItemID(next))
+			}
+		}, func() { detached = nil })
+		if selectedIndex >= 0 && selectedIndex < len(items) {
+			showPreview(items[selectedIndex])
+		}
+	})
+
+	showPreview = func(path string) {
+		if profilePath != path {
+			var msg string
+			profile, msg = previewProfile(path)
+			profilePath = path
+			if display != nil && msg != "" {
+				msg += fmt.Sprintf("; shown in the colours of your display (%s)", display.desc)
+			}
+			profileLabel.SetText(msg)
+			showInfo(path)
+		}
+		proof := profile != nil && proofRadio.Selected == proofOriginal
+		var img *canvas.Image
+		t, adj, style := perItem[path].transform, currentAdjust(), currentStyle()
+		// decoded like the output (EXIF orientation applied), not loaded by the
+		// canvas, which would show portrait phone photos sideways
+		if previewSrcPath != path {
+			previewSrcPath, previewSrc, previewLoading = path, previews.get(path), false
+			if previewSrc == nil {
+				previewSrc, previewLoading = exifThumbnail(path), true
+				go func() {
+					src, err := decodePreview(path)
+					fyne.Do(func() {
+						if err == nil {
+							previews.put(path, src)
+						}
+						if previewSrcPath != path {
+							return
+						}
+						previewLoading = false
+						if err == nil {
+							previewSrc = src
+						}
+						showPreview(path)
+					})
+				}()
+			}
+		}
+		if previewSrc != nil {
+			src := previewSrc
+			switch {
+			case proof && display != nil:
+				src = profile.convert(src, display)
+			case proof:
+				src = profile.toSRGB(src)
+			case display != nil:
+				src = srgbProfile.convert(src, display) // exported pixels are sRGB
+			}
+			shown := style.apply(adj.apply(t.apply(src)))
+			if histCheck.Checked {
+				before := t.apply(previewSrc)
+				after := before
+				if !perItem[path].skipAutoFix {
+					if wbCheck.Checked {
+						after = autoWhiteBalance(after)
+					}
+					if exposureCheck.Checked {
+						after = autoExpose(after)
+					}
+				}
+				hb, ha := computeHistogram(before), computeHistogram(style.apply(adj.apply(after)))
+				histBefore.Image, histAfter.Image = hb.render(80), ha.render(80)
+				histBefore.Refresh()
+				histAfter.Refresh()
+				clipLabel.SetText(clipSummary(hb, ha))
+				if clipOverlayCheck.Checked {
+					shown = clippingOverlay(shown)
+				}
+			}
+			img = canvas.NewImageFromImage(shown)
+		} else if histCheck.Checked && !previewLoading {
+			clipLabel.SetText("No histogram for this item")
+		}
+		if img == nil && previewLoading {
+			img = canvas.NewImageFromImage(nil)
+		} else if img == nil {
+			img = canvas.NewImageFromFile(path)
+		}
+		img.FillMode = canvas.ImageFillContain
+		img.SetMinSize(fyne.NewSize(400, 400))
+		previewContainer.Objects = []fyne.CanvasObject{img}
+		if previewLoading {
+			previewContainer.Objects = append(previewContainer.Objects, previewSpinner)
+			previewSpinner.Start()
+		} else {
+			previewSpinner.Stop()
+		}
+		previewContainer.Refresh()
+		if detached != nil {
+			detached.show(img, path)
+		}
+	}
+
+	proofRadio.OnChanged = func(string) { refreshPreview(0) }
+	histCheck.OnChanged = func(on bool) {
+		if on {
+			histBox.Show()
+		} else {
+			histBox.Hide()
+		}
+		refreshPreview(0)
+	}
+	clipOverlayCheck.OnChanged = func(bool) { refreshPreview(0) }
+	wbCheck.OnChanged = func(bool) { refreshPreview(0) }
+	exposureCheck.OnChanged = func(bool) { refreshPreview(0) }
+
+	// per-item edits for the selected file
+	straightenLabel := widget.NewLabel("Straighten: 0.0°")
+	straightenSlider := widget.NewSlider(-straightenMax, straightenMax)
+	straightenSlider.Step = 0.1
+	skipAutoFixCheck := widget.NewCheck("Skip auto colour/exposure for this image", nil)
+	priorities := []int{priorityFirst, 0, priorityLast}
+	prioritySelect := widget.NewSelect([]string{"Process first", "Normal", "Process last"}, nil)
+	prioritySelect.SetSelectedIndex(1)
+
+	// editSelected applies edit to the selected file's settings and refreshes it.
+	editSelected := func(edit func(*itemSettings)) {
+		if selectedIndex < 0 || selectedIndex >= len(items) {
+			return
+		}
+		p := items[selectedIndex]
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			dialog.ShowInformation("Folder Selected", "Per-image edits apply to single images.", w)
+			return
+		}
+		st := perItem[p]
+		edit(&st)
+		if st == (itemSettings{}) {
+			delete(perItem, p)
+		} else {
+			perItem[p] = st
+		}
+		list.RefreshItem(widget.ListItemID(selectedIndex))
+		showPreview(p)
+	}
+
+	straightenSlider.OnChanged = func(v float64) {
+		straightenLabel.SetText(fmt.Sprintf("Straighten: %.1f°", v))
+	}
+	straightenSlider.OnChangeEnded = func(v float64) {
+		editSelected(func(st *itemSettings) { st.transform.angle = math.Round(v*10) / 10 })
+	}
+	skipAutoFixCheck.OnChanged = func(on bool) {
+		if selectedIndex >= 0 && selectedIndex < len(items) && perItem[items[selectedIndex]].skipAutoFix != on {
+			editSelected(func(st *itemSettings) { st.skipAutoFix = on })
+		}
+	}
+	// unlike the edits above, priority applies to queued folders too
+	prioritySelect.OnChanged = func(string) {
+		if selectedIndex < 0 || selectedIndex >= len(items) {
+			return
+		}
+		p := items[selectedIndex]
+		st := perItem[p]
+		if st.priority == priorities[prioritySelect.SelectedIndex()] {
+			return
+		}
+		st.priority = priorities[prioritySelect.SelectedIndex()]
+		if st == (itemSettings{}) {
+			delete(perItem, p)
+		} else {
+			perItem[p] = st
+		}
+		list.RefreshItem(widget.ListItemID(selectedIndex))
+	}
+	transformBtn := func(label string, op func(itemTransform) itemTransform) *widget.Button {
+		return widget.NewButton(label, func() {
+			editSelected(func(st *itemSettings) {
+				st.transform = op(st.transform)
+				straightenSlider.SetValue(st.transform.angle)
+			})
+		})
+	}
+	transformBar := container.NewHBox(
+		transformBtn("Rotate Left", itemTransform.rotateCCW),
+		transformBtn("Rotate Right", itemTransform.rotateCW),
+		transformBtn("Flip H", itemTransform.flipHorizontal),
+		transformBtn("Flip V", itemTransform.flipVertical),
+		transformBtn("Reset", func(itemTransform) itemTransform { return itemTransform{} }),
+	)
+
+	removeBtn := widget.NewButton("Remove Selected", func() {
+		if selectedIndex >= 0 && selectedIndex < len(items) {
+			items = append(items[:selectedIndex], items[selectedIndex+1:]...)
+			list.Unselect(widget.ListItemID(selectedIndex))
+			selectedIndex = -1
+			refreshList()
+			preview.Text = "No preview selected"
+			previewContainer.Refresh()
+			if detached != nil {
+				detached.clear()
+			}
+		}
+	})
+
+	clearBtn := widget.NewButton("Clear All", func() {
+		items = nil
+		perItem = map[string]itemSettings{}
+		queueIdx = newQueueIndex()
+		statuses = map[string]string{}
+		selectedIndex = -1
+		selection.clear()
+		refreshList()
+		preview.Text = "No preview selected"
+		previewContainer.Refresh()
+		if detached != nil {
+			detached.clear()
+		}
+	})
+
+	// preview on select
+	list.OnSelected = func(id widget.ListItemID) {
+		if id < 0 || int(id) >= len(items) {
+			selectedIndex = -1
+			return
+		}
+		selectedIndex = int(id)
+		selection.click(items, selectedIndex, currentModifiers())
+		refreshList()
+		straightenSlider.SetValue(perItem[items[id]].transform.angle)
+		skipAutoFixCheck.SetChecked(perItem[items[id]].skipAutoFix)
+		prioritySelect.SetSelectedIndex(1 - perItem[items[id]].priority)
+		showPreview(items[id])
+	}
+
+	left := container.NewBorder(
+		container.NewVBox(widget.NewLabel("Files to compress"), statsLabel,
+			container.NewHBox(widget.NewLabel("Tick:"), selectAllBtn, selectNoneBtn, invertBtn),
+			widget.NewLabel("Click an item to preview, Cmd/Ctrl- or Shift-click to select several, untick to leave it out of runs")),
+		nil, nil, nil,
+		container.NewStack(list, emptyHint), // widget.List scrolls and creates rows for the visible part only
+	)
+
+	opts := container.NewVBox(
 		widget.NewLabel("Preview"),
 		previewContainer,
+		container.NewBorder(nil, nil, widget.NewLabel("Colours:"), nil, proofRadio),
+		infoLabel,
+		estimateLabel,
+		profileLabel,
+		container.NewHBox(histCheck, detachBtn),
+		histBox,
+		transformBar,
+		container.NewBorder(nil, nil, straightenLabel, nil, straightenSlider),
+		skipAutoFixCheck,
+		container.NewGridWithColumns(2, widget.NewLabel("Priority:"), prioritySelect),
 		widget.NewSeparator(),
 		container.NewGridWithColumns(2, widget.NewLabel("Output folder:"), outEntry),
-		container.NewHBox(browseOutBtn),
-		targetEntry,
+		container.NewHBox(widget.NewLabel("Recent:"), recentOutBox),
+		container.NewHBox(browseOutBtn, destBtn, credBtn, destLabel),
+		container.NewGridWithColumns(2, widget.NewLabel("Output names:"), namesSelect),
+		nameLenEntry,
+		container.NewGridWithColumns(2, widget.NewLabel("Preset:"), presetSelect),
+		container.NewBorder(nil, nil, nil, stepsBtn, stepsLabel),
+		container.NewGridWithColumns(2, widget.NewLabel("Mode:"), modeSelect),
+		container.NewGridWithColumns(2, widget.NewLabel("Format:"), formatSelect),
+		container.NewGridWithColumns(2, widget.NewLabel("PNG colours:"), pngColorSelect),
+		container.NewGridWithColumns(2, widget.NewLabel("Animated GIFs:"), animSelect),
+		container.NewBorder(nil, nil, nil, qualityBtn, targetEntry),
+		container.NewHBox(rulesBtn, rulesLabel),
+		container.NewBorder(nil, nil, qualityLabel, nil, qualitySlider),
+		shrinkCheck,
 		container.NewHBox(widthEntry, heightEntry),
-		startBtn,
+		container.NewHBox(minWEntry, minHEntry),
+		gpuCheck,
+		passThroughCheck,
+		searchableCheck,
+		infoSidecarCheck,
+		softwareTagCheck,
+		exifThumbCheck,
+		dpiEntry,
+		container.NewGridWithColumns(3, bleedEntry, bleedSelect, bleedColorEntry),
+		trashCheck,
+		integrityCheck,
+		placeholderCheck,
+		dupCheck,
+		metricsCheck,
+		levelCheck,
+		container.NewHBox(wbCheck, exposureCheck),
+		adjustPanel,
+		jpegPanel,
+		skipDoneCheck,
+		repackCheck,
+		dedupSelect,
+		protectCheck,
+		container.NewGridWithColumns(2, widget.NewLabel("Per-file timeout (s, 0 = none):"), timeoutEntry),
+		container.NewGridWithColumns(2, widget.NewLabel("Flag files over N× the median time (0 = off):"), stuckEntry),
+		stuckSkipCheck,
+		container.NewBorder(nil, nil, powerCheck, nil, container.NewGridWithColumns(2, widget.NewLabel("below battery %:"), batteryEntry)),
+		container.NewHBox(filterBtn, filterLabel),
+		container.NewHBox(startBtn, startSelectedBtn, schedulesBtn, resultsBtn, reviewBtn, restoreBtn, verifyBtn, sheetBtn, compareBtn, stackBtn, mailBtn),
 		progressBar,
 		statusLabel,
+		pauseBar,
+		runLog.view(w),
+		uploadBar,
+		uploadLabel,
 		widget.NewSeparator(),
-		container.NewHBox(removeBtn, clearBtn, addBtn),
+		container.NewHBox(removeBtn, clearBtn, addBtn, addFolderBtn, analyzeBtn, recentInSelect, addURLsBtn, auditBtn, formatsBtn, updatesBtn, privacyBtn, scratchBtn, permsBtn, tourBtn),
 	)
 
-	content := container.NewHSplit(left, opts)
+	content := container.NewHSplit(left, container.NewVScroll(opts))
 	content.Offset = 0.35
 	w.SetContent(content)
+
+	// names for controls whose label changes with their value or that stand alone
+	accessibleName(list, "Files to compress")
+	accessibleName(qualitySlider, "JPEG quality")
+	accessibleName(straightenSlider, "Straighten angle")
+	accessibleName(bleedSelect, "Bleed fill")
+	accessibleName(dedupSelect, "Identical outputs")
+	addKeyboardShortcuts(w, addBtn, addFolderBtn, startBtn, removeBtn, list)
+	watchFocus(w)
+	sessionFields = map[string]sessionField{
+		"output": entryField(outEntry), "target_kb": entryField(targetEntry), "shrink": checkField(shrinkCheck),
+		"max_width": entryField(widthEntry), "max_height": entryField(heightEntry),
+		"min_width": entryField(minWEntry), "min_height": entryField(minHEntry),
+		"preset": selectField(presetSelect), "mode": selectField(modeSelect), "format": selectField(formatSelect),
+		"animation": selectField(animSelect), "png_color": selectField(pngColorSelect),
+		"placeholders": checkField(placeholderCheck), "duplicates": checkField(dupCheck), "metrics": checkField(metricsCheck),
+		"level": checkField(levelCheck), "white_balance": checkField(wbCheck), "exposure": checkField(exposureCheck),
+		"brightness": sliderField(brightSlider), "contrast": sliderField(contrastSlider),
+		"saturation": sliderField(saturationSlider), "gamma": sliderField(gammaSlider),
+		"tone": selectField(toneSelect), "vignette": checkField(vignetteCheck), "grain": checkField(grainCheck),
+		"jpeg_optimize": checkField(optimizeCheck), "jpeg_progressive": checkField(progressiveCheck),
+		"jpeg_restart": entryField(restartEntry), "jpeg_scans": entryField(scansEntry),
+		"timeout": entryField(timeoutEntry), "stuck_factor": entryField(stuckEntry), "stuck_skip": checkField(stuckSkipCheck),
+		"skip_done": checkField(skipDoneCheck), "repack": checkField(repackCheck),
+		"names": selectField(namesSelect), "name_max": entryField(nameLenEntry), "gpu_resize": checkField(gpuCheck),
+		"power_aware": checkField(powerCheck), "battery_floor": entryField(batteryEntry),
+		"dedup": selectField(dedupSelect), "pass_through": checkField(passThroughCheck),
+		"searchable": checkField(searchableCheck), "info_sidecar": checkField(infoSidecarCheck),
+		"software_tag": checkField(softwareTagCheck), "exif_thumbnail": checkField(exifThumbCheck), "print_dpi": entryField(dpiEntry),
+		"bleed": entryField(bleedEntry), "bleed_fill": selectField(bleedSelect), "bleed_colour": entryField(bleedColorEntry), "trash_originals": checkField(trashCheck),
+		"integrity_manifest": checkField(integrityCheck),
+	}
+	// runUnattended runs inputs with no dialogs, for scheduled jobs and
+	// Shortcuts: the window takes on settings for the run and gets its own
+	// back afterwards. It returns the output folder.
+	runUnattended := func(label string, inputs []string, settings map[string]string) (string, error) {
+		if pausedRun != nil {
+			return "", fmt.Errorf("a run is paused for power")
+		}
+		keep := captureSettings(sessionFields)
+		defer applySettings(sessionFields, keep)
+		applySettings(sessionFields, settings)
+		outFolder := strings.TrimSpace(outEntry.Text)
+		if _, err := expandOutputDir(outFolder, "", time.Now()); outFolder == "" || err != nil {
+			return "", fmt.Errorf("output folder %q is not usable", outFolder)
+		}
+		opts, pr := currentOptions()
+		if err := opts.jpeg.validate(); err != nil {
+			return "", err
+		}
+		if _, err := parseStuckFactor(stuckEntry.Text); err != nil {
+			return "", err
+		}
+		timeoutSecs := 0
+		fmt.Sscanf(timeoutEntry.Text, "%d", &timeoutSecs)
+		images, _ := excludeOutputs(expandItems(inputs), outFolder, time.Now())
+		if len(images) == 0 {
+			return "", fmt.Errorf("no images in %s", strings.Join(inputs, ", "))
+		}
+		for _, issue := range preflight(images, outFolder, opts) {
+			if issue.fatal {
+				return "", fmt.Errorf("not run: %s", issue.problem)
+			}
+			runLog.add("WARNING %s: %s", label, issue.problem)
+		}
+		runLog.add("%s: %d images", label, len(images))
+		runBatch(images, outFolder, pr, opts, time.Duration(timeoutSecs)*time.Second)
+		return outFolder, nil
+	}
+	runScheduled := func(job scheduledJob) {
+		if _, err := runUnattended(fmt.Sprintf("Scheduled job %q", job.Name), []string{job.Input}, job.Settings); err != nil {
+			runLog.add("ERROR scheduled job %q: %v", job.Name, err)
+		}
+	}
+	listenShortcutURLs(func(raw string) {
+		req, err := parseShortcutURL(raw)
+		if err != nil {
+			runLog.add("ERROR shortcut: %v", err)
+			if u := callback(req.failure, map[string]string{"errorMessage": err.Error()}); u != nil {
+				a.OpenURL(u)
+			}
+			return
+		}
+		fail := func(err error) {
+			runLog.add("ERROR shortcut %s: %v", req.action, err)
+			if u := callback(req.failure, map[string]string{"errorMessage": err.Error()}); u != nil {
+				a.OpenURL(u)
+			}
+		}
+		if req.out != "" {
+			if err := checkShortcutOutput(req.out, recentFolders(prefs, recentOutputsKey)); err != nil {
+				fail(err)
+				return
+			}
+		}
+		run := func() {
+			settings := captureSettings(sessionFields)
+			if req.preset != "" {
+				settings["preset"] = req.preset
+			}
+			if req.out != "" {
+				settings["output"] = req.out
+			}
+			out, err := runUnattended("Shortcut "+req.action, req.paths, settings)
+			if err != nil {
+				fail(err)
+				return
+			}
+			failed := 0
+			for _, r := range results {
+				if r.err != nil {
+					failed++
+				}
+			}
+			done := map[string]string{"done": fmt.Sprint(len(results) - failed), "failed": fmt.Sprint(failed), "output": out}
+			if u := callback(req.success, done); u != nil {
+				a.OpenURL(u)
+			}
+		}
+		if prefs.Bool(shortcutTrustKey) {
+			run()
+			return
+		}
+		w.RequestFocus()
+		showShortcutConfirm(w, req, func(allow, always bool) {
+			if !allow {
+				fail(fmt.Errorf("declined in Image Compressor"))
+				return
+			}
+			if always {
+				prefs.SetBool(shortcutTrustKey, true)
+			}
+			run()
+		})
+	})
+	startScheduler := func() {
+		go func() {
+			for range time.Tick(scheduleCheck) {
+				fyne.Do(func() {
+					// runs are blocking, so a due job never starts mid-run
+					now := time.Now()
+					for i := range schedules {
+						if schedules[i].due(now) {
+							schedules[i].LastRun = now
+							saveSchedules(prefs, schedules)
+							runScheduled(schedules[i])
+						}
+					}
+				})
+			}
+		}()
+	}
+
+	// offer the previous session, flagging one that ended in a crash, and
+	// autosave this one
+	// autosaving starts once the old session is restored or declined, so it
+	// cannot overwrite that session while the question is still open
+	startAutosave := func() {
+		// the queue is settled now, so scratch files it does not hold can go
+		if freed := scratch.trim(expandItems(items), false); freed > 0 {
+			runLog.add("Cleared %s of old scratch files from %s", formatSize(freed), scratch.base())
+		}
+		go func() {
+			for range time.Tick(autosaveInterval) {
+				fyne.Do(func() { persistQueue(false) })
+			}
+		}()
+		startScheduler() // after a restore, so jobs hand back the restored settings
+	}
+	a.Lifecycle().SetOnStarted(func() {
+		w.Canvas().Focus(addBtn) // keyboard users start where new users do
+		saved, err := loadQueue(queueDir)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("saved session unreadable: %v", err), w)
+			startAutosave()
+			return
+		}
+		if (len(saved.Items) == 0 && len(saved.Settings) == 0) || len(items) > 0 {
+			startAutosave()
+			if len(items) == 0 && !prefs.Bool(onboardedKey) {
+				startTour()
+			}
+			return
+		}
+		title, msg := "Restore Session", fmt.Sprintf("Restore the %d items and the settings from your last session?", len(saved.Items))
+		if !saved.CleanExit && saved.Settings != nil { // older saves had no settings or exit flag
+			title = "Recover Session"
+			msg = "The app did not shut down cleanly last time. " + msg
+		}
+		dialog.ShowConfirm(title, msg, func(ok bool) {
+			defer startAutosave()
+			if !ok {
+				return
+			}
+			items, perItem, statuses = saved.restore()
+			applySettings(sessionFields, saved.Settings)
+			refreshList()
+		}, w)
+	})
+	a.Lifecycle().SetOnStopped(func() {
+		persistQueue(true)
+		usage.save(prefs)
+	})
+
+	checkUpdatesAtLaunch(a, w, prefs, uploadsBusy)
+	startTelemetry(w, prefs)
 	w.ShowAndRun()
 }
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//
+// Output manifest
+// - Lives in the output folder and remembers which input produced which output
+// - Lets a re-run skip inputs whose output is still up to date, even though
+//   the no-overwrite policy would otherwise write "name (1).jpg" every time
+//
+
+const manifestFileName = ".imgcompress-manifest.json"
+
+type manifestEntry struct {
+	Output    string    `json:"output"`
+	InSize    int64     `json:"in_size"`
+	InModTime time.Time `json:"in_mtime"`
+	OutSize   int64     `json:"out_size"`
+	Settings  string    `json:"settings"`
+}
+
+type outputManifest struct {
+	path    string
+	Entries map[string]manifestEntry `json:"entries"` // keyed by absolute input path
+}
+
+func loadManifest(outDir string) (*outputManifest, error) {
+	m := &outputManifest{
+		path:    filepath.Join(outDir, manifestFileName),
+		Entries: map[string]manifestEntry{},
+	}
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return m, fmt.Errorf("%s: %v", m.path, err)
+	}
+	if m.Entries == nil {
+		m.Entries = map[string]manifestEntry{}
+	}
+	return m, nil
+}
+
+func (m *outputManifest) save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, data, 0644)
+}
+
+// settingsSignature fingerprints everything that affects the output bytes.
+func settingsSignature(opts compressOptions) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%+v", opts)))
+	return fmt.Sprintf("%x", sum[:8])
+}
+
+// upToDate returns the recorded output when the input is unchanged, the
+// settings match, and the output file is still there untouched.
+func (m *outputManifest) upToDate(inPath string, opts compressOptions) (string, bool) {
+	abs, err := filepath.Abs(inPath)
+	if err != nil {
+		return "", false
+	}
+	e, ok := m.Entries[abs]
+	if !ok || e.Settings != settingsSignature(opts) {
+		return "", false
+	}
+	in, err := os.Stat(inPath)
+	if err != nil || in.Size() != e.InSize || !in.ModTime().Equal(e.InModTime) {
+		return "", false
+	}
+	out, err := os.Stat(e.Output)
+	if err != nil || out.Size() != e.OutSize {
+		return "", false
+	}
+	return e.Output, true
+}
+
+func (m *outputManifest) record(res fileResult, opts compressOptions) {
+	abs, err := filepath.Abs(res.inPath)
+	if err != nil {
+		return
+	}
+	in, err := os.Stat(res.inPath)
+	if err != nil {
+		return
+	}
+	m.Entries[abs] = manifestEntry{
+		Output:    res.outPath,
+		InSize:    in.Size(),
+		InModTime: in.ModTime(),
+		OutSize:   res.outSize,
+		Settings:  settingsSignature(opts),
+	}
+}
+
+// manifestSet holds one manifest per output folder for runs whose output
+// template puts files in several folders.
+type manifestSet struct {
+	byDir  map[string]*outputManifest
+	onLoad func(err error) // told about unreadable manifests, which start over
+}
+
+func newManifestSet(onLoad func(err error)) *manifestSet {
+	return &manifestSet{byDir: map[string]*outputManifest{}, onLoad: onLoad}
+}
+
+func (s *manifestSet) get(outDir string) *outputManifest {
+	if m, ok := s.byDir[outDir]; ok {
+		return m
+	}
+	m, err := loadManifest(outDir)
+	if err != nil && s.onLoad != nil {
+		s.onLoad(err)
+	}
+	s.byDir[outDir] = m
+	return m
+}
+
+// save writes every manifest, returning the first error.
+func (s *manifestSet) save() error {
+	var first error
+	for _, m := range s.byDir {
+		if err := m.save(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// Quality metrics between a reference and a compressed image
+// - Both are compared at the reference's size, capped at metricMaxSide
+// - SSIM on luma over 8×8 windows (stride 4), PSNR over RGB
+//
+
+const metricMaxSide = 1024
+
+// metricPair brings a and b to the same (bounded) size as NRGBA.
+func metricPair(ref, cmp image.Image) (*image.NRGBA, *image.NRGBA) {
+	rb := ref.Bounds()
+	w, h := rb.Dx(), rb.Dy()
+	if w > metricMaxSide || h > metricMaxSide {
+		fit := imaging.Fit(ref, metricMaxSide, metricMaxSide, imaging.Box)
+		w, h = fit.Bounds().Dx(), fit.Bounds().Dy()
+	}
+	a := imaging.Resize(ref, w, h, imaging.Box)
+	b := imaging.Resize(cmp, w, h, imaging.Box)
+	return a, b
+}
+
+func lumaPlane(img *image.NRGBA) []float64 {
+	b := img.Bounds()
+	out := make([]float64, b.Dx()*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			o := y*img.Stride + x*4
+			out[y*b.Dx()+x] = 0.299*float64(img.Pix[o]) + 0.587*float64(img.Pix[o+1]) + 0.114*float64(img.Pix[o+2])
+		}
+	}
+	return out
+}
+
+// ssim returns the mean structural similarity (1 = identical).
+func ssim(ref, cmp image.Image) float64 {
+	a, b := metricPair(ref, cmp)
+	w, h := a.Bounds().Dx(), a.Bounds().Dy()
+	la, lb := lumaPlane(a), lumaPlane(b)
+
+	const (
+		win = 8
+		c1  = (0.01 * 255) * (0.01 * 255)
+		c2  = (0.03 * 255) * (0.03 * 255)
+	)
+	if w < win || h < win {
+		return ssimWindow(la, lb, w, 0, 0, w, h, c1, c2)
+	}
+	var sum float64
+	var n int
+	for y := 0; y+win <= h; y += win / 2 {
+		for x := 0; x+win <= w; x += win / 2 {
+			sum += ssimWindow(la, lb, w, x, y, win, win, c1, c2)
+			n++
+		}
+	}
+	return sum / float64(n)
+}
+
+func ssimWindow(a, b []float64, stride, x0, y0, ww, wh int, c1, c2 float64) float64 {
+	var ma, mb float64
+	n := float64(ww * wh)
+	if n == 0 {
+		return 1 // nothing to compare
+	}
+	for y := y0; y < y0+wh; y++ {
+		for x := x0; x < x0+ww; x++ {
+			ma += a[y*stride+x]
+			mb += b[y*stride+x]
+		}
+	}
+	ma /= n
+	mb /= n
+	if n < 2 {
+		// a single pixel has no variance; only the means compare
+		return (2*ma*mb + c1) / (ma*ma + mb*mb + c1)
+	}
+	var va, vb, cov float64
+	for y := y0; y < y0+wh; y++ {
+		for x := x0; x < x0+ww; x++ {
+			da, db := a[y*stride+x]-ma, b[y*stride+x]-mb
+			va += da * da
+			vb += db * db
+			cov += da * db
+		}
+	}
+	va /= n - 1
+	vb /= n - 1
+	cov /= n - 1
+	return ((2*ma*mb + c1) * (2*cov + c2)) / ((ma*ma + mb*mb + c1) * (va + vb + c2))
+}
+
+// psnr returns the peak signal-to-noise ratio in dB (+Inf when identical).
+func psnr(ref, cmp image.Image) float64 {
+	a, b := metricPair(ref, cmp)
+	var se float64
+	var n int
+	for i := 0; i < len(a.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			d := float64(a.Pix[i+c]) - float64(b.Pix[i+c])
+			se += d * d
+		}
+		n += 3
+	}
+	if se == 0 {
+		return math.Inf(1)
+	}
+	mse := se / float64(n)
+	return 10 * math.Log10(255*255/mse)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+//
+// Output names
+// - macOS hands out decomposed (NFD) names, which other systems show as
+//   broken accents or treat as different files; NFC composes them
+// - ASCII drops accents and replaces anything else (emoji, CJK) with "_"
+//   for old FTP servers, CD-ROMs and strict web hosts
+// - Both portable modes also replace characters Windows forbids and
+//   rename its reserved device names
+// - A length limit truncates long names on a character boundary, leaving
+//   room for the " (n)" suffix added to avoid overwrites
+//
+
+const (
+	namesKeep  = ""
+	namesNFC   = "nfc"
+	namesASCII = "ascii"
+)
+
+// uniqueSuffixRoom is the length of the largest " (n)" suffix expected.
+const uniqueSuffixRoom = len(" (999)")
+
+type nameOptions struct {
+	unicode  string // namesKeep, namesNFC or namesASCII
+	maxBytes int    // whole file name including extension; 0 = no limit
+}
+
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// toASCII strips accents and replaces runs of other non-ASCII characters.
+func toASCII(s string) string {
+	var sb strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// combining accent: drop it, keep the base letter
+		case r < utf8.RuneSelf:
+			sb.WriteRune(r)
+		case !strings.HasSuffix(sb.String(), "_"):
+			sb.WriteByte('_') // one for a whole run of emoji or CJK
+		}
+	}
+	return sb.String()
+}
+
+// portable replaces characters and names that Windows cannot store.
+func portable(stem string) string {
+	stem = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, stem)
+	stem = strings.TrimRight(stem, ". ")
+	if windowsReserved[strings.ToUpper(stem)] {
+		stem += "_"
+	}
+	if stem == "" {
+		stem = "image"
+	}
+	return stem
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// outputStem turns an input file name without extension into the output
+// name without extension.
+func (o nameOptions) outputStem(stem, ext string) string {
+	switch o.unicode {
+	case namesNFC:
+		stem = portable(norm.NFC.String(stem))
+	case namesASCII:
+		stem = portable(toASCII(stem))
+	}
+	if o.maxBytes > 0 {
+		room := max(1, o.maxBytes-len(ext)-uniqueSuffixRoom)
+		stem = strings.TrimRight(truncateUTF8(stem, room), ". ")
+		if stem == "" {
+			stem = "image"
+		}
+	}
+	return stem
+}
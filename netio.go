@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+//
+// Network volumes
+// - SMB and NFS shares drop out for a moment now and then; a multi-hour
+//   batch against a NAS should ride that out rather than fail every file
+//   that was open at the time
+// - Input reads and output writes are retried with backoff (1s, 2s, 4s,
+//   8s) when the error is a transient one: timeouts, stale handles, resets,
+//   a share that went away. Anything else fails at once
+// - Outputs are written to a hidden temp file beside the final name and
+//   renamed into place, so the rename stays on the same volume and a write
+//   cut short never leaves a truncated image under the real name
+//
+
+const (
+	ioRetries = 4
+	ioBackoff = time.Second
+)
+
+// isTransient reports whether err looks like a network blip worth retrying.
+func isTransient(err error) bool {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var errno syscall.Errno
+	return errors.As(err, &errno) && transientErrnos[errno]
+}
+
+// retryIO runs fn until it succeeds, fails for good, or ctx ends.
+func retryIO(ctx context.Context, fn func() error) error {
+	wait := ioBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == ioRetries || !isTransient(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// atomicWrite has write fill a temp file next to path, keeping its
+// extension for encoders that go by it, and renames that into place
+// unless ctx was cancelled meanwhile.
+func atomicWrite(ctx context.Context, path string, write func(tmp string) error) error {
+	dir, base := filepath.Split(path)
+	ext := filepath.Ext(base)
+	tmp := filepath.Join(dir, "."+base[:len(base)-len(ext)]+".partial"+ext)
+	err := retryIO(ctx, func() error { return write(tmp) })
+	if err == nil {
+		err = ctx.Err() // abandoned while writing: never put it in place
+	}
+	if err == nil {
+		err = retryIO(ctx, func() error { return disk.Rename(tmp, path) })
+	}
+	if err != nil {
+		disk.Remove(tmp)
+	}
+	return err
+}
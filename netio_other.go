@@ -0,0 +1,14 @@
+//go:build !darwin && !linux
+
+package main
+
+import "syscall"
+
+// transientErrnos are the Windows errors for a share that is briefly away.
+var transientErrnos = map[syscall.Errno]bool{
+	53:   true, // ERROR_BAD_NETPATH
+	59:   true, // ERROR_UNEXP_NET_ERR
+	64:   true, // ERROR_NETNAME_DELETED
+	121:  true, // ERROR_SEM_TIMEOUT
+	1231: true, // ERROR_NETWORK_UNREACHABLE
+}
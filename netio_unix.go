@@ -0,0 +1,22 @@
+//go:build darwin || linux
+
+package main
+
+import "syscall"
+
+// transientErrnos are the errors SMB and NFS mounts return while the
+// server or the network is briefly away.
+var transientErrnos = map[syscall.Errno]bool{
+	syscall.EAGAIN:       true,
+	syscall.EINTR:        true,
+	syscall.EIO:          true, // soft NFS mounts report timeouts as EIO
+	syscall.ESTALE:       true,
+	syscall.ETIMEDOUT:    true,
+	syscall.ECONNRESET:   true,
+	syscall.ECONNABORTED: true,
+	syscall.ENETDOWN:     true,
+	syscall.ENETUNREACH:  true,
+	syscall.ENETRESET:    true,
+	syscall.EHOSTDOWN:    true,
+	syscall.EHOSTUNREACH: true,
+}
@@ -0,0 +1,162 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+//
+// First run
+// - The first launch, with nothing queued and no session to restore, walks
+//   through the basics in four short pages: adding images by dropping them
+//   on the window (tried out live), what a target size does, the output
+//   folder, and the example preset
+// - "Finish" can create ~/Pictures/Compressed as the output folder and pick
+//   the example preset; "Skip" leaves the window as it is. Either way the
+//   tour does not open again on its own; "Getting Started..." reopens it
+// - An empty queue shows where to drop files instead of a blank list
+//
+
+const onboardedKey = "onboarding.done"
+
+// onboardingChoice is what the user picked on the tour's last page.
+type onboardingChoice struct {
+	outDir    string // "" leaves the output folder as it is
+	usePreset bool
+}
+
+// defaultOutputDir is the folder the tour offers to create.
+func defaultOutputDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "Pictures", "Compressed")
+}
+
+// emptyQueueHint is shown over the queue while it is empty.
+func emptyQueueHint() fyne.CanvasObject {
+	icon := widget.NewIcon(theme.DownloadIcon())
+	title := widget.NewLabelWithStyle("Drop images or folders here", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	sub := widget.NewLabelWithStyle("or use Add Files/Folders below. JPEG, PNG, WebP, TIFF, BMP, GIF and PDF files are read.",
+		fyne.TextAlignCenter, fyne.TextStyle{})
+	sub.Wrapping = fyne.TextWrapWord
+	frame := canvas.NewRectangle(nil)
+	frame.StrokeColor = theme.Color(theme.ColorNameDisabled)
+	frame.StrokeWidth = 2
+	frame.CornerRadius = theme.Size(theme.SizeNameInputRadius)
+	return container.NewStack(frame, container.NewPadded(container.NewCenter(container.NewVBox(icon, title, sub))))
+}
+
+// showOnboarding opens the tour; done gets the final page's choices, or
+// nothing when skipped. The returned function is called with the number of
+// files and folders each drop on the window queued, for the first page.
+func showOnboarding(w fyne.Window, prefs fyne.Preferences, done func(onboardingChoice)) (dropped func(n int)) {
+	prefs.SetBool(onboardedKey, true)
+	wrap := func(text string) *widget.Label {
+		l := widget.NewLabel(text)
+		l.Wrapping = fyne.TextWrapWord
+		return l
+	}
+
+	dropStatus := widget.NewLabelWithStyle("Waiting for a drop...", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+	dropPage := container.NewVBox(
+		wrap("Image Compressor makes smaller copies of your photos and leaves the originals alone."),
+		wrap("Try it now: drag a few photos or a folder from Finder or Explorer onto this window. "+
+			"They join the queue on the left; \"Add Files/Folders\" does the same from a file picker."),
+		container.NewGridWrap(fyne.NewSize(460, 140), emptyQueueHint()),
+		dropStatus,
+	)
+	targetPage := container.NewVBox(
+		wrap("Target size is the most each compressed file may weigh, in KB. Enter 500 and every "+
+			"output comes out at 500KB or less: the app tries JPEG qualities until the largest one that fits."),
+		wrap("Photos that cannot get that small even at the lowest quality are marked in the results. "+
+			"Tick \"Downscale when the target size can't be reached\" to shrink them until they fit."),
+		wrap("Leave it empty or 0 to use the default quality for each format instead (Quality Defaults...), "+
+			"which keeps the look the same and lets the size vary."),
+	)
+	outDir := defaultOutputDir()
+	createCheck := widget.NewCheck("Create "+outDir+" and save outputs there", nil)
+	createCheck.SetChecked(outDir != "")
+	if outDir == "" {
+		createCheck.Disable()
+	}
+	outPage := container.NewVBox(
+		wrap("Compressed copies go to the output folder. It can be a fixed folder, or a pattern such as "+
+			"{input_folder}/compressed to put them next to each original."),
+		createCheck,
+		wrap("You can change it any time under \"Output folder\"; recent choices appear as quick buttons."),
+	)
+	presetCheck := widget.NewCheck("Start with the \""+examplePreset+"\" preset", nil)
+	presetCheck.SetChecked(true)
+	presetPage := container.NewVBox(
+		wrap("Presets set the size and format for a purpose in one step: social media crops, app icons, "+
+			"or the example below for photos on a website, which keeps each JPEG under 500KB."),
+		presetCheck,
+		wrap("Pick \"Custom\" in the Preset list to go back to your own settings. Then press "+
+			"\"Start Compress\"; \"Results\" shows what each file came to."),
+	)
+
+	pages := []fyne.CanvasObject{dropPage, targetPage, outPage, presetPage}
+	titles := []string{"Add Images", "Target Size", "Output Folder", "Presets"}
+	header := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	body := container.NewStack()
+	var d dialog.Dialog
+	cur := 0
+	back := widget.NewButton("Back", nil)
+	next := widget.NewButton("Next", nil)
+	next.Importance = widget.HighImportance
+	show := func() {
+		header.SetText(fmt.Sprintf("Getting Started — %s (%d of %d)", titles[cur], cur+1, len(pages)))
+		body.Objects = []fyne.CanvasObject{pages[cur]}
+		body.Refresh()
+		if cur == 0 {
+			back.Disable()
+		} else {
+			back.Enable()
+		}
+		if cur == len(pages)-1 {
+			next.SetText("Finish")
+		} else {
+			next.SetText("Next")
+		}
+	}
+	back.OnTapped = func() {
+		cur--
+		show()
+	}
+	next.OnTapped = func() {
+		if cur < len(pages)-1 {
+			cur++
+			show()
+			return
+		}
+		d.Hide()
+		choice := onboardingChoice{usePreset: presetCheck.Checked}
+		if createCheck.Checked {
+			choice.outDir = outDir
+		}
+		done(choice)
+	}
+	skip := widget.NewButton("Skip", func() { d.Hide() })
+	d = dialog.NewCustomWithoutButtons("Welcome", container.NewBorder(header, container.NewHBox(skip, back, next), nil, nil, body), w)
+	d.Resize(fyne.NewSize(560, 420))
+	show()
+	d.Show()
+
+	total := 0
+	return func(n int) {
+		total += n
+		dropStatus.SetText(fmt.Sprintf("%d dropped and queued — that is all it takes. Press Next.", total))
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//
+// Output folder templates
+// - The output entry takes a path that need not exist yet; it is created on
+//   demand
+// - Placeholders: {input_folder} (the folder of each input file), {date}
+//   (2006-01-02) and {time} (150405) of the run's start; a leading ~ is the
+//   home folder
+// - e.g. {input_folder}/compressed or ~/Pictures/Exports/{date}
+//
+
+var outputPlaceholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// expandOutputDir resolves tmpl for one input file of a run started at start.
+func expandOutputDir(tmpl, inPath string, start time.Time) (string, error) {
+	tmpl = strings.TrimSpace(tmpl)
+	if tmpl == "~" || strings.HasPrefix(tmpl, "~/") || strings.HasPrefix(tmpl, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("output folder: %v", err)
+		}
+		tmpl = home + tmpl[1:]
+	}
+	var bad string
+	dir := outputPlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		switch m {
+		case "{input_folder}":
+			return filepath.Dir(inPath)
+		case "{date}":
+			return start.Format("2006-01-02")
+		case "{time}":
+			return start.Format("150405")
+		}
+		bad = m
+		return m
+	})
+	if bad != "" {
+		return "", fmt.Errorf("output folder: unknown placeholder %s (use {input_folder}, {date} or {time})", bad)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// isOutputTemplate reports whether the output entry uses placeholders.
+func isOutputTemplate(s string) bool {
+	return outputPlaceholder.MatchString(s)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+//
+// Per-folder settings overrides
+// - A ".imgcompress.yaml" in any folder applies to every image beneath it
+// - Files in nested folders inherit outer files, inner keys win
+// - Only keys present in the file override the window's settings
+//
+// Example:
+//
+//	target_kb: 150
+//	shrink_to_fit: true # downscale if target_kb is out of reach
+//	min_width: 800     # automated downscaling never goes below this
+//	min_height: 600
+//	max_width: 1600
+//	format: png        # jpeg | png | heic (macOS)
+//	mode: auto         # photo | document | document-gray | document-bw | auto
+//	placeholders: true
+//
+
+const overridesFileName = ".imgcompress.yaml"
+
+type folderOverrides struct {
+	TargetKB     *int    `yaml:"target_kb"`
+	ShrinkToFit  *bool   `yaml:"shrink_to_fit"`
+	MinWidth     *int    `yaml:"min_width"`
+	MinHeight    *int    `yaml:"min_height"`
+	MaxWidth     *int    `yaml:"max_width"`
+	MaxHeight    *int    `yaml:"max_height"`
+	Format       *string `yaml:"format"`
+	Mode         *string `yaml:"mode"`
+	Placeholders *bool   `yaml:"placeholders"`
+	Metrics      *bool   `yaml:"metrics"`
+}
+
+func (f *folderOverrides) validate() error {
+	if f.Format != nil && *f.Format != "jpeg" && *f.Format != "png" && *f.Format != "heic" {
+		return fmt.Errorf("format must be jpeg, png or heic, got %q", *f.Format)
+	}
+	if f.Mode != nil {
+		if _, ok := overrideModes[*f.Mode]; !ok {
+			return fmt.Errorf("unknown mode %q", *f.Mode)
+		}
+	}
+	return nil
+}
+
+// overrideModes maps the YAML mode names onto docMode/autoDetect.
+var overrideModes = map[string]struct {
+	docMode    string
+	autoDetect bool
+}{
+	"photo":         {},
+	"document":      {docMode: docColor},
+	"document-gray": {docMode: docGray},
+	"document-bw":   {docMode: docBilevel},
+	"auto":          {autoDetect: true},
+}
+
+func (f *folderOverrides) apply(o compressOptions) compressOptions {
+	if f.TargetKB != nil {
+		o.targetKB = *f.TargetKB
+	}
+	if f.ShrinkToFit != nil {
+		o.shrinkToFit = *f.ShrinkToFit
+	}
+	if f.MinWidth != nil {
+		o.minW = *f.MinWidth
+	}
+	if f.MinHeight != nil {
+		o.minH = *f.MinHeight
+	}
+	if f.MaxWidth != nil {
+		o.maxW = *f.MaxWidth
+		o.fillW, o.fillH = 0, 0
+	}
+	if f.MaxHeight != nil {
+		o.maxH = *f.MaxHeight
+		o.fillW, o.fillH = 0, 0
+	}
+	if f.Format != nil {
+		o.format = *f.Format
+	}
+	if f.Mode != nil {
+		m := overrideModes[*f.Mode]
+		o.docMode, o.autoDetect = m.docMode, m.autoDetect
+	}
+	if f.Placeholders != nil {
+		o.placeholders = *f.Placeholders
+	}
+	if f.Metrics != nil {
+		o.metrics = *f.Metrics
+	}
+	return o
+}
+
+// overrideResolver loads and caches override files per directory for one run.
+type overrideResolver struct {
+	cache map[string]*folderOverrides // nil entry: no file in that dir
+}
+
+func newOverrideResolver() *overrideResolver {
+	return &overrideResolver{cache: map[string]*folderOverrides{}}
+}
+
+func (r *overrideResolver) load(dir string) (*folderOverrides, error) {
+	if fo, ok := r.cache[dir]; ok {
+		return fo, nil
+	}
+	path := filepath.Join(dir, overridesFileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		r.cache[dir] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	fo := &folderOverrides{}
+	if err := yaml.Unmarshal(data, fo); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := fo.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	r.cache[dir] = fo
+	return fo, nil
+}
+
+// optionsFor applies every override file from the filesystem root down to
+// the file's own folder on top of base.
+func (r *overrideResolver) optionsFor(path string, base compressOptions) (compressOptions, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return base, err
+	}
+	var dirs []string
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	opts := base
+	for i := len(dirs) - 1; i >= 0; i-- {
+		fo, err := r.load(dirs[i])
+		if err != nil {
+			return base, err
+		}
+		if fo != nil {
+			opts = fo.apply(opts)
+		}
+	}
+	return opts, nil
+}
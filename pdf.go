@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+//
+// Minimal PDF writer
+// - One JPEG (DCTDecode) image per page, scaled to fill the page
+// - Enough for contact sheets and rebuilt scans; no fonts, no compression
+//
+
+type pdfPage struct {
+	jpeg          []byte
+	width, height int     // image pixels
+	dpi           float64 // used to derive the page size in points
+	gray          bool    // jpeg is single-channel
+}
+
+func writeImagePDF(w io.Writer, pages []pdfPage) error {
+	buf := &bytes.Buffer{}
+	var offsets []int
+	obj := func(body func()) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(buf, "%d 0 obj\n", len(offsets))
+		body()
+		buf.WriteString("\nendobj\n")
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1: catalog, 2: page tree, then 3 objects per page (page, contents, image)
+	obj(func() { buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>") })
+	obj(func() {
+		buf.WriteString("<< /Type /Pages /Kids [")
+		for i := range pages {
+			fmt.Fprintf(buf, "%d 0 R ", 3+i*3)
+		}
+		fmt.Fprintf(buf, "] /Count %d >>", len(pages))
+	})
+
+	for i, p := range pages {
+		dpi := p.dpi
+		if dpi <= 0 {
+			dpi = 72
+		}
+		wPt := float64(p.width) * 72 / dpi
+		hPt := float64(p.height) * 72 / dpi
+		pageObj := 3 + i*3
+
+		obj(func() {
+			fmt.Fprintf(buf, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+				"/Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+				wPt, hPt, pageObj+2, pageObj+1)
+		})
+		content := fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", wPt, hPt)
+		obj(func() {
+			fmt.Fprintf(buf, "<< /Length %d >>\nstream\n%s\nendstream", len(content), content)
+		})
+		space := "/DeviceRGB"
+		if p.gray {
+			space = "/DeviceGray"
+		}
+		obj(func() {
+			fmt.Fprintf(buf, "<< /Type /XObject /Subtype /Image /Width %d /Height %d "+
+				"/ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n",
+				p.width, p.height, space, len(p.jpeg))
+			buf.Write(p.jpeg)
+			buf.WriteString("\nendstream")
+		})
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
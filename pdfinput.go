@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// PDF input
+// - Pages are rasterized with pdftoppm (poppler) when it is in PATH
+// - Without it, embedded JPEG images are extracted directly, one per page,
+//   which covers the usual scanned PDF
+// - Every page is re-encoded and the PDF rebuilt with the minimal writer;
+//   a target size applies to the whole document
+//
+
+const (
+	pdfRasterDPI  = 150 // pdftoppm resolution
+	pdfDefaultDPI = 300 // assumed for extracted images when the page size is unknown
+	pdfMinImage   = 64  // smaller embedded images are logos and icons, not pages
+)
+
+var (
+	pdfImageDict = regexp.MustCompile(`(?s)<<((?:[^<>]|<<[^<>]*>>)*)>>\s*stream\r?\n`)
+	pdfDCTFilter = regexp.MustCompile(`/Filter\s*(?:\[\s*)?/DCTDecode\s*\]?\s*[/>]`)
+	pdfMediaBox  = regexp.MustCompile(`/MediaBox\s*\[\s*(-?[\d.]+)\s+(-?[\d.]+)\s+(-?[\d.]+)\s+(-?[\d.]+)\s*\]`)
+)
+
+// rasterizePDF renders every page with pdftoppm.
+func rasterizePDF(ctx context.Context, pdftoppm, inPath string) ([]image.Image, error) {
+	dir, err := scratch.dir("pdf")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	out, err := exec.CommandContext(ctx, pdftoppm, "-r", strconv.Itoa(pdfRasterDPI), "-png", inPath, filepath.Join(dir, "page")).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("pdftoppm failed: %v %s", err, bytes.TrimSpace(out))
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files) // page numbers are zero-padded
+	var pages []image.Image
+	for _, f := range files {
+		img, err := imaging.Open(f)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, img)
+	}
+	return pages, nil
+}
+
+// extractPDFImages returns the embedded JPEG images in file order.
+func extractPDFImages(data []byte) []image.Image {
+	var pages []image.Image
+	for _, m := range pdfImageDict.FindAllSubmatchIndex(data, -1) {
+		dict := data[m[2]:m[3]]
+		if !bytes.Contains(dict, []byte("/Image")) || !pdfDCTFilter.Match(dict) {
+			continue
+		}
+		end := bytes.Index(data[m[1]:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		img, err := jpeg.Decode(bytes.NewReader(data[m[1] : m[1]+end]))
+		if err != nil {
+			continue
+		}
+		if b := img.Bounds(); b.Dx() < pdfMinImage || b.Dy() < pdfMinImage {
+			continue
+		}
+		pages = append(pages, img)
+	}
+	return pages
+}
+
+// pdfPageWidths returns each page's MediaBox width in points, when the page
+// objects are stored uncompressed.
+func pdfPageWidths(data []byte) []float64 {
+	var widths []float64
+	for _, m := range pdfMediaBox.FindAllSubmatch(data, -1) {
+		x0, _ := strconv.ParseFloat(string(m[1]), 64)
+		x1, _ := strconv.ParseFloat(string(m[3]), 64)
+		widths = append(widths, x1-x0)
+	}
+	return widths
+}
+
+// compressPDF rebuilds inPath with every page re-encoded as JPEG.
+func compressPDF(ctx context.Context, inPath, outPath string, opts compressOptions) (fileResult, error) {
+	fail := fileResult{inPath: inPath, outPath: outPath}
+	data, err := os.ReadFile(inPath)
+	if err != nil {
+		return fail, fmt.Errorf("load failed: %v", err)
+	}
+
+	var pages []image.Image
+	var dpis []float64
+	source := "rasterized"
+	if pdftoppm, lerr := exec.LookPath("pdftoppm"); lerr == nil {
+		if pages, err = rasterizePDF(ctx, pdftoppm, inPath); err != nil {
+			return fail, err
+		}
+		for range pages {
+			dpis = append(dpis, pdfRasterDPI)
+		}
+	} else {
+		source = "extracted"
+		pages = extractPDFImages(data)
+		widths := pdfPageWidths(data)
+		for i, p := range pages {
+			dpi := float64(pdfDefaultDPI)
+			if len(widths) == len(pages) && widths[i] > 0 {
+				dpi = float64(p.Bounds().Dx()) * 72 / widths[i]
+			}
+			dpis = append(dpis, dpi)
+		}
+	}
+	if len(pages) == 0 {
+		return fail, fmt.Errorf("no page images found; install pdftoppm (poppler) to rasterize this PDF")
+	}
+
+	floor := targetQualityFloor
+	if opts.docMode != "" {
+		floor = docQualityFloor
+	}
+	budget := 0
+	if opts.targetKB > 0 {
+		budget = opts.targetKB * 1024 / len(pages)
+	}
+	var out []pdfPage
+	minQ := 100
+	for i, img := range pages {
+		if err := ctx.Err(); err != nil {
+			return fail, err
+		}
+		srcW := img.Bounds().Dx()
+		if opts.docMode != "" {
+			img = deskew(img)
+		}
+		if opts.maxW > 0 || opts.maxH > 0 {
+			mw, mh := opts.maxW, opts.maxH
+			if mw <= 0 {
+				mw = srcW
+			}
+			if mh <= 0 {
+				mh = img.Bounds().Dy()
+			}
+			img = imaging.Fit(img, mw, mh, imaging.Lanczos)
+		}
+		gray := opts.docMode == docGray || opts.docMode == docBilevel
+		if gray {
+			img = toGray(img)
+		}
+
+		var jpg []byte
+		q := opts.quality.clamped().jpeg
+		if budget > 0 {
+			jpg, q, err = findQualityForTarget(ctx, img, budget, floor)
+		} else {
+			jpg, err = encodeJPEGBytes(img, q)
+		}
+		if err != nil {
+			return fail, fmt.Errorf("page %d: compress failed: %v", i+1, err)
+		}
+		if q < minQ {
+			minQ = q
+		}
+		b := img.Bounds()
+		// keep the physical page size when the image was downscaled
+		dpi := dpis[i] * float64(b.Dx()) / float64(srcW)
+		out = append(out, pdfPage{jpeg: jpg, width: b.Dx(), height: b.Dy(), dpi: dpi, gray: gray})
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail, err
+	}
+	outPath = withExt(outPath, ".pdf")
+	fail.outPath = outPath
+	err = atomicWrite(ctx, outPath, func(tmp string) error {
+		f, err := disk.Create(tmp)
+		if err != nil {
+			return err
+		}
+		if err := writeImagePDF(f, out); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+	if err != nil {
+		return fail, fmt.Errorf("write failed: %v", err)
+	}
+
+	res := fileResult{inPath: inPath, outPath: outPath, quality: minQ, inSize: int64(len(data))}
+	info, err := disk.Stat(outPath)
+	if err != nil {
+		return fail, fmt.Errorf("stat failed: %v", err)
+	}
+	res.outSize = info.Size()
+	res.lowQuality = budget > 0 && minQ <= floor
+	res.msg = fmt.Sprintf("OK %s -> %s (%d pages %s, q>=%d, %dKB, %.0f%% smaller)",
+		inPath, outPath, len(out), source, minQ, res.outSize/1024, savedPercent(res))
+	if res.lowQuality {
+		res.msg += " WARNING: pages at the lowest quality, check them"
+	}
+	return res, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//
+// Output permissions
+// - By default outputs get the usual 0644 files and 0755 folders, narrowed
+//   by the process umask like any other program's
+// - For shared team folders and web roots, "Permissions..." sets the file
+//   and folder modes exactly (umask or not) and, on macOS and Linux, a
+//   group to hand outputs to, e.g. 0664/2775 and "www-data"
+// - Applied to each output once it is written, and to the folders below
+//   the output folder that lead to it; the output folder itself and copies
+//   in the second folder are left alone
+//
+
+const (
+	permsFileKey  = "perms.file"
+	permsDirKey   = "perms.dir"
+	permsGroupKey = "perms.group"
+)
+
+type outputPerms struct {
+	fileMode os.FileMode // 0 keeps the mode the file was created with
+	dirMode  os.FileMode // 0 keeps the mode the folder was created with
+	group    string      // group name or id; "" keeps the user's default
+}
+
+func loadOutputPerms(p preferences) outputPerms {
+	file, _ := parseMode(p.String(permsFileKey))
+	dir, _ := parseMode(p.String(permsDirKey))
+	return outputPerms{fileMode: file, dirMode: dir, group: p.String(permsGroupKey)}
+}
+
+func (o outputPerms) save(p preferences) {
+	p.SetString(permsFileKey, formatMode(o.fileMode))
+	p.SetString(permsDirKey, formatMode(o.dirMode))
+	p.SetString(permsGroupKey, o.group)
+}
+
+// parseMode reads an octal mode such as "664" or "0664", including the
+// setgid bit folders in shared trees usually carry ("2775"); "" is 0.
+func parseMode(s string) (os.FileMode, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || n > 07777 {
+		return 0, fmt.Errorf("%q is not an octal mode like 0644", s)
+	}
+	mode := os.FileMode(n & 0777)
+	if n&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if n&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if n&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+func formatMode(m os.FileMode) string {
+	if m == 0 {
+		return ""
+	}
+	n := uint32(m.Perm())
+	if m&os.ModeSetuid != 0 {
+		n |= 04000
+	}
+	if m&os.ModeSetgid != 0 {
+		n |= 02000
+	}
+	if m&os.ModeSticky != 0 {
+		n |= 01000
+	}
+	return fmt.Sprintf("%04o", n)
+}
+
+func (o outputPerms) active() bool {
+	return o.fileMode != 0 || o.dirMode != 0 || o.group != ""
+}
+
+// apply sets the configured mode and group on path, and on each folder
+// between root and it.
+func (o outputPerms) apply(path, root string) error {
+	if !o.active() || path == "" {
+		return nil
+	}
+	if o.fileMode != 0 {
+		if err := os.Chmod(path, o.fileMode); err != nil {
+			return fmt.Errorf("permissions failed: %v", err)
+		}
+	}
+	if err := setGroup(path, o.group); err != nil {
+		return fmt.Errorf("group failed: %v", err)
+	}
+	root = filepath.Clean(root)
+	for dir := filepath.Dir(path); dir != root && isWithin(dir, root); dir = filepath.Dir(dir) {
+		if o.dirMode != 0 {
+			if err := os.Chmod(dir, o.dirMode); err != nil {
+				return fmt.Errorf("permissions failed: %v", err)
+			}
+		}
+		if err := setGroup(dir, o.group); err != nil {
+			return fmt.Errorf("group failed: %v", err)
+		}
+	}
+	return nil
+}
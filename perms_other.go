@@ -0,0 +1,14 @@
+//go:build !darwin && !linux
+
+package main
+
+import "fmt"
+
+// setGroup is not available here; folder permissions come from the ACLs
+// of the output folder instead.
+func setGroup(path, group string) error {
+	if group == "" {
+		return nil
+	}
+	return fmt.Errorf("group ownership is not supported on this platform")
+}
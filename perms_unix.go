@@ -0,0 +1,27 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// setGroup hands path to group, given by name or numeric id; "" does nothing.
+func setGroup(path, group string) error {
+	if group == "" {
+		return nil
+	}
+	gid, err := strconv.Atoi(group)
+	if err != nil {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			return err
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return err
+		}
+	}
+	return os.Lchown(path, -1, gid)
+}
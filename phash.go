@@ -0,0 +1,101 @@
+package main
+
+import (
+	"image"
+	"math/bits"
+
+	"github.com/disintegration/imaging"
+)
+
+// Hamming distance (out of 64) at or below which two images count as
+// near-duplicates. Burst frames and re-saves typically land under 6.
+const dupThreshold = 8
+
+// dHash is a 64-bit difference hash: shrink to 9×8 grey and record whether
+// each pixel is brighter than its right-hand neighbour. Robust to resizing
+// and recompression, which are exactly the differences between re-saves.
+func dHash(img image.Image) uint64 {
+	small := imaging.Grayscale(imaging.Resize(img, 9, 8, imaging.Box))
+	var h uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			l := small.Pix[y*small.Stride+x*4]
+			r := small.Pix[y*small.Stride+(x+1)*4]
+			h <<= 1
+			if l > r {
+				h |= 1
+			}
+		}
+	}
+	return h
+}
+
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// hashImages returns the dHash of each path; ok[i] is false for files that
+// could not be decoded (they are never reported as duplicates).
+func hashImages(paths []string) (hashes []uint64, ok []bool) {
+	hashes = make([]uint64, len(paths))
+	ok = make([]bool, len(paths))
+	for i, p := range paths {
+		img, err := loadImageApplyEXIF(p)
+		if err != nil {
+			continue
+		}
+		hashes[i] = dHash(img)
+		ok[i] = true
+	}
+	return hashes, ok
+}
+
+// groupNearDuplicates clusters indexes whose hashes are within threshold of
+// any other member (single linkage). Only groups of two or more are returned,
+// each in input order.
+func groupNearDuplicates(hashes []uint64, ok []bool, threshold int) [][]int {
+	parent := make([]int, len(hashes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range hashes {
+		if !ok[i] {
+			continue
+		}
+		for j := i + 1; j < len(hashes); j++ {
+			if ok[j] && hammingDistance(hashes[i], hashes[j]) <= threshold {
+				if ri, rj := find(i), find(j); ri != rj {
+					parent[rj] = ri
+				}
+			}
+		}
+	}
+
+	members := map[int][]int{}
+	var roots []int
+	for i := range hashes {
+		if !ok[i] {
+			continue
+		}
+		r := find(i)
+		if _, seen := members[r]; !seen {
+			roots = append(roots, r)
+		}
+		members[r] = append(members[r], i)
+	}
+	var groups [][]int
+	for _, r := range roots {
+		if len(members[r]) > 1 {
+			groups = append(groups, members[r])
+		}
+	}
+	return groups
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"image"
+	"math"
+	"strings"
+)
+
+//
+// Processing pipeline
+// - Each image goes through named steps in order: decode → rotate →
+//   adjust → deskew → crop → resize → bleed → encode → deliver, the order
+//   edits ran in before steps could be moved. The middle ones can be
+//   reordered or turned off, per preset, with "Steps..."; decode comes
+//   first and encode and deliver last, always
+// - Watermarking would slot in after adjust; the app has no watermark
+//   feature yet, so there is no such step
+// - A step only does something when its settings ask for it: "resize" with
+//   no maximum size passes the image through, whether it is on or not
+// - The order matters: adjusting after resizing is faster and measures
+//   auto white balance and exposure on what is delivered, adjusting before
+//   it works on every source pixel. Document deskew measures the page's
+//   text lines, so it comes after the colour work that cleans the scan
+// - Saved in the preferences as "pipeline.<preset>", e.g.
+//   "rotate,-adjust,deskew,crop,resize,bleed" (a leading "-" turns a step
+//   off); pipelines saved before deskew was a step get it in its default
+//   place
+//
+
+const (
+	stepDecode  = "decode"
+	stepRotate  = "rotate"
+	stepCrop    = "crop"
+	stepResize  = "resize"
+	stepAdjust  = "adjust"
+	stepDeskew  = "deskew"
+	stepBleed   = "bleed"
+	stepEncode  = "encode"
+	stepDeliver = "deliver"
+)
+
+// stepInfo describes each step in the editor.
+var stepInfo = map[string]string{
+	stepDecode:  "Decode the source, upright by its EXIF orientation",
+	stepRotate:  "Rotate, flip and straighten (manual, horizon)",
+	stepCrop:    "Crop to the preset's canvas",
+	stepResize:  "Fit within the maximum width and height",
+	stepAdjust:  "White balance, exposure, colour sliders and styles",
+	stepDeskew:  "Straighten scanned pages (document mode)",
+	stepBleed:   "Add the print bleed margin",
+	stepEncode:  "Encode to the output format",
+	stepDeliver: "Write, copy and upload the output",
+}
+
+type pipelineStep struct {
+	name string
+	on   bool
+}
+
+// pipeline is the ordered list of movable steps; decode, encode and
+// deliver are implied around it.
+type pipeline []pipelineStep
+
+func defaultPipeline() pipeline {
+	return pipeline{{stepRotate, true}, {stepAdjust, true}, {stepDeskew, true}, {stepCrop, true}, {stepResize, true}, {stepBleed, true}}
+}
+
+// parsePipeline reads the saved form. Unknown steps are dropped and
+// missing ones appended in their default place, so pipelines saved by
+// other versions still load.
+func parsePipeline(s string) pipeline {
+	if strings.TrimSpace(s) == "" {
+		return defaultPipeline()
+	}
+	var p pipeline
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		name := strings.TrimPrefix(part, "-")
+		if seen[name] || !defaultPipeline().has(name) {
+			continue
+		}
+		seen[name] = true
+		p = append(p, pipelineStep{name, !strings.HasPrefix(part, "-")})
+	}
+	for i, st := range defaultPipeline() {
+		if !seen[st.name] {
+			at := min(i, len(p))
+			p = append(p[:at], append(pipeline{st}, p[at:]...)...)
+		}
+	}
+	return p
+}
+
+func (p pipeline) has(name string) bool {
+	for _, st := range p {
+		if st.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p pipeline) encode() string {
+	parts := make([]string, len(p))
+	for i, st := range p {
+		parts[i] = st.name
+		if !st.on {
+			parts[i] = "-" + st.name
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// String is the pipeline as it runs, e.g. "decode → rotate → ... → deliver".
+func (p pipeline) String() string {
+	names := []string{stepDecode}
+	for _, st := range p {
+		if st.on {
+			names = append(names, st.name)
+		}
+	}
+	return strings.Join(append(names, stepEncode, stepDeliver), " → ")
+}
+
+func loadPipeline(prefs preferences, presetName string) pipeline {
+	return parsePipeline(prefs.String("pipeline." + presetName))
+}
+
+func savePipeline(prefs preferences, presetName string, p pipeline) {
+	prefs.SetString("pipeline."+presetName, p.encode())
+}
+
+// edit runs the enabled steps between decode and encode on img.
+func (p pipeline) edit(ctx context.Context, img image.Image, opts compressOptions) (image.Image, error) {
+	if p == nil {
+		p = defaultPipeline()
+	}
+	for _, st := range p {
+		if !st.on {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		before := img.Bounds()
+		done := opts.timings.start(st.name)
+		switch st.name {
+		case stepRotate:
+			img = opts.transform.apply(img)
+			if opts.autoLevel && opts.transform.angle == 0 {
+				if a := detectHorizon(img); math.Abs(a) >= horizonMinApply {
+					img = straighten(img, a)
+				}
+			}
+		case stepCrop:
+			if opts.fillW > 0 && opts.fillH > 0 {
+				img = keepIfSameSize(img, fillImage(img, opts.fillW, opts.fillH, opts.anchor, opts.gpuResize))
+			}
+		case stepResize:
+			if opts.fillW <= 0 && (opts.maxW > 0 || opts.maxH > 0) {
+				img = keepIfSameSize(img, fitImage(img, opts.maxW, opts.maxH, opts.gpuResize))
+			}
+		case stepAdjust:
+			if opts.autoWB {
+				img = autoWhiteBalance(img)
+			}
+			if opts.autoExposure {
+				img = autoExpose(img)
+			}
+			img = opts.adjust.apply(img)
+			img = opts.style.apply(img)
+		case stepDeskew:
+			if opts.docMode != "" {
+				img = deskew(img)
+			}
+		case stepBleed:
+			img = opts.bleed.apply(img, opts.printDPI)
+		}
+		done()
+		if st.name == stepCrop || st.name == stepResize {
+			if err := checkMinSize(before, img.Bounds(), opts.minW, opts.minH); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return img, nil
+}
+
+// keepIfSameSize returns orig when a resize left the size as it was: the
+// resizers copy such images unchanged, and keeping orig lets untouched
+// sources be copied through.
+func keepIfSameSize(orig, resized image.Image) image.Image {
+	if resized.Bounds().Size() == orig.Bounds().Size() {
+		return orig
+	}
+	return resized
+}
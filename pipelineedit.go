@@ -0,0 +1,67 @@
+//go:build !headless
+
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showStepsEditor edits a preset's pipeline; onSave receives the result.
+func showStepsEditor(w fyne.Window, presetName string, p pipeline, onSave func(pipeline)) {
+	p = append(pipeline(nil), p...)
+	rows := container.NewVBox()
+	order := widget.NewLabel("")
+	fixed := func(name string) fyne.CanvasObject {
+		check := widget.NewCheck(name, nil)
+		check.SetChecked(true)
+		check.Disable()
+		return container.NewBorder(nil, nil, check, nil, widget.NewLabel(stepInfo[name]))
+	}
+	var rebuild func()
+	rebuild = func() {
+		rows.RemoveAll()
+		rows.Add(fixed(stepDecode))
+		for i := range p {
+			check := widget.NewCheck(p[i].name, func(on bool) {
+				p[i].on = on
+				order.SetText(p.String())
+			})
+			check.SetChecked(p[i].on)
+			up := widget.NewButton("↑", func() {
+				p[i-1], p[i] = p[i], p[i-1]
+				rebuild()
+			})
+			down := widget.NewButton("↓", func() {
+				p[i], p[i+1] = p[i+1], p[i]
+				rebuild()
+			})
+			if i == 0 {
+				up.Disable()
+			}
+			if i == len(p)-1 {
+				down.Disable()
+			}
+			rows.Add(container.NewBorder(nil, nil, check, container.NewHBox(up, down), widget.NewLabel(stepInfo[p[i].name])))
+		}
+		rows.Add(fixed(stepEncode))
+		rows.Add(fixed(stepDeliver))
+		order.SetText(p.String())
+	}
+	rebuild()
+	order.Wrapping = fyne.TextWrapWord
+	reset := widget.NewButton("Reset to Default", func() {
+		p = defaultPipeline()
+		rebuild()
+	})
+	content := container.NewBorder(nil, container.NewVBox(order, reset), nil, nil, rows)
+	d := dialog.NewCustomConfirm("Steps — "+presetName, "Save", "Cancel", content, func(ok bool) {
+		if ok {
+			onSave(p)
+		}
+	}, w)
+	d.Resize(fyne.NewSize(620, 460))
+	d.Show()
+}
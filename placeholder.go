@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// Lazy-loading placeholders
+// - BlurHash string (https://blurha.sh), 4x3 components
+// - 20px blurred JPEG as a data URI (no WebP encoder in this build)
+// Both go into "<output name>.lqip.json" next to the compressed file.
+//
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func base83(value, length int) string {
+	var sb strings.Builder
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		sb.WriteByte(base83Chars[digit])
+	}
+	return sb.String()
+}
+
+func srgbToLinear(v uint8) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) int {
+	c := math.Max(0, math.Min(1, v))
+	if c <= 0.0031308 {
+		return int(c*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(c, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
+
+// blurHash encodes img with cx×cy components. The image is sampled at
+// 32px, which is plenty for a hash that only keeps a dozen colours.
+func blurHash(img image.Image, cx, cy int) string {
+	small := imaging.Fit(img, 32, 32, imaging.Box)
+	w, h := small.Bounds().Dx(), small.Bounds().Dy()
+
+	factors := make([][3]float64, 0, cx*cy)
+	for j := 0; j < cy; j++ {
+		for i := 0; i < cx; i++ {
+			norm := 2.0
+			if i == 0 && j == 0 {
+				norm = 1
+			}
+			var r, g, b float64
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(h))
+					o := y*small.Stride + x*4
+					r += basis * srgbToLinear(small.Pix[o])
+					g += basis * srgbToLinear(small.Pix[o+1])
+					b += basis * srgbToLinear(small.Pix[o+2])
+				}
+			}
+			scale := norm / float64(w*h)
+			factors = append(factors, [3]float64{r * scale, g * scale, b * scale})
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(base83((cx-1)+(cy-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+	maxValue := 1.0
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, f := range ac {
+			for _, c := range f {
+				actualMax = math.Max(actualMax, math.Abs(c))
+			}
+		}
+		quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maxValue = float64(quantisedMax+1) / 166
+		sb.WriteString(base83(quantisedMax, 1))
+	} else {
+		sb.WriteString(base83(0, 1))
+	}
+
+	sb.WriteString(base83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+	for _, f := range ac {
+		q := func(v float64) int {
+			return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maxValue, 0.5)*9+9.5))))
+		}
+		sb.WriteString(base83(q(f[0])*19*19+q(f[1])*19+q(f[2]), 2))
+	}
+	return sb.String()
+}
+
+// lqipDataURI returns a 20px wide blurred JPEG as a data: URI.
+func lqipDataURI(img image.Image) (string, error) {
+	tiny := imaging.Blur(imaging.Resize(img, 20, 0, imaging.Box), 1)
+	data, err := encodeJPEGBytes(tiny, 60)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+type placeholderSidecar struct {
+	File     string `json:"file"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	BlurHash string `json:"blurhash"`
+	LQIP     string `json:"lqip"`
+}
+
+// writePlaceholderSidecar computes placeholders for the final (resized) image
+// and writes them next to outPath.
+func writePlaceholderSidecar(img image.Image, outPath string) error {
+	uri, err := lqipDataURI(img)
+	if err != nil {
+		return err
+	}
+	b := img.Bounds()
+	base := outPath[:len(outPath)-len(filepath.Ext(outPath))]
+	js, err := json.MarshalIndent(placeholderSidecar{
+		File:     filepath.Base(outPath),
+		Width:    b.Dx(),
+		Height:   b.Dy(),
+		BlurHash: blurHash(img, 4, 3),
+		LQIP:     uri,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(base+".lqip.json", js, 0644); err != nil {
+		return fmt.Errorf("sidecar failed: %v", err)
+	}
+	return nil
+}
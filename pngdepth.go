@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// PNG colour depth
+// - PNG outputs are written in the smallest representation that keeps
+//   every pixel exact: grayscale for grey images, a palette (1, 2, 4 or 8
+//   bits per pixel, transparency included) for up to 256 colours, RGB for
+//   opaque images and RGBA otherwise; without alpha or colour to store,
+//   that is often half the size or less
+// - "Automatic" encodes each valid candidate and keeps the smallest.
+//   Truecolor, Palette and Grayscale force one; a palette that would need
+//   more than 256 colours falls back to truecolor rather than quantizing
+// - 16-bit sources are checked at 16 bits: they keep 16-bit grey when
+//   grey, and only get a palette when every sample fits in 8 bits
+// - Grayscale of a colour image converts it, the only lossy choice here
+//
+
+const (
+	pngColorAuto      = "" // smallest lossless
+	pngColorTruecolor = "truecolor"
+	pngColorPalette   = "palette"
+	pngColorGray      = "gray"
+)
+
+var pngColorModes = []string{pngColorAuto, pngColorTruecolor, pngColorPalette, pngColorGray}
+
+// colorCensus reports whether img is grey and opaque, and its colours when
+// there are at most 256 of them (nil otherwise). Samples are compared at
+// 16 bits: deep reports a sample that 8 bits cannot hold, and such an
+// image never gets a palette.
+func colorCensus(img image.Image) (gray, opaque, deep bool, palette color.Palette) {
+	gray, opaque = true, true
+	seen := map[color.NRGBA]bool{}
+	add := func(c color.NRGBA) {
+		if c.A != 0xFF {
+			opaque = false
+		}
+		if c.R != c.G || c.G != c.B {
+			gray = false
+		}
+		if seen != nil && !seen[c] {
+			if len(seen) == 256 {
+				seen = nil // too many for a palette
+				return
+			}
+			seen[c] = true
+			palette = append(palette, c)
+		}
+	}
+	b := img.Bounds()
+	if src, ok := img.(*image.NRGBA); ok {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, y):src.PixOffset(b.Max.X, y)]
+			for i := 0; i < len(row); i += 4 {
+				add(color.NRGBA{row[i], row[i+1], row[i+2], row[i+3]})
+			}
+		}
+	} else {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+				if !deep && !(exact8(c.R) && exact8(c.G) && exact8(c.B) && exact8(c.A)) {
+					deep, seen = true, nil
+				}
+				if deep {
+					// 8-bit copies would merge distinct samples; test at 16
+					opaque = opaque && c.A == 0xFFFF
+					gray = gray && c.R == c.G && c.G == c.B
+					continue
+				}
+				add(color.NRGBA{uint8(c.R >> 8), uint8(c.G >> 8), uint8(c.B >> 8), uint8(c.A >> 8)})
+			}
+		}
+	}
+	if seen == nil {
+		palette = nil
+	}
+	return gray, opaque, deep, palette
+}
+
+// exact8 reports whether a 16-bit sample is an 8-bit one widened.
+func exact8(v uint16) bool { return v>>8 == v&0xFF }
+
+// toPaletted maps img onto palette, which holds all of its colours.
+func toPaletted(img image.Image, palette color.Palette) *image.Paletted {
+	b := img.Bounds()
+	p := image.NewPaletted(b, palette)
+	index := make(map[color.NRGBA]uint8, len(palette))
+	for i, c := range palette {
+		index[c.(color.NRGBA)] = uint8(i)
+	}
+	if src, ok := img.(*image.NRGBA); ok {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, y):src.PixOffset(b.Max.X, y)]
+			out := p.Pix[p.PixOffset(b.Min.X, y):]
+			for i := 0; i < len(row); i += 4 {
+				out[i/4] = index[color.NRGBA{row[i], row[i+1], row[i+2], row[i+3]}]
+			}
+		}
+		return p
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p.SetColorIndex(x, y, index[color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)])
+		}
+	}
+	return p
+}
+
+// toGray16 is toGray keeping 16-bit samples.
+func toGray16(img image.Image) *image.Gray16 {
+	b := img.Bounds()
+	g := image.NewGray16(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			g.Set(x-b.Min.X, y-b.Min.Y, color.Gray16Model.Convert(img.At(x, y)))
+		}
+	}
+	return g
+}
+
+// encodePNG encodes img in the representation mode asks for, returning
+// the bytes and a short description such as "palette, 12 colours".
+func encodePNG(img image.Image, level png.CompressionLevel, mode string) ([]byte, string, error) {
+	gray, opaque, deep, palette := colorCensus(img)
+	truecolor := "RGBA"
+	if opaque {
+		truecolor = "RGB" // the encoder drops an opaque alpha channel itself
+	}
+	grayImg := func() (image.Image, string) {
+		if deep {
+			return toGray16(img), "grayscale, 16-bit"
+		}
+		return toGray(img), "grayscale"
+	}
+	type candidate struct {
+		img  image.Image
+		desc string
+	}
+	var cands []candidate
+	paletteDesc := fmt.Sprintf("palette, %d colours", len(palette))
+	switch mode {
+	case pngColorTruecolor:
+		cands = []candidate{{img, truecolor}}
+	case pngColorGray:
+		if opaque {
+			g, desc := grayImg()
+			cands = []candidate{{g, desc}}
+		} else {
+			cands = []candidate{{imaging.Grayscale(img), "grayscale, as RGBA to keep transparency"}}
+		}
+	case pngColorPalette:
+		if deep {
+			cands = []candidate{{img, truecolor + " (16-bit colours do not fit a palette)"}}
+		} else if palette == nil {
+			cands = []candidate{{img, truecolor + " (over 256 colours for a palette)"}}
+		} else {
+			cands = []candidate{{toPaletted(img, palette), paletteDesc}}
+		}
+	default:
+		cands = []candidate{{img, truecolor}}
+		if gray && opaque {
+			g, desc := grayImg()
+			cands = append(cands, candidate{g, desc})
+		}
+		if palette != nil {
+			cands = append(cands, candidate{toPaletted(img, palette), paletteDesc})
+		}
+	}
+	enc := png.Encoder{CompressionLevel: level}
+	var best []byte
+	desc := ""
+	for _, c := range cands {
+		var buf bytes.Buffer
+		if err := enc.Encode(&buf, c.img); err != nil {
+			return nil, "", err
+		}
+		if best == nil || buf.Len() < len(best) {
+			best, desc = buf.Bytes(), c.desc
+		}
+	}
+	return best, desc, nil
+}
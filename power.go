@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+//
+// Battery and thermal awareness
+// - With "Pause on low battery or when overheating" ticked, the power state
+//   is checked before each file. On battery, uploads drop to one at a time;
+//   below the battery threshold, or while the system throttles the CPU for
+//   heat, compression also pauses until the laptop is plugged in, charged
+//   past the threshold or cooled down
+// - The pause waits off the UI thread with "Resume Now" and "Cancel Run"
+//   shown; after powerMaxPause the run carries on regardless, so an
+//   unattended batch always finishes. While paused, no other run starts
+//
+
+const (
+	defaultBatteryFloor = 20 // percent
+	batteryHysteresis   = 5  // percent above the floor before resuming
+	powerPoll           = 30 * time.Second
+	powerMaxPause       = 30 * time.Minute
+)
+
+// powerState is a reading of the machine's power and thermal state; known
+// is false where it cannot be read, and nothing is throttled then.
+type powerState struct {
+	known     bool
+	onBattery bool
+	percent   int // battery charge, -1 when unknown
+	throttled bool
+}
+
+// powerPolicy decides from readings whether a run should slow down.
+type powerPolicy struct {
+	enabled bool
+	floor   int // battery percent below which compression pauses
+}
+
+// pause reports why compression should wait, or "" to go on. resuming
+// asks for the hysteresis margin, so the charge must climb past the floor.
+func (p powerPolicy) pause(st powerState, resuming bool) string {
+	if !p.enabled || !st.known {
+		return ""
+	}
+	if st.throttled {
+		return "the system is throttling for heat"
+	}
+	floor := p.floor
+	if resuming {
+		floor += batteryHysteresis
+	}
+	if st.onBattery && st.percent >= 0 && st.percent < floor {
+		return fmt.Sprintf("on battery at %d%%", st.percent)
+	}
+	return ""
+}
+
+// uploadLimit is how many uploads may run at once: 1 on battery, 0 for
+// no extra limit.
+func (p powerPolicy) uploadLimit(st powerState) int {
+	if p.enabled && st.known && (st.onBattery || st.throttled) {
+		return 1
+	}
+	return 0
+}
+
+// waitForPower polls the power state in the background until p says to go
+// on or powerMaxPause has passed, then calls resume from that goroutine
+// with the reason. Closing stop ends the wait without a call.
+func (p powerPolicy) waitForPower(stop <-chan struct{}, resume func(how string)) {
+	go func() {
+		tick := time.NewTicker(powerPoll)
+		defer tick.Stop()
+		limit := time.After(powerMaxPause)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-limit:
+				resume(fmt.Sprintf("paused for the maximum %s", powerMaxPause))
+				return
+			case <-tick.C:
+				if p.pause(readPowerState(), true) == "" {
+					resume("power recovered")
+					return
+				}
+			}
+		}
+	}()
+}
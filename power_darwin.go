@@ -0,0 +1,47 @@
+//go:build darwin
+
+package main
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	pmsetPercent = regexp.MustCompile(`(\d+)%;`)
+	pmsetSpeed   = regexp.MustCompile(`CPU_Speed_Limit\s*=\s*(\d+)`)
+)
+
+// readPowerState asks pmset: "pmset -g batt" names the power source and the
+// charge, "pmset -g therm" reports a CPU speed limit under 100 when the Mac
+// throttles for heat.
+func readPowerState() powerState {
+	out, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return powerState{percent: -1}
+	}
+	st := parsePmsetBatt(string(out))
+	if therm, err := exec.Command("pmset", "-g", "therm").Output(); err == nil {
+		st.throttled = parsePmsetTherm(string(therm))
+	}
+	return st
+}
+
+func parsePmsetBatt(s string) powerState {
+	st := powerState{known: true, percent: -1, onBattery: strings.Contains(s, "'Battery Power'")}
+	if m := pmsetPercent.FindStringSubmatch(s); m != nil {
+		st.percent, _ = strconv.Atoi(m[1])
+	}
+	return st
+}
+
+func parsePmsetTherm(s string) bool {
+	m := pmsetSpeed.FindStringSubmatch(s)
+	if m == nil {
+		return false
+	}
+	limit, _ := strconv.Atoi(m[1])
+	return limit < 100
+}
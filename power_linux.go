@@ -0,0 +1,59 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readPowerState reads sysfs: mains adapters and batteries under
+// /sys/class/power_supply, and thermal zones whose temperature has reached
+// a passive trip point, where the kernel starts slowing the CPU down.
+func readPowerState() powerState {
+	st := powerState{percent: -1}
+	read := func(path string) string {
+		data, _ := os.ReadFile(path)
+		return strings.TrimSpace(string(data))
+	}
+	supplies, _ := filepath.Glob("/sys/class/power_supply/*")
+	onMains := false
+	for _, dir := range supplies {
+		switch read(filepath.Join(dir, "type")) {
+		case "Mains", "USB":
+			if read(filepath.Join(dir, "online")) == "1" {
+				onMains = true
+			}
+		case "Battery":
+			st.known = true
+			if n, err := strconv.Atoi(read(filepath.Join(dir, "capacity"))); err == nil {
+				st.percent = n
+			}
+			if read(filepath.Join(dir, "status")) == "Discharging" {
+				st.onBattery = true
+			}
+		}
+	}
+	st.onBattery = st.onBattery && !onMains
+
+	zones, _ := filepath.Glob("/sys/class/thermal/thermal_zone*")
+	for _, zone := range zones {
+		temp, err := strconv.Atoi(read(filepath.Join(zone, "temp")))
+		if err != nil {
+			continue
+		}
+		trips, _ := filepath.Glob(filepath.Join(zone, "trip_point_*_type"))
+		for _, t := range trips {
+			if read(t) != "passive" {
+				continue
+			}
+			limit, err := strconv.Atoi(read(strings.TrimSuffix(t, "_type") + "_temp"))
+			if err == nil && limit > 0 && temp >= limit {
+				st.known, st.throttled = true, true
+			}
+		}
+	}
+	return st
+}
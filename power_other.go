@@ -0,0 +1,8 @@
+//go:build !darwin && !linux
+
+package main
+
+// readPowerState is unknown on this platform; runs never pause for power.
+func readPowerState() powerState {
+	return powerState{percent: -1}
+}
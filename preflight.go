@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//
+// Pre-flight checks
+// - Run when Start is clicked, before any file is touched, so problems show
+//   up together with a suggested fix instead of one failed file at a time
+// - Fatal problems block the run; warnings can be overridden
+//
+
+const (
+	preflightSample = 200  // images whose dimensions are checked
+	minTargetBPP    = 0.15 // below this even q10 JPEG rarely fits
+)
+
+type preflightIssue struct {
+	problem, fix string
+	fatal        bool
+}
+
+// nearestExisting walks up from dir to the first folder that exists, where
+// a template's folders would be created.
+func nearestExisting(dir string) string {
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(nearestExisting(dir), ".imgcompress-check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// isWithin reports whether path is dir or below it.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// excludeOutputs drops images that sit inside one of the run's output
+// folders, so an output folder inside a queued input folder is never
+// compressed again on the next run.
+func excludeOutputs(images []string, outTemplate string, started time.Time) (kept []string, dropped int) {
+	var dirs []string
+	seen := map[string]bool{}
+	for _, f := range images {
+		if dir, err := expandOutputDir(outTemplate, f, started); err == nil && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, f := range images {
+		inside := false
+		for _, dir := range dirs {
+			if isWithin(f, dir) {
+				inside = true
+				break
+			}
+		}
+		if inside {
+			dropped++
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	return kept, dropped
+}
+
+// preflight checks a run of images into outTemplate.
+func preflight(images []string, outTemplate string, opts compressOptions) []preflightIssue {
+	var issues []preflightIssue
+	started := time.Now()
+
+	dirs := map[string]bool{}
+	for _, f := range images {
+		if dir, err := expandOutputDir(outTemplate, f, started); err == nil {
+			dirs[dir] = true
+		}
+	}
+	for dir := range dirs {
+		if err := checkWritable(dir); err != nil {
+			issues = append(issues, preflightIssue{
+				problem: fmt.Sprintf("The output folder %s cannot be written: %v", dir, err),
+				fix:     "Choose another output folder with Browse..., or fix its permissions.",
+				fatal:   true,
+			})
+			break
+		}
+	}
+
+	if opts.minW > 0 && opts.maxW > 0 && opts.minW > opts.maxW || opts.minH > 0 && opts.maxH > 0 && opts.minH > opts.maxH {
+		issues = append(issues, preflightIssue{
+			problem: fmt.Sprintf("The minimum size %dx%d is larger than the maximum size %dx%d, so every resized file would fail.", opts.minW, opts.minH, opts.maxW, opts.maxH),
+			fix:     "Lower the minimum or raise the maximum dimensions.",
+			fatal:   true,
+		})
+	}
+
+	var inTotal int64
+	tooSmall := 0
+	for i, f := range images {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		inTotal += info.Size()
+		if opts.targetKB <= 0 || opts.shrinkToFit || i >= preflightSample {
+			continue
+		}
+		w, h := 0, 0
+		if fh, err := os.Open(f); err == nil {
+			if cfg, _, err := image.DecodeConfig(fh); err == nil {
+				w, h = cfg.Width, cfg.Height
+			}
+			fh.Close()
+		}
+		if opts.fillW > 0 && opts.fillH > 0 {
+			w, h = opts.fillW, opts.fillH
+		} else if w > 0 && (opts.maxW > 0 && w > opts.maxW || opts.maxH > 0 && h > opts.maxH) {
+			scale := 1.0
+			if opts.maxW > 0 {
+				scale = min(scale, float64(opts.maxW)/float64(w))
+			}
+			if opts.maxH > 0 {
+				scale = min(scale, float64(opts.maxH)/float64(h))
+			}
+			w, h = int(float64(w)*scale), int(float64(h)*scale)
+		}
+		if w > 0 && float64(opts.targetKB)*1024*8/float64(w*h) < minTargetBPP {
+			tooSmall++
+		}
+	}
+	if tooSmall > 0 {
+		issues = append(issues, preflightIssue{
+			problem: fmt.Sprintf("%d images are too large to reach %dKB even at the lowest quality, so they would be written over target.", tooSmall, opts.targetKB),
+			fix:     "Raise the target size, lower the max width/height, or tick \"Downscale when the target size can't be reached\".",
+		})
+	}
+
+	// outputs are rarely bigger than the inputs; a target caps them further
+	need := uint64(inTotal)
+	if opts.targetKB > 0 {
+		need = min(need, uint64(len(images))*uint64(opts.targetKB)*1024)
+	}
+	// checked on the first file's volume: per-input templates rarely span disks
+	if len(images) > 0 {
+		dir, _ := expandOutputDir(outTemplate, images[0], started)
+		if free, ok := freeBytes(nearestExisting(dir)); ok && free < need {
+			issues = append(issues, preflightIssue{
+				problem: fmt.Sprintf("The output disk has %s free, but this run may write up to %s.", formatSize(int64(free)), formatSize(int64(need))),
+				fix:     "Free up space, choose an output folder on another disk, or set a target size.",
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,14 @@
+package main
+
+// preferences is the part of fyne.Preferences the settings code uses, so
+// that code also builds without Fyne (see headless.go).
+type preferences interface {
+	Bool(key string) bool
+	SetBool(key string, value bool)
+	IntWithFallback(key string, fallback int) int
+	SetInt(key string, value int)
+	String(key string) string
+	SetString(key string, value string)
+	StringList(key string) []string
+	SetStringList(key string, value []string)
+}
@@ -0,0 +1,68 @@
+package main
+
+import "github.com/disintegration/imaging"
+
+// preset is a named output recipe picked from the Preset dropdown.
+// The zero-value fields mean "use the manual options from the window".
+type preset struct {
+	name    string
+	iconSet bool // render an app icon set instead of a compressed copy
+
+	// exact output canvas, cropped from the source around anchor
+	width, height int
+	anchor        imaging.Anchor
+	format        string
+	maxKB         int // platform-recommended upper bound on file size
+}
+
+// examplePreset is the preset the first-run tour offers as a starting point.
+const examplePreset = "Web Photo (JPEG under 500KB)"
+
+var presets = []preset{
+	{name: "Custom"},
+	{name: "App Icon Set (macOS/iOS/Android)", iconSet: true},
+	{name: examplePreset, format: "jpeg", maxKB: 500},
+
+	// Social media. Sizes and limits follow each platform's upload guidelines.
+	{name: "Instagram Feed Square (1080×1080)", width: 1080, height: 1080, anchor: imaging.Center, format: "jpeg", maxKB: 8 * 1024},
+	{name: "Instagram Feed Portrait (1080×1350)", width: 1080, height: 1350, anchor: imaging.Center, format: "jpeg", maxKB: 8 * 1024},
+	{name: "Instagram Story (1080×1920)", width: 1080, height: 1920, anchor: imaging.Center, format: "jpeg", maxKB: 8 * 1024},
+	{name: "Twitter/X Card (1200×628)", width: 1200, height: 628, anchor: imaging.Center, format: "jpeg", maxKB: 5 * 1024},
+	{name: "Open Graph Image (1200×630)", width: 1200, height: 630, anchor: imaging.Center, format: "jpeg", maxKB: 300}, // WhatsApp/LinkedIn drop larger previews
+	{name: "YouTube Thumbnail (1280×720)", width: 1280, height: 720, anchor: imaging.Center, format: "jpeg", maxKB: 2 * 1024},
+}
+
+func presetNames() []string {
+	names := make([]string, len(presets))
+	for i, p := range presets {
+		names[i] = p.name
+	}
+	return names
+}
+
+// findPreset returns the preset with the given name, or the Custom preset.
+func findPreset(name string) preset {
+	for _, p := range presets {
+		if p.name == name {
+			return p
+		}
+	}
+	return presets[0]
+}
+
+// apply overlays the preset on the window's options. A manual target size
+// smaller than the preset's limit is kept.
+func (p preset) apply(o compressOptions) compressOptions {
+	if p.width > 0 && p.height > 0 {
+		o.fillW, o.fillH = p.width, p.height
+		o.anchor = p.anchor
+		o.maxW, o.maxH = 0, 0
+	}
+	if p.format != "" {
+		o.format = p.format
+	}
+	if p.maxKB > 0 && (o.targetKB <= 0 || o.targetKB > p.maxKB) {
+		o.targetKB = p.maxKB
+	}
+	return o
+}
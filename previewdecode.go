@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"os"
+
+	"github.com/disintegration/imaging"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+//
+// Fast previews
+// - Decoding a 40MP photo for the preview takes a second or more and some
+//   200MB, and used to block the window while it ran
+// - Selecting a file now shows the thumbnail embedded in its EXIF at once
+//   (upright, a little soft) with a spinner, while the full image decodes in
+//   the background; it replaces the thumbnail when ready
+// - Only the downscaled copy is kept, and the last few are cached, so
+//   stepping back and forth through the queue does not decode again
+//
+
+const (
+	previewMaxSide   = 1200 // longest side of the decoded preview, in pixels
+	previewCacheSize = 8
+)
+
+// orientImage turns img upright for an EXIF orientation value.
+func orientImage(img image.Image, orient int) image.Image {
+	switch orient {
+	case 3:
+		return imaging.Rotate180(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 8:
+		return imaging.Rotate90(img)
+	}
+	return img
+}
+
+// exifThumbnail is the upright thumbnail embedded in path's EXIF, or nil.
+func exifThumbnail(path string) (img image.Image) {
+	defer func() {
+		if recover() != nil {
+			img = nil
+		}
+	}()
+	ex, err := readEXIF(path)
+	if err != nil {
+		return nil
+	}
+	data, err := ex.JpegThumbnail()
+	if err != nil {
+		return nil
+	}
+	img, err = imaging.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	if tag, err := ex.Get(exif.Orientation); err == nil {
+		if orient, err := tag.Int(0); err == nil {
+			img = orientImage(img, orient)
+		}
+	}
+	return img
+}
+
+// decodePreview is path decoded upright and fitted to previewMaxSide; the
+// full-resolution image is dropped as soon as it is scaled.
+func decodePreview(path string) (image.Image, error) {
+	src, err := loadImageApplyEXIF(path)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.Fit(src, previewMaxSide, previewMaxSide, imaging.Box), nil
+}
+
+// previewCache keeps the most recently decoded previews, keyed by path
+// and modification time so a file replaced on disk decodes again.
+type previewCache struct {
+	order []string // least recent first
+	imgs  map[string]image.Image
+}
+
+func previewKey(path string) string {
+	if info, err := os.Stat(path); err == nil {
+		return path + "@" + info.ModTime().String()
+	}
+	return path
+}
+
+func newPreviewCache() *previewCache {
+	return &previewCache{imgs: map[string]image.Image{}}
+}
+
+func (c *previewCache) get(path string) image.Image {
+	path = previewKey(path)
+	img := c.imgs[path]
+	if img != nil {
+		c.touch(path)
+	}
+	return img
+}
+
+func (c *previewCache) put(path string, img image.Image) {
+	path = previewKey(path)
+	if _, ok := c.imgs[path]; !ok && len(c.order) >= previewCacheSize {
+		delete(c.imgs, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.imgs[path] = img
+	c.touch(path)
+}
+
+func (c *previewCache) touch(path string) {
+	for i, p := range c.order {
+		if p == path {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.order = append(c.order, path)
+}
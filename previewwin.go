@@ -0,0 +1,72 @@
+//go:build !headless
+
+package main
+
+import (
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+//
+// Detached preview
+// - A second window showing the same preview as the main one, following
+//   the list selection, so it can live on a bigger display
+// - F or the Full Screen button toggles full screen, Escape leaves it, and
+//   the arrow keys step through the queue
+//
+
+type previewWindow struct {
+	win  fyne.Window
+	box  *fyne.Container
+	name *widget.Label
+}
+
+// newPreviewWindow opens the window. onStep moves the list selection by
+// delta; onClosed runs when the user closes the window.
+func newPreviewWindow(a fyne.App, onStep func(delta int), onClosed func()) *previewWindow {
+	p := &previewWindow{
+		win:  a.NewWindow("Preview"),
+		box:  container.NewStack(widget.NewLabel("No preview selected")),
+		name: widget.NewLabel(""),
+	}
+	toggle := func() { p.win.SetFullScreen(!p.win.FullScreen()) }
+	fullBtn := widget.NewButton("Full Screen", toggle)
+	prevBtn := widget.NewButton("Previous", func() { onStep(-1) })
+	nextBtn := widget.NewButton("Next", func() { onStep(1) })
+	p.win.Canvas().SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		switch ev.Name {
+		case fyne.KeyF:
+			toggle()
+		case fyne.KeyEscape:
+			p.win.SetFullScreen(false)
+		case fyne.KeyLeft, fyne.KeyUp:
+			onStep(-1)
+		case fyne.KeyRight, fyne.KeyDown:
+			onStep(1)
+		}
+	})
+	p.win.SetOnClosed(onClosed)
+	p.win.SetContent(container.NewBorder(nil, container.NewHBox(prevBtn, nextBtn, fullBtn, p.name), nil, nil, p.box))
+	p.win.Resize(fyne.NewSize(1100, 800))
+	p.win.Show()
+	return p
+}
+
+// show displays the same picture as img, which belongs to the main window.
+func (p *previewWindow) show(img *canvas.Image, path string) {
+	c := &canvas.Image{Image: img.Image, File: img.File, FillMode: canvas.ImageFillContain}
+	p.box.Objects = []fyne.CanvasObject{c}
+	p.box.Refresh()
+	p.name.SetText(filepath.Base(path))
+}
+
+// clear empties the window when nothing is selected.
+func (p *previewWindow) clear() {
+	p.box.Objects = []fyne.CanvasObject{widget.NewLabel("No preview selected")}
+	p.box.Refresh()
+	p.name.SetText("")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+)
+
+//
+// Print resolution
+// - Print submission portals reject files without a DPI, and the encoders
+//   write none; "Print DPI" stamps one into every JPEG (JFIF density) and
+//   PNG (pHYs) output
+// - Only the metadata changes, never the pixels: the DPI sets the size the
+//   image prints at, which Results show next to each file
+// - An existing JFIF or pHYs density is overwritten; other formats are left
+//   as they are
+//
+
+const maxPrintDPI = 0xFFFF // JFIF stores the density in 16 bits
+
+// printSize is how large w×h pixels print at dpi, in cm and inches.
+func printSize(w, h, dpi int) string {
+	in := func(px int) float64 { return float64(px) / float64(dpi) }
+	return fmt.Sprintf("%.1f×%.1f cm (%.1f×%.1f in)", in(w)*2.54, in(h)*2.54, in(w), in(h))
+}
+
+// withJPEGDensity sets the JFIF density of a JPEG to dpi, adding the JFIF
+// header right after the SOI when there is none.
+func withJPEGDensity(data []byte, dpi int) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("not a JPEG")
+	}
+	if len(data) >= 20 && data[2] == 0xFF && data[3] == 0xE0 && string(data[6:11]) == "JFIF\x00" {
+		out := bytes.Clone(data)
+		out[13] = 1 // units: dots per inch
+		binary.BigEndian.PutUint16(out[14:], uint16(dpi))
+		binary.BigEndian.PutUint16(out[16:], uint16(dpi))
+		return out, nil
+	}
+	var out bytes.Buffer
+	out.Write(data[:2])
+	out.Write([]byte{0xFF, 0xE0, 0x00, 0x10})
+	out.WriteString("JFIF\x00")
+	out.Write([]byte{1, 2, 1}) // version 1.02, dots per inch
+	binary.Write(&out, binary.BigEndian, [2]uint16{uint16(dpi), uint16(dpi)})
+	out.Write([]byte{0, 0}) // no JFIF thumbnail
+	out.Write(data[2:])
+	return out.Bytes(), nil
+}
+
+// withPNGDensity sets a PNG's pHYs chunk to dpi, replacing any there.
+func withPNGDensity(data []byte, dpi int) ([]byte, error) {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if len(data) < ihdrEnd || string(data[12:16]) != "IHDR" {
+		return nil, fmt.Errorf("not a PNG")
+	}
+	ppm := uint32(math.Round(float64(dpi) / 0.0254))
+	body := make([]byte, 4+9)
+	copy(body, "pHYs")
+	binary.BigEndian.PutUint32(body[4:], ppm)
+	binary.BigEndian.PutUint32(body[8:], ppm)
+	body[12] = 1 // unit: metre
+	var out bytes.Buffer
+	out.Write(data[:ihdrEnd])
+	binary.Write(&out, binary.BigEndian, uint32(len(body)-4))
+	out.Write(body)
+	binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(body))
+	for at := ihdrEnd; at+8 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[at:]))
+		end := at + 12 + n
+		if n < 0 || end > len(data) {
+			out.Write(data[at:]) // trailing garbage is kept as it was
+			break
+		}
+		if string(data[at+4:at+8]) != "pHYs" {
+			out.Write(data[at:end])
+		}
+		at = end
+	}
+	return out.Bytes(), nil
+}
+
+// setPrintDPI stamps dpi into the JPEG or PNG at outPath.
+func setPrintDPI(outPath string, dpi int) error {
+	var with func([]byte, int) ([]byte, error)
+	switch sniffFormat(outPath) {
+	case ".jpg":
+		with = withJPEGDensity
+	case ".png":
+		with = withPNGDensity
+	default:
+		return nil
+	}
+	data, err := disk.ReadFile(outPath)
+	if err != nil {
+		return fmt.Errorf("print DPI failed: %v", err)
+	}
+	out, err := with(data, min(dpi, maxPrintDPI))
+	if err != nil {
+		return fmt.Errorf("print DPI failed: %v", err)
+	}
+	return atomicWrite(context.Background(), outPath, func(tmp string) error { return disk.WriteFile(tmp, out, 0644) })
+}
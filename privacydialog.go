@@ -0,0 +1,113 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// startTelemetry loads the usage counts and, for users who opted in, sends
+// the saved crash reports and the week's statistics in the background.
+// Others are asked once, the first time a crash report is waiting.
+func startTelemetry(w fyne.Window, prefs fyne.Preferences) {
+	usage.load(prefs)
+	if reportURL == "" {
+		return
+	}
+	pending := len(savedCrashReports())
+	if pending > 0 && !prefs.Bool(crashReportsKey) && !prefs.Bool(crashAskedKey) {
+		askToSendCrashReports(w, prefs, pending)
+	}
+	go func() {
+		if prefs.Bool(crashReportsKey) {
+			sendCrashReports() // kept for the next launch when offline
+		}
+		usage.send(prefs)
+	}()
+}
+
+func askToSendCrashReports(w fyne.Window, prefs fyne.Preferences, pending int) {
+	msg := widget.NewLabel(fmt.Sprintf("Image Compressor kept %d crash reports. Sending them helps fix the crash; "+
+		"each holds the app version, platform, input format, the error and stack trace, never file names, paths or images.", pending))
+	msg.Wrapping = fyne.TextWrapWord
+	var d dialog.Dialog
+	send := func(always bool) {
+		prefs.SetBool(crashAskedKey, true)
+		prefs.SetBool(crashReportsKey, always)
+		d.Hide()
+		go func() {
+			if _, err := sendCrashReports(); err != nil {
+				fyne.Do(func() { dialog.ShowError(err, w) })
+			}
+		}()
+	}
+	notNow := widget.NewButton("Not Now", func() {
+		prefs.SetBool(crashAskedKey, true)
+		d.Hide()
+	})
+	once := widget.NewButton("Send", func() { send(false) })
+	always := widget.NewButton("Always Send", func() { send(true) })
+	always.Importance = widget.HighImportance
+	d = dialog.NewCustomWithoutButtons("Send Crash Reports?", container.NewBorder(nil, container.NewHBox(notNow, once, always), nil, nil, msg), w)
+	d.Resize(fyne.NewSize(520, 200))
+	d.Show()
+}
+
+// showPrivacy edits the two sending options, shows what would be sent, and
+// whether Shortcuts may start runs without asking.
+func showPrivacy(w fyne.Window, prefs fyne.Preferences) {
+	crashCheck := widget.NewCheck("Send crash reports", func(on bool) { prefs.SetBool(crashReportsKey, on) })
+	crashCheck.SetChecked(prefs.Bool(crashReportsKey))
+	usageCheck := widget.NewCheck("Send anonymous usage statistics", func(on bool) {
+		prefs.SetBool(usageStatsKey, on)
+		usage.enable(on)
+		usage.save(prefs)
+	})
+	usageCheck.SetChecked(prefs.Bool(usageStatsKey))
+	about := widget.NewLabel("Crash reports hold the app version, platform, input format, the error and stack trace, with paths removed. " +
+		"Usage statistics are counts of files read, written, failed and crashed by format. " +
+		"Neither includes file names, paths or images.")
+	about.Wrapping = fyne.TextWrapWord
+	if reportURL == "" {
+		crashCheck.Disable()
+		usageCheck.Disable()
+		about.SetText("This build has no report server: crash reports stay on this computer and no statistics are kept.")
+	}
+
+	saved := widget.NewLabel("")
+	var deleteBtn *widget.Button
+	refresh := func() {
+		n := len(savedCrashReports())
+		saved.SetText(fmt.Sprintf("%d crash reports saved in %s", n, crashDir))
+		if n == 0 {
+			deleteBtn.Disable()
+		}
+	}
+	deleteBtn = widget.NewButton("Delete Saved Reports", func() {
+		for _, p := range savedCrashReports() {
+			os.Remove(p)
+		}
+		refresh()
+	})
+	refresh()
+	showSent := widget.NewButton("Show Usage Data", func() {
+		data := widget.NewLabel(string(usage.payload()))
+		data.TextStyle = fyne.TextStyle{Monospace: true}
+		scroll := container.NewScroll(data)
+		scroll.SetMinSize(fyne.NewSize(420, 300))
+		dialog.ShowCustom("Usage Data", "Close", scroll, w)
+	})
+	trustCheck := widget.NewCheck("Let Shortcuts start runs without asking", func(on bool) { prefs.SetBool(shortcutTrustKey, on) })
+	trustCheck.SetChecked(prefs.Bool(shortcutTrustKey))
+	content := container.NewVBox(crashCheck, usageCheck, about, saved, container.NewHBox(deleteBtn, showSent),
+		widget.NewSeparator(), trustCheck)
+	d := dialog.NewCustom("Privacy", "Close", content, w)
+	d.Resize(fyne.NewSize(560, 340))
+	d.Show()
+}
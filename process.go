@@ -0,0 +1,643 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+//
+// Compression core
+// - Everything one file goes through, from decode to the written output,
+//   and the batch's options and results; the window and the headless
+//   command both drive it
+//
+
+// Prevent overwrite: if "name.jpg" exists → use "name (1).jpg", etc.
+func uniqueOutputPath(path string) string {
+	if _, err := disk.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+
+	dir := filepath.Dir(path)
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	name := base[:len(base)-len(ext)]
+
+	counter := 1
+	for {
+		newName := fmt.Sprintf("%s (%d)%s", name, counter, ext)
+		newPath := filepath.Join(dir, newName)
+		if _, err := disk.Stat(newPath); os.IsNotExist(err) {
+			return newPath
+		}
+		counter++
+	}
+}
+
+// withExt swaps the extension of a planned output path, keeping it unique.
+func withExt(path, ext string) string {
+	if strings.EqualFold(filepath.Ext(path), ext) {
+		return path
+	}
+	return uniqueOutputPath(path[:len(path)-len(filepath.Ext(path))] + ext)
+}
+
+// Load image and correct EXIF rotation
+func loadImageApplyEXIF(path string) (img image.Image, err error) {
+	defer recoverAsError(&err, path)
+	f, err := disk.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	img, err = imaging.Decode(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read EXIF again for orientation
+	ex, err := readEXIF(path)
+	if err != nil {
+		return img, nil // no EXIF → fine
+	}
+
+	orientTag, err := ex.Get(exif.Orientation)
+	if err != nil {
+		return img, nil
+	}
+	orient, err := orientTag.Int(0)
+	if err != nil {
+		return img, nil
+	}
+
+	switch orient {
+	case 3:
+		img = imaging.Rotate180(img)
+	case 6:
+		img = imaging.Rotate270(img)
+	case 8:
+		img = imaging.Rotate90(img)
+	}
+
+	return img, nil
+}
+
+// Encode to JPEG with a given quality
+func encodeJPEGBytes(img image.Image, q int) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := jpeg.Encode(buf, img, &jpeg.Options{Quality: q})
+	return buf.Bytes(), err
+}
+
+// Encode to the given output format; quality only applies to JPEG and HEIC
+func encodeForFormat(img image.Image, format string, q int) ([]byte, error) {
+	switch format {
+	case "png":
+		return encodePNGBytes(img)
+	case "heic":
+		return encodeHEIC(img, q)
+	}
+	return encodeJPEGBytes(img, q)
+}
+
+// targetQualityFloor is the lowest JPEG quality the target search goes to
+// for photos; outputs that end up there are flagged as low quality.
+const targetQualityFloor = 10
+
+// countWriter discards what is written and counts it, so trial encodes
+// can be measured without keeping them.
+type countWriter struct{ n int }
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += len(p)
+	return len(p), nil
+}
+
+// jpegSize is the encoded size of img at quality q.
+func jpegSize(img image.Image, q int) (int, error) {
+	var c countWriter
+	err := jpeg.Encode(&c, img, &jpeg.Options{Quality: q})
+	return c.n, err
+}
+
+// Search quality for target size, never going below floor. The search
+// starts at estimateQuality and gallops outwards until the target is
+// bracketed, then bisects. Candidates are only measured; it returns the
+// chosen quality and its encoded size, for the caller to encode once.
+func qualityForTarget(ctx context.Context, img image.Image, targetBytes, floor int) (int, int, error) {
+	lo, hi := floor, 95
+	bestQ, bestSize, floorSize := 0, 0, -1
+
+	// try encodes at q and narrows [lo, hi]; it reports whether q fits
+	try := func(q int) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		n, err := jpegSize(img, q)
+		if err != nil {
+			return false, err
+		}
+		if q == floor {
+			floorSize = n
+		}
+		if n <= targetBytes {
+			bestQ, bestSize = q, n
+			lo = q + 1
+			return true, nil
+		}
+		hi = q - 1
+		return false, nil
+	}
+
+	q := max(floor, min(95, estimateQuality(img, targetBytes)))
+	fits, err := try(q)
+	if err != nil {
+		return 0, 0, err
+	}
+	for step := 2; lo <= hi; step *= 2 {
+		next := max(q-step, lo)
+		if fits {
+			next = min(q+step, hi)
+		}
+		q = next
+		ok, err := try(q)
+		if err != nil {
+			return 0, 0, err
+		}
+		if ok != fits {
+			break
+		}
+	}
+	for lo <= hi {
+		if _, err := try((lo + hi) / 2); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if bestQ == 0 {
+		if floorSize < 0 {
+			var err error
+			if floorSize, err = jpegSize(img, floor); err != nil {
+				return 0, 0, err
+			}
+		}
+		return floor, floorSize, nil
+	}
+	return bestQ, bestSize, nil
+}
+
+// findQualityForTarget is qualityForTarget with the chosen encode, for
+// callers that need the bytes, such as PDF pages.
+func findQualityForTarget(ctx context.Context, img image.Image, targetBytes, floor int) ([]byte, int, error) {
+	q, _, err := qualityForTarget(ctx, img, targetBytes, floor)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := encodeJPEGBytes(img, q)
+	return data, q, err
+}
+
+// writeJPEGFile encodes img straight into path, through jpegtran when t
+// asks for tuning, so the output is never held in memory. A timeout that
+// strikes while encoding removes the late output. Errors wrap the I/O
+// error so retryIO can tell a network blip.
+func writeJPEGFile(ctx context.Context, path string, img image.Image, q int, t jpegTuning) error {
+	f, err := disk.Create(path)
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	bw := bufio.NewWriter(f)
+	if t.identity() {
+		if err = jpeg.Encode(bw, img, &jpeg.Options{Quality: q}); err != nil {
+			err = fmt.Errorf("compress failed: %w", err)
+		}
+	} else {
+		err = encodeTunedJPEG(bw, img, q, t)
+	}
+	if err == nil {
+		if err = bw.Flush(); err != nil {
+			err = fmt.Errorf("write failed: %w", err)
+		}
+	}
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("write failed: %w", cerr)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		disk.Remove(path)
+	}
+	return err
+}
+
+var imageExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".webp": true,
+	".bmp": true, ".tiff": true, ".tif": true, ".gif": true, ".pdf": true,
+	".heic": true, ".heif": true, ".avif": true, // listed so they fail with a clear error
+}
+
+func listImages(root string) ([]string, error) {
+	return listImagesDepth(root, -1)
+}
+
+// listImagesDepth lists images at most depth folders below root; 0 is root
+// only, a negative depth is unlimited.
+func listImagesDepth(root string, depth int) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel, rerr := filepath.Rel(root, path); depth >= 0 && rerr == nil && rel != "." &&
+				strings.Count(rel, string(filepath.Separator)) >= depth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if imageExts[filepath.Ext(path)] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// compressOptions carries the settings for one run, from the window or a preset.
+type compressOptions struct {
+	targetKB     int
+	shrinkToFit  bool // downscale when targetKB is out of reach at minimum quality
+	minW, minH   int  // automated downscaling never goes below this
+	maxW, maxH   int
+	fillW, fillH int             // exact canvas size: scale and crop to fill
+	anchor       imaging.Anchor  // crop gravity for fill
+	format       string          // "jpeg" (default), "png" or "heic"
+	placeholders bool            // write a BlurHash/LQIP sidecar per output
+	docMode      string          // "" for photos, else docColor/docGray/docBilevel
+	autoDetect   bool            // route screenshot-like images to PNG
+	metrics      bool            // compute SSIM/PSNR of each output
+	transform    itemTransform   // manual rotate/flip for this file
+	autoLevel    bool            // detect and level the horizon when not set manually
+	autoWB       bool            // grey-world white balance
+	autoExposure bool            // stretch levels and centre mid-tones
+	adjust       colorAdjust     // manual batch colour adjustments
+	style        styleFilter     // B&W/sepia, vignette, grain
+	animation    string          // "" keeps the first frame; animationMP4/WebM converts GIFs
+	jpeg         jpegTuning      // jpegtran post-processing of JPEG output
+	quality      qualityDefaults // per-format quality when there is no target
+	subfolder    string          // output subfolder chosen by a rule, e.g. "photos"
+	naming       nameOptions     // Unicode normalization and length limit of output names
+	gpuResize    bool            // scale large images on the GPU where available
+	passThrough  bool            // copy JPEGs that re-encoding would only degrade
+	searchable   bool            // write the capture date and keywords back into outputs
+	infoSidecar  bool            // write a .info.json with processing details per output
+	softwareTag  bool            // record the app and settings in each output's XMP
+	pngColor     string          // pngColorAuto, or a forced PNG representation
+	exifThumb    bool            // embed an EXIF thumbnail in JPEG outputs
+	printDPI     int             // print resolution stamped into outputs; 0 leaves none
+	bleed        bleedOptions    // print bleed margin added around each image
+	pipeline     pipeline        // order of the editing steps; nil is defaultPipeline
+	timings      *stepTimer      // the run's time by step; nil records nothing
+}
+
+// outputExts maps output formats to the extension their files get; a new
+// format needs its entry here so names are planned right from the start.
+var outputExts = map[string]string{
+	"jpeg":        ".jpg",
+	"png":         ".png",
+	"heic":        ".heic",
+	animationMP4:  ".mp4",
+	animationWebM: ".webm",
+}
+
+// outputExt returns the file extension for the encoded format.
+func outputExt(format string) string {
+	if ext, ok := outputExts[format]; ok {
+		return ext
+	}
+	return ".jpg"
+}
+
+// expandItems turns queued files and folders into a flat list of image paths.
+func expandItems(items []string) []string {
+	var images []string
+	for _, p := range items {
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			imgs, err := listImages(p)
+			if err == nil {
+				images = append(images, imgs...)
+			}
+		} else {
+			images = append(images, p)
+		}
+	}
+	return images
+}
+
+func formatPSNR(db float64) string {
+	if math.IsInf(db, 1) {
+		return "lossless"
+	}
+	return fmt.Sprintf("%.1fdB", db)
+}
+
+func writePlaceholders(img image.Image, outPath string, opts compressOptions) error {
+	if !opts.placeholders {
+		return nil
+	}
+	return writePlaceholderSidecar(img, outPath)
+}
+
+// fileResult is the outcome of one input file, shown in the log and results table.
+type fileResult struct {
+	inPath, outPath string
+	inSize, outSize int64
+	quality         int           // JPEG quality used; 0 for lossless output
+	skipped         bool          // output from a previous run is still up to date, or skipRule
+	skipRule        bool          // a rule said skip
+	lowQuality      bool          // the target was only met at the quality floor
+	linkedTo        string        // identical earlier output this one links to
+	suggestQ        int           // quality floor advised for a blocky source; 0 when none
+	stuckFor        time.Duration // time taken when the watchdog flagged the file; 0 otherwise
+	ssim, psnr      float64       // 0 when metrics were not requested
+	msg             string
+	err             error
+}
+
+// finishOutput stats the written file, writes sidecars, optionally scores it
+// against the encoder input, and builds the one-line summary.
+func finishOutput(inPath, outPath string, img image.Image, q int, desc string, opts compressOptions) (fileResult, error) {
+	defer opts.timings.start(stepFinish)()
+	res := fileResult{inPath: inPath, outPath: outPath, quality: q}
+	outPath, err := settleOutputExt(outPath)
+	if err != nil {
+		return res, err
+	}
+	res.outPath = outPath
+	var meta sourceMetadata
+	if opts.searchable || opts.infoSidecar {
+		meta = readSourceMetadata(inPath)
+	}
+	if opts.searchable || opts.softwareTag {
+		tags := meta
+		if !opts.searchable {
+			tags = sourceMetadata{}
+		}
+		if opts.softwareTag {
+			b := img.Bounds()
+			tags.software, tags.history = softwareName(), processingHistory(opts, sniffFormat(outPath), q, b.Dx(), b.Dy())
+		}
+		if err := tagOutput(outPath, tags); err != nil {
+			return res, err
+		}
+	}
+	if opts.exifThumb {
+		if err := embedThumbnail(outPath); err != nil {
+			return res, err
+		}
+	}
+	if opts.printDPI > 0 {
+		if err := setPrintDPI(outPath, opts.printDPI); err != nil {
+			return res, err
+		}
+	}
+	if info, err := disk.Stat(inPath); err == nil {
+		res.inSize = info.Size()
+	}
+	info, err := disk.Stat(outPath)
+	if err != nil {
+		return res, fmt.Errorf("stat failed: %v", err)
+	}
+	res.outSize = info.Size()
+
+	if err := writePlaceholders(img, outPath, opts); err != nil {
+		return res, err
+	}
+	if opts.metrics {
+		// measures encoding loss only; resizing was intentional
+		if out, err := openImage(outPath); err == nil {
+			res.ssim = ssim(img, out)
+			res.psnr = psnr(img, out)
+		}
+	}
+
+	var parts []string
+	if desc != "" {
+		parts = append(parts, desc)
+	}
+	parts = append(parts, fmt.Sprintf("%dKB", res.outSize/1024))
+	if opts.targetKB > 0 && res.outSize > int64(opts.targetKB)*1024 {
+		parts = append(parts, fmt.Sprintf("over %dKB target", opts.targetKB))
+	}
+	if s := opts.bleed.String(); s != "" {
+		parts = append(parts, s)
+	}
+	if opts.printDPI > 0 {
+		b := img.Bounds()
+		parts = append(parts, fmt.Sprintf("%d DPI, prints at %s", opts.printDPI, printSize(b.Dx(), b.Dy(), opts.printDPI)))
+	}
+	floor := targetQualityFloor
+	if opts.docMode != "" {
+		floor = docQualityFloor
+	}
+	if res.lowQuality = opts.targetKB > 0 && q > 0 && q <= floor; res.lowQuality {
+		parts = append(parts, "WARNING: lowest quality, check it")
+	}
+	if opts.metrics && res.ssim > 0 {
+		parts = append(parts, fmt.Sprintf("SSIM %.4f, PSNR %s", res.ssim, formatPSNR(res.psnr)))
+	}
+	res.msg = fmt.Sprintf("OK %s -> %s (%s)", inPath, outPath, strings.Join(parts, ", "))
+	if opts.infoSidecar {
+		if err := writeInfoSidecar(res, img.Bounds().Dx(), img.Bounds().Dy(), meta, opts); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// processImageSync does the actual work synchronously on the main thread.
+// ctx is checked between stages; see runWithTimeout.
+func processImageSync(ctx context.Context, inPath, outPath string, opts compressOptions) (fileResult, error) {
+	fail := fileResult{inPath: inPath, outPath: outPath}
+	// route by content, not by name: exports often get extensions wrong
+	format := inputFormat(inPath)
+	if opts.animation != "" && format == ".gif" && isAnimatedGIF(inPath) {
+		if err := disk.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+			return fail, fmt.Errorf("mkdir failed: %v", err)
+		}
+		return convertAnimation(ctx, inPath, withExt(outPath, outputExt(opts.animation)), opts.animation)
+	}
+	if format == ".pdf" {
+		if err := disk.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+			return fail, fmt.Errorf("mkdir failed: %v", err)
+		}
+		return compressPDF(ctx, inPath, outPath, opts)
+	}
+
+	if err := checkDecodable(inPath); err != nil {
+		return fail, err
+	}
+	var img image.Image
+	decodeDone := opts.timings.start(stepDecode)
+	err := retryIO(ctx, func() (err error) {
+		img, err = loadImageApplyEXIF(inPath)
+		return err
+	})
+	decodeDone()
+	if err != nil {
+		return fail, fmt.Errorf("load failed: %v", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return fail, err
+	}
+	decoded := img
+	if img, err = opts.pipeline.edit(ctx, img, opts); err != nil {
+		return fail, err
+	}
+	untouched := img == decoded // no step changed the pixels
+
+	if err := ctx.Err(); err != nil {
+		return fail, err
+	}
+	if err := disk.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fail, fmt.Errorf("mkdir failed: %v", err)
+	}
+
+	if opts.docMode != "" {
+		defer opts.timings.start(stepEncode)()
+		return processDocument(ctx, inPath, outPath, img, opts)
+	}
+
+	if opts.autoDetect && opts.format != "png" && looksLikeScreenshot(img) {
+		opts.format = "png"
+		outPath = withExt(outPath, ".png")
+	}
+
+	quality := opts.quality.clamped()
+	if opts.format == "png" {
+		// lossless: target size only reported, not searched
+		encodeDone := opts.timings.start(stepEncode)
+		data, desc, err := encodePNG(img, quality.pngCompression(), opts.pngColor)
+		encodeDone()
+		if err != nil {
+			return fail, fmt.Errorf("encode failed: %v", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return fail, err // timed out: do not leave a late output behind
+		}
+		writeDone := opts.timings.start(stepWrite)
+		err = atomicWrite(ctx, outPath, func(tmp string) error { return disk.WriteFile(tmp, data, 0644) })
+		writeDone()
+		if err != nil {
+			return fail, fmt.Errorf("save failed: %v", err)
+		}
+		return finishOutput(inPath, outPath, img, 0, desc, opts)
+	}
+
+	if opts.format == "heic" {
+		if err := checkHEIC(); err != nil {
+			return fail, err
+		}
+		q := quality.heic
+		var data []byte
+		if opts.targetKB > 0 {
+			searchDone := opts.timings.start(stepSearch)
+			data, q, err = heicForTarget(ctx, img, opts.targetKB*1024)
+			searchDone()
+		} else {
+			encodeDone := opts.timings.start(stepEncode)
+			data, err = encodeHEIC(img, q)
+			encodeDone()
+		}
+		if err != nil {
+			return fail, fmt.Errorf("encode failed: %v", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return fail, err // timed out: do not leave a late output behind
+		}
+		writeDone := opts.timings.start(stepWrite)
+		err = atomicWrite(ctx, outPath, func(tmp string) error { return disk.WriteFile(tmp, data, 0644) })
+		writeDone()
+		if err != nil {
+			return fail, fmt.Errorf("save failed: %v", err)
+		}
+		return finishOutput(inPath, outPath, img, q, fmt.Sprintf("HEIC q=%d", q), opts)
+	}
+
+	if opts.passThrough && untouched && format == ".jpg" && opts.jpeg.identity() {
+		if srcQ, ok, why := alreadyCompressed(inPath, quality.jpeg, opts.targetKB); ok {
+			writeDone := opts.timings.start(stepWrite)
+			err := atomicWrite(ctx, outPath, func(tmp string) error { return copyFile(inPath, tmp) })
+			writeDone()
+			if err != nil {
+				return fail, fmt.Errorf("write failed: %v", err)
+			}
+			return finishOutput(inPath, outPath, img, srcQ, why, opts)
+		}
+	}
+
+	advise := 0 // quality floor for a source that has been through several generations
+	if format == ".jpg" {
+		advise = generationAdvice(inPath, decoded)
+	}
+
+	if opts.targetKB <= 0 && opts.jpeg.identity() {
+		// save jpeg with the default quality
+		encodeDone := opts.timings.start(stepEncode)
+		err := atomicWrite(ctx, outPath, func(tmp string) error { return writeJPEGFile(ctx, tmp, img, quality.jpeg, opts.jpeg) })
+		encodeDone()
+		if err != nil {
+			return fail, fmt.Errorf("save failed: %v", err)
+		}
+		res, err := finishOutput(inPath, outPath, img, quality.jpeg, "", opts)
+		return adviseFloor(res, advise), err
+	}
+
+	q := quality.jpeg
+	desc := ""
+	if opts.targetKB > 0 {
+		// target mode
+		targetBytes := opts.targetKB * 1024
+		searchDone := opts.timings.start(stepSearch)
+		if opts.shrinkToFit {
+			before := img.Bounds()
+			q, img, err = fitTarget(ctx, img, targetBytes, targetQualityFloor, opts.minW, opts.minH, opts.gpuResize)
+			if err == nil && img.Bounds() != before {
+				desc = fmt.Sprintf(", shrunk to %dx%d to meet target", img.Bounds().Dx(), img.Bounds().Dy())
+			}
+		} else {
+			q, _, err = qualityForTarget(ctx, img, targetBytes, targetQualityFloor)
+		}
+		searchDone()
+		if err != nil {
+			return fail, fmt.Errorf("compress failed: %v", err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return fail, err // timed out: do not leave a late output behind
+	}
+	encodeDone := opts.timings.start(stepEncode)
+	err = atomicWrite(ctx, outPath, func(tmp string) error { return writeJPEGFile(ctx, tmp, img, q, opts.jpeg) })
+	encodeDone()
+	if err != nil {
+		return fail, err
+	}
+	res, err := finishOutput(inPath, outPath, img, q, fmt.Sprintf("q=%d", q)+desc, opts)
+	return adviseFloor(res, advise), err
+}
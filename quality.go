@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+)
+
+//
+// Starting quality estimate
+// - JPEG size is roughly bits-per-pixel × pixels, and bits-per-pixel at a
+//   given quality grows with how busy the image is
+// - Busyness is the entropy of neighbouring-pixel differences on a sample
+//   of rows, so the estimate costs no trial encode
+// - The search starts at the estimate and only brackets around it
+// - When the target is out of reach at the lowest quality, resolution is
+//   stepped down too, never below the minimum width/height
+//
+
+// jpegBPPCurve is bits per pixel relative to q50, measured on a mix of
+// photos with Go's encoder.
+var jpegBPPCurve = []struct{ q, rel float64 }{
+	{10, 0.40}, {30, 0.75}, {50, 1.00}, {70, 1.33}, {85, 1.90}, {95, 3.30},
+}
+
+// detailEntropy is the Shannon entropy, in bits, of horizontal luma
+// differences at full resolution, sampled on up to 64 rows: ~5 for smooth
+// photos, ~7 for foliage or noise.
+func detailEntropy(img image.Image) float64 {
+	b := img.Bounds()
+	rows := min(64, b.Dy())
+	var hist [256]int
+	n := 0
+	for i := 0; i < rows; i++ {
+		y := b.Min.Y + i*b.Dy()/rows
+		prev := -1
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			l := int((299*r + 587*g + 114*bl) / 1000 >> 8)
+			if prev >= 0 {
+				hist[(l-prev)&0xFF]++
+				n++
+			}
+			prev = l
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	e := 0.0
+	for _, c := range hist {
+		if c > 0 {
+			p := float64(c) / float64(n)
+			e -= p * math.Log2(p)
+		}
+	}
+	return e
+}
+
+// estimateQuality guesses the JPEG quality that lands img near targetBytes.
+func estimateQuality(img image.Image, targetBytes int) int {
+	b := img.Bounds()
+	pixels := float64(b.Dx() * b.Dy())
+	if pixels == 0 || targetBytes <= 0 {
+		return 52
+	}
+	at50 := math.Exp(0.486*detailEntropy(img) - 2.45) // bpp at q50, fitted
+	rel := float64(targetBytes) * 8 / pixels / at50
+
+	curve := jpegBPPCurve
+	if rel <= curve[0].rel {
+		return int(curve[0].q)
+	}
+	for i := 1; i < len(curve); i++ {
+		if rel <= curve[i].rel {
+			lo, hi := curve[i-1], curve[i]
+			return int(math.Round(lo.q + (rel-lo.rel)/(hi.rel-lo.rel)*(hi.q-lo.q)))
+		}
+	}
+	return 95
+}
+
+// estimateJPEGBytes runs the same model the other way: the expected size
+// of img encoded at quality q after scaling to w×h.
+func estimateJPEGBytes(img image.Image, w, h, q int) int {
+	at50 := math.Exp(0.486*detailEntropy(img) - 2.45)
+	curve := jpegBPPCurve
+	rel := curve[len(curve)-1].rel
+	if fq := float64(q); fq <= curve[0].q {
+		rel = curve[0].rel
+	} else {
+		for i := 1; i < len(curve); i++ {
+			if fq <= curve[i].q {
+				lo, hi := curve[i-1], curve[i]
+				rel = lo.rel + (fq-lo.q)/(hi.q-lo.q)*(hi.rel-lo.rel)
+				break
+			}
+		}
+	}
+	return int(at50 * rel * float64(w*h) / 8)
+}
+
+// fitTarget runs the quality search and, when even the floor quality is
+// over target, shrinks the image in 10% steps and searches again until the
+// target is met or the next step would go below minW×minH. It returns the
+// quality and the image to encode.
+func fitTarget(ctx context.Context, img image.Image, targetBytes, floor, minW, minH int, gpu bool) (int, image.Image, error) {
+	q, n, err := qualityForTarget(ctx, img, targetBytes, floor)
+	if err != nil || n <= targetBytes {
+		return q, img, err
+	}
+	b := img.Bounds()
+	minScale := math.Max(float64(minW)/float64(b.Dx()), float64(minH)/float64(b.Dy()))
+	if minScale >= 1 {
+		return q, img, nil
+	}
+	for step := 9; step >= 1; step-- {
+		scale := float64(step) / 10
+		last := step == 1 || scale <= minScale
+		if scale < minScale {
+			scale = minScale
+		}
+		w := max(1, int(math.Ceil(float64(b.Dx())*scale)))
+		h := max(1, int(math.Ceil(float64(b.Dy())*scale)))
+		small := lanczosResize(img, w, h, gpu)
+		q, n, err = qualityForTarget(ctx, small, targetBytes, floor)
+		if err != nil {
+			return 0, nil, err
+		}
+		if n <= targetBytes || last {
+			return q, small, nil
+		}
+	}
+	return q, img, nil // not reached
+}
+
+// checkMinSize fails a resize that took img below the minimum size. Inputs
+// that were already smaller pass: they were not downscaled.
+func checkMinSize(before, after image.Rectangle, minW, minH int) error {
+	if after == before {
+		return nil
+	}
+	if after.Dx() < minW && before.Dx() >= minW {
+		return fmt.Errorf("resizing to %dx%d would go below the minimum width of %dpx", after.Dx(), after.Dy(), minW)
+	}
+	if after.Dy() < minH && before.Dy() >= minH {
+		return fmt.Errorf("resizing to %dx%d would go below the minimum height of %dpx", after.Dx(), after.Dy(), minH)
+	}
+	return nil
+}
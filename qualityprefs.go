@@ -0,0 +1,72 @@
+package main
+
+import "image/png"
+
+//
+// Default quality per output format
+// - Used whenever no target size is set; edited with "Quality Defaults..."
+//   and kept in the app preferences between launches
+// - WebP and AVIF values are stored for when those encoders are available
+//   (see Formats...)
+// - HEIC has its own quality, used on macOS where it can be written
+//
+
+type qualityDefaults struct {
+	jpeg, webp, avif int // 1..100
+	heic             int // 1..100
+	pngLevel         int // 0 (store) .. 9 (smallest)
+}
+
+var builtinQuality = qualityDefaults{jpeg: 82, webp: 75, avif: 50, heic: 60, pngLevel: 9}
+
+func loadQualityDefaults(p preferences) qualityDefaults {
+	return qualityDefaults{
+		jpeg:     p.IntWithFallback("quality.jpeg", builtinQuality.jpeg),
+		webp:     p.IntWithFallback("quality.webp", builtinQuality.webp),
+		avif:     p.IntWithFallback("quality.avif", builtinQuality.avif),
+		heic:     p.IntWithFallback("quality.heic", builtinQuality.heic),
+		pngLevel: p.IntWithFallback("quality.pngLevel", builtinQuality.pngLevel),
+	}.clamped()
+}
+
+func (d qualityDefaults) save(p preferences) {
+	p.SetInt("quality.jpeg", d.jpeg)
+	p.SetInt("quality.webp", d.webp)
+	p.SetInt("quality.avif", d.avif)
+	p.SetInt("quality.heic", d.heic)
+	p.SetInt("quality.pngLevel", d.pngLevel)
+}
+
+// clamped keeps values in range. The zero value, from options built without
+// the window (e.g. mail), means builtinQuality.
+func (d qualityDefaults) clamped() qualityDefaults {
+	if d == (qualityDefaults{}) {
+		return builtinQuality
+	}
+	q := func(v, def int) int {
+		if v <= 0 {
+			return def
+		}
+		return min(v, 100)
+	}
+	d.jpeg = q(d.jpeg, builtinQuality.jpeg)
+	d.webp = q(d.webp, builtinQuality.webp)
+	d.avif = q(d.avif, builtinQuality.avif)
+	d.heic = q(d.heic, builtinQuality.heic)
+	d.pngLevel = max(0, min(d.pngLevel, 9))
+	return d
+}
+
+// pngCompression maps the zlib-style 0..9 level onto Go's encoder, which
+// only has four settings.
+func (d qualityDefaults) pngCompression() png.CompressionLevel {
+	switch {
+	case d.pngLevel == 0:
+		return png.NoCompression
+	case d.pngLevel <= 3:
+		return png.BestSpeed
+	case d.pngLevel <= 6:
+		return png.DefaultCompression
+	}
+	return png.BestCompression
+}
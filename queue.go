@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+//
+// Queue persistence
+// - The queue, per-item edits, last run status and window settings are
+//   saved in the app's storage folder: periodically, during runs, after every
+//   run and when the app quits
+// - On the next launch the user is offered to restore them
+// - Saves replace the file atomically, so a crash mid-write keeps the last one
+//
+
+const queueFileName = "queue.json"
+
+type savedItem struct {
+	Path        string  `json:"path"`
+	Turns       int     `json:"turns,omitempty"`
+	FlipH       bool    `json:"flip_h,omitempty"`
+	FlipV       bool    `json:"flip_v,omitempty"`
+	Angle       float64 `json:"angle,omitempty"`
+	SkipAutoFix bool    `json:"skip_auto_fix,omitempty"`
+	Excluded    bool    `json:"excluded,omitempty"`
+	Priority    int     `json:"priority,omitempty"`
+}
+
+type savedQueue struct {
+	Items     []savedItem       `json:"items"`
+	Statuses  map[string]string `json:"statuses,omitempty"` // keyed by input file, see resultStatus
+	Settings  map[string]string `json:"settings,omitempty"` // see sessionField
+	CleanExit bool              `json:"clean_exit,omitempty"`
+}
+
+func newSavedQueue(items []string, perItem map[string]itemSettings, statuses map[string]string) savedQueue {
+	q := savedQueue{Statuses: statuses}
+	for _, p := range items {
+		st := perItem[p]
+		q.Items = append(q.Items, savedItem{
+			Path: p, Turns: st.transform.turns, FlipH: st.transform.flipH, FlipV: st.transform.flipV,
+			Angle: st.transform.angle, SkipAutoFix: st.skipAutoFix, Excluded: st.excluded, Priority: st.priority,
+		})
+	}
+	return q
+}
+
+// restore returns the queue's paths and edits, dropping files that no longer exist.
+func (q savedQueue) restore() ([]string, map[string]itemSettings, map[string]string) {
+	var items []string
+	perItem := map[string]itemSettings{}
+	for _, it := range q.Items {
+		if _, err := os.Stat(it.Path); err != nil {
+			continue
+		}
+		items = append(items, it.Path)
+		st := itemSettings{
+			transform:   itemTransform{turns: it.Turns, flipH: it.FlipH, flipV: it.FlipV, angle: it.Angle},
+			skipAutoFix: it.SkipAutoFix,
+			excluded:    it.Excluded,
+			priority:    it.Priority,
+		}
+		if st != (itemSettings{}) {
+			perItem[it.Path] = st
+		}
+	}
+	statuses := q.Statuses
+	if statuses == nil {
+		statuses = map[string]string{}
+	}
+	return items, perItem, statuses
+}
+
+func loadQueue(dir string) (savedQueue, error) {
+	var q savedQueue
+	data, err := os.ReadFile(filepath.Join(dir, queueFileName))
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return q, err
+	}
+	return q, json.Unmarshal(data, &q)
+}
+
+func saveQueue(dir string, q savedQueue) error {
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, queueFileName+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, queueFileName))
+}
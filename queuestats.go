@@ -0,0 +1,133 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+//
+// Queue statistics
+// - The header above the file list shows how many images the queue expands
+//   to and their combined size, so a 15GB job is obvious before Start
+// - After a run, the combined output size of the files done so far is added
+// - Sizes and folder listings are cached in a queueIndex, so a queue of a
+//   whole photo archive is not stat'ed again on every tick or refresh
+// - List refreshes are coalesced: a burst of changes repaints once
+//
+
+// queueRefreshDelay is how long a refresh waits for further changes.
+const queueRefreshDelay = 50 * time.Millisecond
+
+// queueIndex caches what the statistics need to know about queued paths.
+// Runs still list folders afresh; only the header uses the cache.
+type queueIndex struct {
+	sizes   map[string]int64    // file -> size in bytes, -1 if unreadable
+	folders map[string][]string // queued folder -> the images in it
+}
+
+func newQueueIndex() *queueIndex {
+	return &queueIndex{sizes: map[string]int64{}, folders: map[string][]string{}}
+}
+
+func (q *queueIndex) size(path string) int64 {
+	if n, ok := q.sizes[path]; ok {
+		return n
+	}
+	n := int64(-1)
+	if info, err := os.Stat(path); err == nil {
+		n = info.Size()
+	}
+	q.sizes[path] = n
+	return n
+}
+
+// expand is expandItems with each folder listed once.
+func (q *queueIndex) expand(items []string) []string {
+	var images []string
+	for _, p := range items {
+		imgs, ok := q.folders[p]
+		if !ok {
+			if info, err := os.Stat(p); err == nil && info.IsDir() {
+				imgs, _ = listImages(p)
+				if imgs == nil {
+					imgs = []string{}
+				}
+			}
+			q.folders[p] = imgs
+		}
+		if imgs == nil {
+			images = append(images, p)
+		} else {
+			images = append(images, imgs...)
+		}
+	}
+	return images
+}
+
+// forget drops cached facts about path, e.g. after it is queued again.
+func (q *queueIndex) forget(path string) {
+	delete(q.sizes, path)
+	delete(q.folders, path)
+}
+
+// coalescer runs fn once, queueRefreshDelay after the first of a burst of
+// requests. It is used from the UI goroutine only.
+type coalescer struct {
+	fn      func()
+	pending bool
+}
+
+func (c *coalescer) request() {
+	if c.pending {
+		return
+	}
+	c.pending = true
+	time.AfterFunc(queueRefreshDelay, func() {
+		fyne.Do(func() {
+			c.pending = false
+			c.fn()
+		})
+	})
+}
+
+// queueStats summarises the ticked part of the queue and the last run's
+// results.
+func queueStats(index *queueIndex, items []string, perItem map[string]itemSettings, results []fileResult) string {
+	if len(items) == 0 {
+		return "Queue is empty"
+	}
+	images := index.expand(activeItems(items, perItem))
+	if len(images) == 0 {
+		return "Nothing ticked for processing"
+	}
+	var in int64
+	for _, p := range images {
+		if n := index.size(p); n > 0 {
+			in += n
+		}
+	}
+	s := fmt.Sprintf("%d files, %s", len(images), formatSize(in))
+
+	queued := make(map[string]bool, len(images))
+	for _, p := range images {
+		queued[p] = true
+	}
+	var done int
+	var doneIn, out int64
+	for _, r := range results {
+		if r.err == nil && queued[r.inPath] && r.outSize > 0 {
+			done++
+			doneIn += r.inSize
+			out += r.outSize
+		}
+	}
+	if done > 0 {
+		s += fmt.Sprintf(" — %d done: %s → %s", done, formatSize(doneIn), formatSize(out))
+	}
+	return s
+}
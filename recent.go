@@ -0,0 +1,62 @@
+//go:build !headless
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/storage"
+)
+
+//
+// Recent folders
+// - Input folders (where files were added from) and output folders (where a
+//   run wrote to) are remembered in the app preferences, newest first
+// - Folder dialogs open at the most recent one, and the lists back the
+//   quick-pick buttons and dropdown in the main window
+//
+
+const (
+	recentInputsKey  = "recent.inputs"
+	recentOutputsKey = "recent.outputs"
+	recentMax        = 8
+)
+
+// recentFolders returns the remembered folders that still exist.
+func recentFolders(p fyne.Preferences, key string) []string {
+	var dirs []string
+	for _, d := range p.StringList(key) {
+		if info, err := os.Stat(d); err == nil && info.IsDir() {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// rememberFolder moves dir to the front of the list under key.
+func rememberFolder(p fyne.Preferences, key, dir string) {
+	dir = filepath.Clean(dir)
+	dirs := []string{dir}
+	for _, d := range p.StringList(key) {
+		if d != dir && len(dirs) < recentMax {
+			dirs = append(dirs, d)
+		}
+	}
+	p.SetStringList(key, dirs)
+}
+
+// recentLocation is the newest folder under key as a dialog location, or
+// nil to keep the dialog's default.
+func recentLocation(p fyne.Preferences, key string) fyne.ListableURI {
+	dirs := recentFolders(p, key)
+	if len(dirs) == 0 {
+		return nil
+	}
+	l, err := storage.ListerForURI(storage.NewFileURI(dirs[0]))
+	if err != nil {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+var reportHeader = []string{"input", "output", "input_bytes", "output_bytes", "saved_percent", "quality", "ssim", "psnr_db", "status"}
+
+func savedPercent(r fileResult) float64 {
+	if r.inSize <= 0 || r.err != nil {
+		return 0
+	}
+	return 100 * (1 - float64(r.outSize)/float64(r.inSize))
+}
+
+func resultStatus(r fileResult) string {
+	if r.err != nil {
+		return "error: " + r.err.Error()
+	}
+	if r.skipRule {
+		return "skipped (rule)"
+	}
+	if r.skipped {
+		return "skipped (up to date)"
+	}
+	if r.lowQuality {
+		return "ok (low quality)"
+	}
+	if r.suggestQ > 0 {
+		return "ok (re-saved source)"
+	}
+	if r.stuckFor > 0 {
+		return "ok (slow)"
+	}
+	if r.linkedTo != "" {
+		return "ok (linked)"
+	}
+	return "ok"
+}
+
+// reportRow is one results-table/CSV row; metric columns are blank when not computed.
+func reportRow(r fileResult) []string {
+	row := []string{r.inPath, r.outPath, "", "", "", "", "", "", resultStatus(r)}
+	if r.err != nil || r.skipped {
+		return row
+	}
+	row[2] = strconv.FormatInt(r.inSize, 10)
+	row[3] = strconv.FormatInt(r.outSize, 10)
+	row[4] = fmt.Sprintf("%.1f", savedPercent(r))
+	if r.quality > 0 {
+		row[5] = strconv.Itoa(r.quality)
+	}
+	if r.ssim > 0 {
+		row[6] = fmt.Sprintf("%.4f", r.ssim)
+		if math.IsInf(r.psnr, 1) {
+			row[7] = "inf"
+		} else {
+			row[7] = fmt.Sprintf("%.2f", r.psnr)
+		}
+	}
+	return row
+}
+
+// writeReportCSV writes one row per processed file.
+func writeReportCSV(w io.Writer, results []fileResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(reportHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := cw.Write(reportRow(r)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%dKB", n/1024)
+}
@@ -0,0 +1,185 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+var resultColumns = []struct {
+	title string
+	width float32
+}{
+	{"File", 220}, {"Input", 80}, {"Output", 80}, {"Saved", 70},
+	{"Quality", 60}, {"SSIM", 70}, {"PSNR", 80}, {"Status", 260},
+}
+
+// resultCell formats results[row] for table column col.
+func resultCell(r fileResult, col int) string {
+	csvRow := reportRow(r)
+	switch col {
+	case 0:
+		return filepath.Base(r.inPath)
+	case 1:
+		return fmt.Sprintf("%dKB", r.inSize/1024)
+	case 2:
+		if r.err != nil || r.skipped {
+			return ""
+		}
+		return fmt.Sprintf("%dKB", r.outSize/1024)
+	case 3:
+		if r.err != nil || r.skipped {
+			return ""
+		}
+		return fmt.Sprintf("%.0f%%", savedPercent(r))
+	case 4:
+		return csvRow[5]
+	case 5:
+		return csvRow[6]
+	case 6:
+		if r.ssim > 0 {
+			return formatPSNR(r.psnr)
+		}
+		return ""
+	default:
+		if r.err != nil {
+			return r.err.Error()
+		}
+		if r.skipRule {
+			return "Skipped (rule)"
+		}
+		if r.skipped {
+			return "Skipped (up to date)"
+		}
+		if r.lowQuality {
+			return fmt.Sprintf("⚠ Low quality (q=%d)", r.quality)
+		}
+		if r.suggestQ > 0 {
+			return fmt.Sprintf("⚠ Re-saved source, use q≥%d (got q=%d)", r.suggestQ, r.quality)
+		}
+		if r.stuckFor > 0 {
+			return fmt.Sprintf("⚠ Slow (took %s)", r.stuckFor)
+		}
+		if r.linkedTo != "" {
+			return "OK (identical to " + filepath.Base(r.linkedTo) + ", linked)"
+		}
+		return "OK"
+	}
+}
+
+// showResultsWindow lists the last batch with a CSV export.
+func showResultsWindow(a fyne.App, results []fileResult, timings *stepTimer) {
+	win := a.NewWindow("Results")
+	win.Resize(fyne.NewSize(960, 520))
+
+	table := widget.NewTableWithHeaders(
+		func() (int, int) { return len(results), len(resultColumns) },
+		func() fyne.CanvasObject { return widget.NewLabel("template") },
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			label.Importance = widget.MediumImportance
+			if r := results[id.Row]; r.err == nil && (r.lowQuality || r.suggestQ > 0 || r.stuckFor > 0) {
+				label.Importance = widget.WarningImportance // the whole row in yellow
+			}
+			label.SetText(resultCell(results[id.Row], id.Col))
+		},
+	)
+	table.ShowHeaderColumn = false
+	table.CreateHeader = func() fyne.CanvasObject { return widget.NewLabel("header") }
+	table.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		if id.Col >= 0 {
+			o.(*widget.Label).SetText(resultColumns[id.Col].title)
+		}
+	}
+	for i, c := range resultColumns {
+		table.SetColumnWidth(i, c.width)
+	}
+
+	var in, out int64
+	failed, skipped, low, blocky, slow := 0, 0, 0, 0, 0
+	for _, r := range results {
+		if r.stuckFor > 0 {
+			slow++
+		}
+		if r.err != nil {
+			failed++
+			continue
+		}
+		if r.skipped {
+			skipped++
+			continue
+		}
+		if r.lowQuality {
+			low++
+		}
+		if r.suggestQ > 0 {
+			blocky++
+		}
+		in += r.inSize
+		out += r.outSize
+	}
+	summary := widget.NewLabel(fmt.Sprintf("%d files, %d skipped, %d failed — %dKB → %dKB",
+		len(results), skipped, failed, in/1024, out/1024))
+	if low > 0 {
+		summary.SetText(summary.Text + fmt.Sprintf(" — ⚠ %d at the lowest quality", low))
+		summary.Importance = widget.WarningImportance
+	}
+	if blocky > 0 {
+		summary.SetText(summary.Text + fmt.Sprintf(" — ⚠ %d re-saved sources below their quality", blocky))
+		summary.Importance = widget.WarningImportance
+	}
+	if slow > 0 {
+		summary.SetText(summary.Text + fmt.Sprintf(" — ⚠ %d stuck or slow", slow))
+		summary.Importance = widget.WarningImportance
+	}
+
+	// Share sends the selected row's output, or every output when none is selected.
+	selectedRow := -1
+	table.OnSelected = func(id widget.TableCellID) { selectedRow = id.Row }
+	shareBtn := widget.NewButton("Share...", func() {
+		var paths []string
+		for i, r := range results {
+			if r.err == nil && r.outPath != "" && (selectedRow < 0 || i == selectedRow) {
+				paths = append(paths, r.outPath)
+			}
+		}
+		if len(paths) == 0 {
+			dialog.ShowInformation("Nothing to Share", "The selected file has no output.", win)
+			return
+		}
+		if err := showShareSheet(paths); err != nil {
+			dialog.ShowError(err, win)
+		}
+	})
+
+	saveBtn := widget.NewButton("Save CSV Report...", func() {
+		d := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+			if err != nil || wc == nil {
+				return
+			}
+			defer wc.Close()
+			if err := writeReportCSV(wc, results); err != nil {
+				dialog.ShowError(err, win)
+			}
+		}, win)
+		d.SetFileName("compression-report.csv")
+		d.Show()
+	})
+
+	var body fyne.CanvasObject = table
+	if t := timings.report(); t != "" {
+		timing := widget.NewLabel(t)
+		timing.TextStyle = fyne.TextStyle{Monospace: true}
+		body = container.NewAppTabs(
+			container.NewTabItem("Files", table),
+			container.NewTabItem("Time by Step", container.NewScroll(timing)))
+	}
+	win.SetContent(container.NewBorder(nil, container.NewHBox(saveBtn, shareBtn, summary), nil, nil, body))
+	win.Show()
+}
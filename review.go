@@ -0,0 +1,202 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+//
+// Review gallery
+// - "Review..." steps through the last run's outputs, each next to its
+//   original, for a human check without redoing the whole batch
+// - Accept keeps the output; "Redo at q=N" marks the file for another pass
+//   at a fixed, higher quality (10 up, or the floor a re-saved source asks
+//   for), replacing the rejected output
+// - "Run Redos" compresses the marked files with the window's other
+//   settings; files from inside archives are accepted or left as they are
+// - Left/Right step, A accepts, R marks a redo
+//
+
+const redoQualityStep = 10
+
+const (
+	reviewPending = iota
+	reviewAccepted
+	reviewRedo
+)
+
+// reviewable are the results with an output image to look at.
+func reviewable(results []fileResult) []fileResult {
+	var out []fileResult
+	for _, r := range results {
+		if r.err != nil || r.skipped || r.outPath == "" {
+			continue
+		}
+		switch sniffFormat(r.outPath) {
+		case ".jpg", ".png", ".gif", ".webp":
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// redoQuality is the quality a redo of r runs at, or 0 when r cannot be
+// redone: a lossless or top-quality output, or a source that is not a
+// file of its own.
+func redoQuality(r fileResult) int {
+	if r.quality <= 0 || r.quality >= 100 {
+		return 0
+	}
+	if info, err := os.Stat(r.inPath); err != nil || info.IsDir() || isArchive(r.inPath) {
+		return 0
+	}
+	return min(100, max(r.quality+redoQualityStep, r.suggestQ))
+}
+
+// showReviewGallery opens the gallery; onRedo receives the results marked
+// for a redo, by source path.
+func showReviewGallery(a fyne.App, results []fileResult, onRedo func(redos map[string]fileResult)) {
+	items := reviewable(results)
+	win := a.NewWindow("Review")
+	win.Resize(fyne.NewSize(1200, 760))
+	if len(items) == 0 {
+		win.SetContent(widget.NewLabel("The last run wrote no images to review."))
+		win.Show()
+		return
+	}
+
+	verdicts := make([]int, len(items))
+	cur := 0
+	before, after := container.NewStack(), container.NewStack()
+	info := widget.NewLabel("")
+	info.Wrapping = fyne.TextWrapWord
+	tally := widget.NewLabel("")
+	acceptBtn := widget.NewButton("Accept", nil)
+	redoBtn := widget.NewButton("Redo", nil)
+	runBtn := widget.NewButton("Run Redos", nil)
+
+	load := func(box *fyne.Container, path string, index int) {
+		spinner := widget.NewActivity()
+		spinner.Start()
+		box.Objects = []fyne.CanvasObject{container.NewCenter(spinner)}
+		box.Refresh()
+		go func() {
+			img, err := decodePreview(path)
+			fyne.Do(func() {
+				spinner.Stop()
+				if index != cur {
+					return
+				}
+				var o fyne.CanvasObject = widget.NewLabel("Cannot be shown: " + fmt.Sprint(err))
+				if err == nil {
+					c := canvas.NewImageFromImage(img)
+					c.FillMode = canvas.ImageFillContain
+					o = c
+				}
+				box.Objects = []fyne.CanvasObject{o}
+				box.Refresh()
+			})
+		}()
+	}
+	show := func() {
+		r := items[cur]
+		load(before, r.inPath, cur)
+		load(after, r.outPath, cur)
+		text := fmt.Sprintf("%d of %d: %s — %s → %s (%s saved)", cur+1, len(items), filepath.Base(r.inPath),
+			resultCell(r, 1), resultCell(r, 2), resultCell(r, 3))
+		if r.quality > 0 {
+			text += fmt.Sprintf(", q=%d", r.quality)
+		}
+		if r.ssim > 0 {
+			text += fmt.Sprintf(", SSIM %.4f", r.ssim)
+		}
+		switch verdicts[cur] {
+		case reviewAccepted:
+			text += " — accepted"
+		case reviewRedo:
+			text += fmt.Sprintf(" — redo at q=%d", redoQuality(r))
+		}
+		info.SetText(text)
+		if q := redoQuality(r); q > 0 {
+			redoBtn.SetText(fmt.Sprintf("Redo at q=%d", q))
+			redoBtn.Enable()
+		} else {
+			redoBtn.SetText("Redo")
+			redoBtn.Disable()
+		}
+		accepted, redos := 0, 0
+		for _, v := range verdicts {
+			switch v {
+			case reviewAccepted:
+				accepted++
+			case reviewRedo:
+				redos++
+			}
+		}
+		tally.SetText(fmt.Sprintf("%d accepted, %d to redo, %d not reviewed", accepted, redos, len(items)-accepted-redos))
+		if redos > 0 {
+			runBtn.Enable()
+		} else {
+			runBtn.Disable()
+		}
+	}
+	step := func(delta int) {
+		if next := cur + delta; next >= 0 && next < len(items) {
+			cur = next
+			show()
+		}
+	}
+	judge := func(v int) {
+		if v == reviewRedo && redoQuality(items[cur]) == 0 {
+			return
+		}
+		verdicts[cur] = v
+		if cur+1 < len(items) {
+			cur++
+		}
+		show()
+	}
+	acceptBtn.OnTapped = func() { judge(reviewAccepted) }
+	redoBtn.OnTapped = func() { judge(reviewRedo) }
+	runBtn.OnTapped = func() {
+		redos := map[string]fileResult{}
+		for i, v := range verdicts {
+			if v == reviewRedo {
+				redos[items[i].inPath] = items[i]
+			}
+		}
+		win.Close()
+		onRedo(redos)
+	}
+	win.Canvas().SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		switch ev.Name {
+		case fyne.KeyLeft:
+			step(-1)
+		case fyne.KeyRight:
+			step(1)
+		case fyne.KeyA:
+			judge(reviewAccepted)
+		case fyne.KeyR:
+			judge(reviewRedo)
+		}
+	})
+
+	pair := container.NewGridWithColumns(2,
+		container.NewBorder(widget.NewLabel("Original"), nil, nil, nil, before),
+		container.NewBorder(widget.NewLabel("Compressed"), nil, nil, nil, after))
+	bar := container.NewHBox(
+		widget.NewButton("Previous", func() { step(-1) }),
+		widget.NewButton("Next", func() { step(1) }),
+		acceptBtn, redoBtn, runBtn, tally)
+	win.SetContent(container.NewBorder(info, bar, nil, nil, pair))
+	show()
+	win.Show()
+}
@@ -0,0 +1,533 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//
+// Rules
+// - A small per-file rule language, edited with "Rules..." and evaluated
+//   after folder overrides, e.g.
+//
+//	# one rule per line; every matching "if" applies, in order
+//	if width > 4000: resize 2560
+//	if format == "png" and alpha: format png
+//	else: format jpeg, quality 80
+//
+// - Conditions compare file facts (width, height, megapixels, size_kb,
+//   format, alpha, name, folder) with ==, !=, <, <=, >, >=, contains,
+//   combined with and/or/not and parentheses
+// - "else" applies when no "if" above it matched
+// - Actions: resize N, max_width N, max_height N, format jpeg|png|heic,
+//   quality N, target_kb N, mode photo|document|document-gray|document-bw|auto,
+//   folder "name", skip
+// - folder routes the output into a subfolder of the output folder, so
+//   one pass can sort a mixed directory, e.g.
+//
+//	if alpha or format == "png": folder "assets", format png
+//	else: folder "photos", format jpeg
+//
+
+// ruleFacts is what conditions can ask about one input file.
+type ruleFacts struct {
+	width, height int
+	sizeKB        float64
+	format        string // sniffed: "jpeg", "png", "gif", ...
+	alpha         bool   // the image declares an alpha channel
+	model         color.Model
+	name, folder  string
+}
+
+func factsFor(path string) ruleFacts {
+	f := ruleFacts{name: filepath.Base(path), folder: filepath.Base(filepath.Dir(path))}
+	f.format = strings.TrimPrefix(inputFormat(path), ".")
+	if f.format == "jpg" {
+		f.format = "jpeg"
+	}
+	if info, err := os.Stat(path); err == nil {
+		f.sizeKB = float64(info.Size()) / 1024
+	}
+	if fh, err := os.Open(path); err == nil {
+		if cfg, _, err := image.DecodeConfig(fh); err == nil {
+			f.width, f.height = cfg.Width, cfg.Height
+			f.alpha, f.model = hasAlphaModel(cfg.ColorModel), cfg.ColorModel
+		}
+		fh.Close()
+	}
+	return f
+}
+
+func hasAlphaModel(m color.Model) bool {
+	switch m {
+	case color.NRGBAModel, color.NRGBA64Model, color.RGBAModel, color.RGBA64Model, color.AlphaModel, color.Alpha16Model:
+		return true
+	}
+	if p, ok := m.(color.Palette); ok {
+		for _, c := range p {
+			if _, _, _, a := c.RGBA(); a < 0xffff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f ruleFacts) lookup(name string) (any, bool) {
+	switch name {
+	case "width":
+		return float64(f.width), true
+	case "height":
+		return float64(f.height), true
+	case "megapixels":
+		return float64(f.width*f.height) / 1e6, true
+	case "size_kb":
+		return f.sizeKB, true
+	case "format":
+		return f.format, true
+	case "alpha":
+		return f.alpha, true
+	case "name":
+		return f.name, true
+	case "folder":
+		return f.folder, true
+	}
+	return nil, false
+}
+
+// --- parsing ---
+
+type ruleToken struct {
+	kind string // "ident", "num", "str", "op"
+	text string
+	num  float64
+}
+
+func tokenizeRule(s string) ([]ruleToken, error) {
+	var toks []ruleToken
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '#':
+			return toks, nil
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_' || s[j] == '-') {
+				j++
+			}
+			toks = append(toks, ruleToken{kind: "ident", text: strings.ToLower(s[i:j])})
+			i = j
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad number %q", s[i:j])
+			}
+			toks = append(toks, ruleToken{kind: "num", text: s[i:j], num: n})
+			i = j
+		case c == '"':
+			j := strings.IndexByte(s[i+1:], '"')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			toks = append(toks, ruleToken{kind: "str", text: s[i+1 : i+1+j]})
+			i += j + 2
+		case i+1 < len(s) && ruleCompareOps[s[i:i+2]]:
+			toks = append(toks, ruleToken{kind: "op", text: s[i : i+2]})
+			i += 2
+		case strings.ContainsRune("<>():,", c):
+			toks = append(toks, ruleToken{kind: "op", text: s[i : i+1]})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q", s[i:i+1])
+		}
+	}
+	return toks, nil
+}
+
+var ruleCompareOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// ruleExpr is a parsed condition.
+type ruleExpr struct {
+	op          string // "lit", "var", "not", "and", "or", or a comparison
+	left, right *ruleExpr
+	value       any
+}
+
+type ruleParser struct {
+	toks []ruleToken
+	pos  int
+}
+
+func (p *ruleParser) peek() ruleToken {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ruleToken{}
+}
+
+func (p *ruleParser) next() ruleToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *ruleParser) isWord(w string) bool {
+	t := p.peek()
+	return t.kind == "ident" && t.text == w
+}
+
+func (p *ruleParser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == "op" && t.text == op
+}
+
+func (p *ruleParser) parseOr() (*ruleExpr, error) {
+	l, err := p.parseAnd()
+	for err == nil && p.isWord("or") {
+		p.next()
+		var r *ruleExpr
+		if r, err = p.parseAnd(); err == nil {
+			l = &ruleExpr{op: "or", left: l, right: r}
+		}
+	}
+	return l, err
+}
+
+func (p *ruleParser) parseAnd() (*ruleExpr, error) {
+	l, err := p.parseNot()
+	for err == nil && p.isWord("and") {
+		p.next()
+		var r *ruleExpr
+		if r, err = p.parseNot(); err == nil {
+			l = &ruleExpr{op: "and", left: l, right: r}
+		}
+	}
+	return l, err
+}
+
+func (p *ruleParser) parseNot() (*ruleExpr, error) {
+	if p.isWord("not") {
+		p.next()
+		e, err := p.parseNot()
+		return &ruleExpr{op: "not", left: e}, err
+	}
+	return p.parseCompare()
+}
+
+func (p *ruleParser) parseCompare() (*ruleExpr, error) {
+	l, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if (t.kind == "op" && ruleCompareOps[t.text]) || p.isWord("contains") {
+		p.next()
+		r, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return &ruleExpr{op: t.text, left: l, right: r}, nil
+	}
+	return l, nil
+}
+
+func (p *ruleParser) parseTerm() (*ruleExpr, error) {
+	t := p.next()
+	switch {
+	case t.kind == "op" && t.text == "(":
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.next()
+		return e, nil
+	case t.kind == "num":
+		return &ruleExpr{op: "lit", value: t.num}, nil
+	case t.kind == "str":
+		return &ruleExpr{op: "lit", value: t.text}, nil
+	case t.kind == "ident" && (t.text == "true" || t.text == "false"):
+		return &ruleExpr{op: "lit", value: t.text == "true"}, nil
+	case t.kind == "ident":
+		if _, ok := (ruleFacts{}).lookup(t.text); !ok {
+			return nil, fmt.Errorf("unknown fact %q", t.text)
+		}
+		return &ruleExpr{op: "var", value: t.text}, nil
+	case t.kind == "":
+		return nil, fmt.Errorf("condition ends too early")
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+func (e *ruleExpr) eval(f ruleFacts) (any, error) {
+	switch e.op {
+	case "lit":
+		return e.value, nil
+	case "var":
+		v, _ := f.lookup(e.value.(string))
+		return v, nil
+	case "not":
+		b, err := e.left.evalBool(f)
+		return !b, err
+	case "and", "or":
+		l, err := e.left.evalBool(f)
+		if err != nil || (e.op == "and" && !l) || (e.op == "or" && l) {
+			return l, err
+		}
+		return e.right.evalBool(f)
+	}
+	l, err := e.left.eval(f)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(f)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == "contains" {
+		ls, lok := l.(string)
+		rs, rok := r.(string)
+		if !lok || !rok {
+			return nil, fmt.Errorf("contains needs two strings")
+		}
+		return strings.Contains(strings.ToLower(ls), strings.ToLower(rs)), nil
+	}
+	if ln, ok := l.(float64); ok {
+		rn, ok := r.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare a number with %v", r)
+		}
+		switch e.op {
+		case "==":
+			return ln == rn, nil
+		case "!=":
+			return ln != rn, nil
+		case "<":
+			return ln < rn, nil
+		case "<=":
+			return ln <= rn, nil
+		case ">":
+			return ln > rn, nil
+		case ">=":
+			return ln >= rn, nil
+		}
+	}
+	switch e.op {
+	case "==":
+		return fmt.Sprint(l) == fmt.Sprint(r), nil
+	case "!=":
+		return fmt.Sprint(l) != fmt.Sprint(r), nil
+	}
+	return nil, fmt.Errorf("%s needs numbers", e.op)
+}
+
+func (e *ruleExpr) evalBool(f ruleFacts) (bool, error) {
+	v, err := e.eval(f)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%v is not true or false", v)
+	}
+	return b, nil
+}
+
+// ruleAction is one "name value" after the colon.
+type ruleAction struct {
+	name string
+	num  int
+	word string
+}
+
+var ruleActionKinds = map[string]string{ // name -> value kind
+	"resize": "num", "max_width": "num", "max_height": "num", "quality": "num", "target_kb": "num",
+	"format": "word", "mode": "word", "folder": "path", "skip": "",
+}
+
+func parseActions(toks []ruleToken) ([]ruleAction, error) {
+	var acts []ruleAction
+	for len(toks) > 0 {
+		t := toks[0]
+		kind, ok := ruleActionKinds[t.text]
+		if t.kind != "ident" || !ok {
+			return nil, fmt.Errorf("unknown action %q", t.text)
+		}
+		a := ruleAction{name: t.text}
+		toks = toks[1:]
+		if kind != "" {
+			if len(toks) == 0 {
+				return nil, fmt.Errorf("%s needs a value", a.name)
+			}
+			v := toks[0]
+			toks = toks[1:]
+			switch {
+			case kind == "num" && v.kind == "num":
+				a.num = int(v.num)
+			case kind == "word" && (v.kind == "ident" || v.kind == "str"):
+				a.word = strings.ToLower(v.text)
+			case kind == "path" && (v.kind == "ident" || v.kind == "str"):
+				a.word = v.text
+			default:
+				return nil, fmt.Errorf("bad value %q for %s", v.text, a.name)
+			}
+		}
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+		acts = append(acts, a)
+		if len(toks) > 0 {
+			if toks[0].kind != "op" || toks[0].text != "," {
+				return nil, fmt.Errorf("expected , between actions")
+			}
+			toks = toks[1:]
+		}
+	}
+	if len(acts) == 0 {
+		return nil, fmt.Errorf("no action after :")
+	}
+	return acts, nil
+}
+
+func (a ruleAction) validate() error {
+	switch a.name {
+	case "format":
+		if a.word == "webp" || a.word == "avif" || a.word == "heic" && !heicSupported() {
+			return fmt.Errorf("%s output is not available in this build (see Formats...)", a.word)
+		}
+		if a.word != "jpeg" && a.word != "png" && a.word != "heic" {
+			return fmt.Errorf("format must be jpeg, png or heic, got %q", a.word)
+		}
+	case "mode":
+		if _, ok := overrideModes[a.word]; !ok {
+			return fmt.Errorf("unknown mode %q", a.word)
+		}
+	case "quality":
+		if a.num < 1 || a.num > 100 {
+			return fmt.Errorf("quality must be 1-100")
+		}
+	case "folder":
+		clean := filepath.Clean(filepath.FromSlash(a.word))
+		if a.word == "" || filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" ||
+			clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("folder must be a subfolder of the output folder, got %q", a.word)
+		}
+	}
+	return nil
+}
+
+func (a ruleAction) apply(o compressOptions) compressOptions {
+	switch a.name {
+	case "resize":
+		o.maxW, o.maxH, o.fillW, o.fillH = a.num, a.num, 0, 0
+	case "max_width":
+		o.maxW, o.fillW, o.fillH = a.num, 0, 0
+	case "max_height":
+		o.maxH, o.fillW, o.fillH = a.num, 0, 0
+	case "format":
+		o.format = a.word
+	case "quality":
+		o.quality = o.quality.clamped()
+		o.quality.jpeg = a.num
+	case "target_kb":
+		o.targetKB = a.num
+	case "folder":
+		o.subfolder = filepath.Clean(filepath.FromSlash(a.word))
+	case "mode":
+		m := overrideModes[a.word]
+		o.docMode, o.autoDetect = m.docMode, m.autoDetect
+	}
+	return o
+}
+
+type rule struct {
+	line    int
+	cond    *ruleExpr // nil for else
+	actions []ruleAction
+}
+
+// ruleSet is a parsed rules text; the zero value has no rules.
+type ruleSet struct {
+	rules []rule
+}
+
+func parseRules(text string) (ruleSet, error) {
+	var rs ruleSet
+	for n, line := range strings.Split(text, "\n") {
+		toks, err := tokenizeRule(line)
+		if err != nil {
+			return rs, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		if len(toks) == 0 {
+			continue
+		}
+		r := rule{line: n + 1}
+		p := &ruleParser{toks: toks}
+		switch {
+		case p.isWord("else"):
+			p.next()
+		case p.isWord("if"):
+			p.next()
+			if r.cond, err = p.parseOr(); err != nil {
+				return rs, fmt.Errorf("line %d: %v", n+1, err)
+			}
+		default:
+			return rs, fmt.Errorf("line %d: a rule starts with if or else", n+1)
+		}
+		if !p.isOp(":") {
+			return rs, fmt.Errorf("line %d: expected : before the actions", n+1)
+		}
+		p.next()
+		if r.actions, err = parseActions(toks[p.pos:]); err != nil {
+			return rs, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		rs.rules = append(rs.rules, r)
+	}
+	return rs, nil
+}
+
+func (rs ruleSet) empty() bool { return len(rs.rules) == 0 }
+
+// apply evaluates the rules for one file. skip reports a matching "skip".
+func (rs ruleSet) apply(path string, o compressOptions) (out compressOptions, skip bool, err error) {
+	if rs.empty() {
+		return o, false, nil
+	}
+	facts := factsFor(path)
+	matched := false
+	for _, r := range rs.rules {
+		hit := !matched
+		if r.cond != nil {
+			if hit, err = r.cond.evalBool(facts); err != nil {
+				return o, false, fmt.Errorf("rule on line %d: %v", r.line, err)
+			}
+			matched = matched || hit
+		} else {
+			matched = false // an else closes the group above it
+		}
+		if !hit {
+			continue
+		}
+		for _, a := range r.actions {
+			if a.name == "skip" {
+				return o, true, nil
+			}
+			o = a.apply(o)
+		}
+	}
+	return o, false, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//
+// S3 upload
+// - Plain PUT Object signed with AWS Signature V4; no SDK
+// - Credentials come from the secret store (Credentials dialog), else
+//   AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY (and AWS_SESSION_TOKEN)
+// - AWS_ENDPOINT_URL selects an S3-compatible service, addressed path-style
+//
+
+type s3Dest struct {
+	bucket, prefix, region string
+	endpoint               string // empty for AWS virtual-hosted URLs
+	accessKey, secretKey   string
+	sessionToken           string
+	client                 *http.Client
+	limiter                *rateLimiter // shared by all uploads; nil = unlimited
+	sent                   *int64       // upload progress counter, may be nil
+}
+
+// newS3Dest parses "s3://bucket/prefix" and looks up the credentials.
+func newS3Dest(rawURL, region string) (*s3Dest, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("S3 destination must look like s3://bucket/prefix")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	d := &s3Dest{
+		bucket:   u.Host,
+		prefix:   strings.Trim(u.Path, "/"),
+		region:   region,
+		endpoint: strings.TrimRight(os.Getenv("AWS_ENDPOINT_URL"), "/"),
+		client:   &http.Client{Timeout: 5 * time.Minute},
+	}
+	for _, c := range []struct {
+		account string
+		dst     *string
+	}{{"s3-access-key-id", &d.accessKey}, {"s3-secret-access-key", &d.secretKey}, {"s3-session-token", &d.sessionToken}} {
+		if *c.dst, err = lookupCredential(c.account); err != nil {
+			return nil, err
+		}
+	}
+	if d.accessKey == "" || d.secretKey == "" {
+		return nil, fmt.Errorf("S3 destination needs an access key: add it under Credentials... or set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return d, nil
+}
+
+func (d *s3Dest) name() string {
+	if d.prefix == "" {
+		return "s3://" + d.bucket
+	}
+	return "s3://" + d.bucket + "/" + d.prefix
+}
+
+func (d *s3Dest) close() error { return nil }
+func (d *s3Dest) remote() bool { return true }
+
+// objectURL returns the request URL, the Host header, and the canonical (encoded) path.
+func (d *s3Dest) objectURL(key string) (string, string, string) {
+	if d.endpoint != "" {
+		u, _ := url.Parse(d.endpoint)
+		path := "/" + s3Escape(d.bucket) + "/" + s3Escape(key)
+		return d.endpoint + path, u.Host, path
+	}
+	host := d.bucket + ".s3." + d.region + ".amazonaws.com"
+	path := "/" + s3Escape(key)
+	return "https://" + host + path, host, path
+}
+
+func (d *s3Dest) put(localPath string) error {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	key := filepath.Base(localPath)
+	if d.prefix != "" {
+		key = d.prefix + "/" + key
+	}
+	reqURL, host, path := d.objectURL(key)
+	sent := d.sent
+	if sent == nil {
+		sent = new(int64)
+	}
+	body := &throttledReader{r: bytes.NewReader(data), limiter: d.limiter, sent: sent}
+	req, err := http.NewRequest(http.MethodPut, reqURL, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(data))
+	if ct := mime.TypeByExtension(filepath.Ext(localPath)); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+	d.sign(req, host, path, data, time.Now().UTC())
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// sign adds the Signature V4 headers for a single-chunk payload.
+func (d *s3Dest) sign(req *http.Request, host, path string, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if d.sessionToken != "" {
+		req.Header.Set("x-amz-security-token", d.sessionToken)
+	}
+
+	headers := map[string]string{"host": host}
+	names := []string{"host"}
+	for _, h := range []string{"content-type", "x-amz-content-sha256", "x-amz-date", "x-amz-security-token"} {
+		if v := req.Header.Get(h); v != "" {
+			headers[h] = v
+			names = append(names, h)
+		}
+	}
+	// names are already in sorted order
+	var canonHeaders strings.Builder
+	for _, n := range names {
+		canonHeaders.WriteString(n + ":" + strings.TrimSpace(headers[n]) + "\n")
+	}
+	signed := strings.Join(names, ";")
+	canonical := strings.Join([]string{req.Method, path, "", canonHeaders.String(), signed, payloadHash}, "\n")
+
+	scope := day + "/" + d.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+d.secretKey), day)
+	key = hmacSHA256(key, d.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		d.accessKey, scope, signed, sig))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+// s3Escape URI-encodes every byte except unreserved characters and '/'.
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
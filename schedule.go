@@ -0,0 +1,92 @@
+//go:build !headless
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+//
+// Scheduled runs
+// - A scheduled job is an input folder plus a snapshot of the window's
+//   settings (output folder included), run nightly or weekly at a set time,
+//   e.g. "everything new in ~/Pictures/Inbox every night at 2am"
+// - Jobs run while the app is open; one missed while it was closed runs
+//   once when it next starts. The up-to-date check is always on, so each
+//   run only compresses what is new since the last one
+// - Jobs are kept in the app preferences and edited with "Schedules..."
+//
+
+const (
+	schedulesKey  = "schedules"
+	scheduleCheck = time.Minute
+)
+
+type scheduledJob struct {
+	Name     string
+	Input    string
+	Weekly   bool
+	Weekday  time.Weekday // for weekly jobs
+	At       string       // "15:04"
+	Settings map[string]string
+	LastRun  time.Time
+}
+
+// parseClock reads "2:00" or "02:00" as hours and minutes.
+func parseClock(s string) (int, int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &h, &m); err != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, 0, fmt.Errorf("%q is not a time like 02:00", s)
+	}
+	return h, m, nil
+}
+
+// last is the most recent time at or before now the job was meant to run.
+func (j scheduledJob) last(now time.Time) time.Time {
+	h, m, err := parseClock(j.At)
+	if err != nil {
+		return time.Time{}
+	}
+	t := time.Date(now.Year(), now.Month(), now.Day(), h, m, 0, 0, now.Location())
+	if t.After(now) {
+		t = t.AddDate(0, 0, -1)
+	}
+	if j.Weekly {
+		t = t.AddDate(0, 0, -((int(t.Weekday()) - int(j.Weekday) + 7) % 7))
+	}
+	return t
+}
+
+// due reports whether the job has a run it has not done yet.
+func (j scheduledJob) due(now time.Time) bool {
+	last := j.last(now)
+	return !last.IsZero() && j.LastRun.Before(last)
+}
+
+func (j scheduledJob) String() string {
+	when := "daily"
+	if j.Weekly {
+		when = j.Weekday.String() + "s"
+	}
+	return fmt.Sprintf("%s — %s, %s at %s", j.Name, j.Input, when, j.At)
+}
+
+func loadSchedules(p fyne.Preferences) []scheduledJob {
+	var jobs []scheduledJob
+	if s := p.String(schedulesKey); s != "" {
+		json.Unmarshal([]byte(s), &jobs) // unreadable schedules start over
+	}
+	return jobs
+}
+
+func saveSchedules(p fyne.Preferences, jobs []scheduledJob) {
+	data, err := json.Marshal(jobs)
+	if err == nil {
+		p.SetString(schedulesKey, string(data))
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+//
+// Scratch space
+// - Downloads, archive entries, rasterized PDF pages, mail attachments and
+//   merged bursts are staged under one "image-compressor" folder, by
+//   default in the system temp folder; it can be moved to a bigger or
+//   faster disk with "Scratch Space..."
+// - Each use gets its own run folder, e.g. downloads/20240102-150405-1234
+// - A size cap refuses new scratch folders once it is reached, instead of
+//   filling the disk; at startup the oldest run folders are deleted until
+//   the cap is met again, sparing those holding queued files
+// - Short-lived folders (archive entries, PDF pages) are removed as soon as
+//   they are done; ones left behind by a crash go at the next startup
+//
+
+const (
+	scratchDirKey   = "scratch.dir"
+	scratchCapKey   = "scratch.capMB"
+	scratchCapMB    = 4096 // default cap; 0 means no cap
+	scratchAppDir   = "image-compressor"
+	scratchStaleAge = 24 * time.Hour
+)
+
+// scratchTransient are the kinds whose run folders are never reused once
+// the process that made them is gone.
+var scratchTransient = map[string]bool{"archive": true, "pdf": true}
+
+type scratchSpace struct {
+	root     string // parent folder; "" is the system temp folder
+	capBytes int64  // 0 = no cap
+}
+
+// scratch is the app's scratch space, configured from the preferences at
+// startup. Helpers outside the window use it directly.
+var scratch = &scratchSpace{capBytes: scratchCapMB << 20}
+
+func loadScratchSpace(p preferences) *scratchSpace {
+	return &scratchSpace{
+		root:     p.String(scratchDirKey),
+		capBytes: int64(max(0, p.IntWithFallback(scratchCapKey, scratchCapMB))) << 20,
+	}
+}
+
+func (s *scratchSpace) save(p preferences) {
+	p.SetString(scratchDirKey, s.root)
+	p.SetInt(scratchCapKey, int(s.capBytes>>20))
+}
+
+// base is the folder holding every run folder.
+func (s *scratchSpace) base() string {
+	root := s.root
+	if root == "" {
+		root = os.TempDir()
+	}
+	return filepath.Join(root, scratchAppDir)
+}
+
+// usage is the combined size of everything in the scratch space.
+func (s *scratchSpace) usage() int64 {
+	var n int64
+	filepath.WalkDir(s.base(), func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				n += info.Size()
+			}
+		}
+		return nil // unreadable entries do not count
+	})
+	return n
+}
+
+// room is how much more the scratch space may hold under its cap, or -1
+// when it has none.
+func (s *scratchSpace) room() int64 {
+	if s.capBytes <= 0 {
+		return -1
+	}
+	return max(0, s.capBytes-s.usage())
+}
+
+// dir creates a fresh run folder for kind, e.g. "downloads".
+func (s *scratchSpace) dir(kind string) (string, error) {
+	if s.capBytes > 0 {
+		if used := s.usage(); used >= s.capBytes {
+			return "", fmt.Errorf("scratch space is full (%s of %s in %s); raise the cap or clear it with Scratch Space...", formatSize(used), formatSize(s.capBytes), s.base())
+		}
+	}
+	parent := filepath.Join(s.base(), kind)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", fmt.Errorf("scratch folder: %v", err)
+	}
+	dir, err := os.MkdirTemp(parent, time.Now().Format("20060102-150405")+"-*")
+	if err != nil {
+		return "", fmt.Errorf("scratch folder: %v", err)
+	}
+	return dir, nil
+}
+
+type scratchRun struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// runs lists every run folder, oldest first.
+func (s *scratchSpace) runs() []scratchRun {
+	var runs []scratchRun
+	kinds, _ := os.ReadDir(s.base())
+	for _, k := range kinds {
+		if !k.IsDir() {
+			continue
+		}
+		entries, _ := os.ReadDir(filepath.Join(s.base(), k.Name()))
+		for _, e := range entries {
+			run := scratchRun{path: filepath.Join(s.base(), k.Name(), e.Name())}
+			if info, err := e.Info(); err == nil {
+				run.modTime = info.ModTime()
+			}
+			filepath.WalkDir(run.path, func(_ string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					if info, err := d.Info(); err == nil {
+						run.size += info.Size()
+					}
+				}
+				return nil
+			})
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].modTime.Before(runs[j].modTime) })
+	return runs
+}
+
+// trim deletes leftover short-lived folders and ones older than a day (or
+// every folder, with all), then the oldest ones until the cap is met.
+// Folders holding one of inUse are kept. It must run while nothing else
+// writes scratch files, i.e. at startup or on request, and returns the
+// bytes freed.
+func (s *scratchSpace) trim(inUse []string, all bool) (freed int64) {
+	used := func(dir string) bool {
+		for _, p := range inUse {
+			if isWithin(p, dir) {
+				return true
+			}
+		}
+		return false
+	}
+	var total int64
+	runs := s.runs()
+	for _, r := range runs {
+		total += r.size
+	}
+	for _, r := range runs {
+		kind := filepath.Base(filepath.Dir(r.path))
+		expired := all || scratchTransient[kind] || time.Since(r.modTime) > scratchStaleAge
+		overCap := s.capBytes > 0 && total > s.capBytes
+		if used(r.path) || !expired && !overCap {
+			continue
+		}
+		if os.RemoveAll(r.path) == nil {
+			total -= r.size
+			freed += r.size
+		}
+	}
+	return freed
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// Screenshot heuristics, measured on a nearest-neighbour thumbnail so flat
+// areas stay exactly flat. Camera photos almost never have identical
+// neighbouring pixels because of sensor noise; UI captures are mostly that.
+const (
+	shotFlatMin      = 0.5  // share of identical horizontal neighbours
+	shotFlatEdgyMin  = 0.3  // lower flat share is enough when edges are hard...
+	shotSharpEdgeMin = 0.35 // ...i.e. this share of the non-flat steps are big jumps
+	shotSharpDelta   = 96   // channel difference that counts as a hard edge
+)
+
+// looksLikeScreenshot reports whether img is UI/text-like content that should
+// be stored losslessly rather than as JPEG.
+func looksLikeScreenshot(img image.Image) bool {
+	small := imaging.Fit(img, 640, 640, imaging.NearestNeighbor)
+	b := small.Bounds()
+	var flat, steps, sharp int
+	for y := 0; y < b.Dy(); y++ {
+		row := small.Pix[y*small.Stride:]
+		for x := 0; x+1 < b.Dx(); x++ {
+			o := x * 4
+			dr := absDiff(row[o], row[o+4])
+			dg := absDiff(row[o+1], row[o+5])
+			db := absDiff(row[o+2], row[o+6])
+			if dr == 0 && dg == 0 && db == 0 {
+				flat++
+				continue
+			}
+			steps++
+			if dr >= shotSharpDelta || dg >= shotSharpDelta || db >= shotSharpDelta {
+				sharp++
+			}
+		}
+	}
+	total := flat + steps
+	if total == 0 {
+		return false
+	}
+	flatShare := float64(flat) / float64(total)
+	if flatShare >= shotFlatMin {
+		return true
+	}
+	return flatShare >= shotFlatEdgyMin && steps > 0 && float64(sharp)/float64(steps) >= shotSharpEdgeMin
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
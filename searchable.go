@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//
+// Searchable outputs
+// - Re-encoding drops the source's metadata, so Spotlight loses the
+//   capture date and keywords it indexed compressed archives by. With
+//   "Keep capture date and keywords" ticked, both are written back into
+//   each JPEG or PNG output as a small XMP packet, which Spotlight and
+//   Quick Look read
+// - The date comes from EXIF, the keywords from the source's XMP dc:subject
+//   (what Lightroom, Photos and Bridge write)
+// - "Write .info.json sidecars" puts the processing details next to each
+//   output: source, sizes, quality, dimensions, date and keywords
+// - Copied-through JPEGs keep all their metadata and are left as they are
+//
+
+const (
+	xmpNamespace = "http://ns.adobe.com/xap/1.0/\x00"
+	xmpScanLimit = 4 << 20 // bytes of a source searched for its XMP packet
+)
+
+var (
+	xmpSubject = regexp.MustCompile(`(?s)<dc:subject>(.*?)</dc:subject>`)
+	xmpItem    = regexp.MustCompile(`(?s)<rdf:li[^>]*>(.*?)</rdf:li>`)
+)
+
+// sourceMetadata is what an output should keep to stay searchable, and
+// what it records about how it was made.
+type sourceMetadata struct {
+	captured time.Time // zero when unknown
+	keywords []string
+	software string // "" unless processing is recorded
+	history  string
+}
+
+func (m sourceMetadata) empty() bool {
+	return m.captured.IsZero() && len(m.keywords) == 0 && m.software == ""
+}
+
+func readSourceMetadata(path string) sourceMetadata {
+	var m sourceMetadata
+	if ex, err := readEXIF(path); err == nil {
+		if t, err := ex.DateTime(); err == nil {
+			m.captured = t
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return m
+	}
+	defer f.Close()
+	head, _ := io.ReadAll(io.LimitReader(f, xmpScanLimit))
+	if sub := xmpSubject.FindSubmatch(head); sub != nil {
+		for _, li := range xmpItem.FindAllSubmatch(sub[1], -1) {
+			if k := strings.TrimSpace(html.UnescapeString(string(li[1]))); k != "" {
+				m.keywords = append(m.keywords, k)
+			}
+		}
+	}
+	return m
+}
+
+// xmpPacket is the XMP for m: the capture date under the names ImageIO
+// maps to kMDItemContentCreationDate, the keywords as dc:subject, and the
+// software with its history entry.
+func xmpPacket(m sourceMetadata) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xpacket begin="` + "\uFEFF" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>`)
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">`)
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xmp="http://ns.adobe.com/xap/1.0/"`)
+	b.WriteString(` xmlns:exif="http://ns.adobe.com/exif/1.0/" xmlns:photoshop="http://ns.adobe.com/photoshop/1.0/"`)
+	b.WriteString(` xmlns:tiff="http://ns.adobe.com/tiff/1.0/" xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/" xmlns:stEvt="http://ns.adobe.com/xap/1.0/sType/ResourceEvent#">`)
+	if !m.captured.IsZero() {
+		d := m.captured.Format("2006-01-02T15:04:05")
+		fmt.Fprintf(&b, "<exif:DateTimeOriginal>%s</exif:DateTimeOriginal><xmp:CreateDate>%s</xmp:CreateDate><photoshop:DateCreated>%s</photoshop:DateCreated>", d, d, d)
+	}
+	if len(m.keywords) > 0 {
+		b.WriteString("<dc:subject><rdf:Bag>")
+		for _, k := range m.keywords {
+			b.WriteString("<rdf:li>" + html.EscapeString(k) + "</rdf:li>")
+		}
+		b.WriteString("</rdf:Bag></dc:subject>")
+	}
+	if m.software != "" {
+		sw := html.EscapeString(m.software)
+		fmt.Fprintf(&b, "<tiff:Software>%s</tiff:Software><xmp:CreatorTool>%s</xmp:CreatorTool>", sw, sw)
+		fmt.Fprintf(&b, `<xmpMM:History><rdf:Seq><rdf:li rdf:parseType="Resource"><stEvt:action>converted</stEvt:action>`+
+			"<stEvt:when>%s</stEvt:when><stEvt:softwareAgent>%s</stEvt:softwareAgent><stEvt:parameters>%s</stEvt:parameters>"+
+			"</rdf:li></rdf:Seq></xmpMM:History>", time.Now().Format(time.RFC3339), sw, html.EscapeString(m.history))
+	}
+	b.WriteString(`</rdf:Description></rdf:RDF></x:xmpmeta><?xpacket end="w"?>`)
+	return []byte(b.String())
+}
+
+// withJPEGXMP inserts an APP1 XMP segment after the SOI and any JFIF
+// header, where readers look for it.
+func withJPEGXMP(data, packet []byte) ([]byte, error) {
+	return withJPEGAPP1(data, append([]byte(xmpNamespace), packet...))
+}
+
+// withJPEGAPP1 inserts the APP1 segment body seg after the SOI and any
+// JFIF header, ahead of APP1 segments already there.
+func withJPEGAPP1(data, seg []byte) ([]byte, error) {
+	if len(seg)+2 > 0xFFFF {
+		return nil, fmt.Errorf("metadata too large for one JPEG segment")
+	}
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("not a JPEG")
+	}
+	at := 2
+	for at+4 <= len(data) && data[at] == 0xFF && data[at+1] == 0xE0 { // APP0 (JFIF) stays first
+		at += 2 + int(binary.BigEndian.Uint16(data[at+2:]))
+	}
+	if at > len(data) {
+		return nil, fmt.Errorf("corrupt JPEG header")
+	}
+	var out bytes.Buffer
+	out.Write(data[:at])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(seg)+2))
+	out.Write(seg)
+	out.Write(data[at:])
+	return out.Bytes(), nil
+}
+
+// withPNGXMP inserts an iTXt chunk with the XMP right after IHDR.
+func withPNGXMP(data, packet []byte) ([]byte, error) {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4 // signature, then the IHDR chunk
+	if len(data) < ihdrEnd || string(data[12:16]) != "IHDR" {
+		return nil, fmt.Errorf("not a PNG")
+	}
+	body := append([]byte("iTXtXML:com.adobe.xmp\x00\x00\x00\x00\x00"), packet...)
+	var out bytes.Buffer
+	out.Write(data[:ihdrEnd])
+	binary.Write(&out, binary.BigEndian, uint32(len(body)-4))
+	out.Write(body)
+	binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(body))
+	out.Write(data[ihdrEnd:])
+	return out.Bytes(), nil
+}
+
+// tagOutput writes m into outPath unless the output already carries
+// metadata of its own (a copied-through source; the encoders write none).
+func tagOutput(outPath string, m sourceMetadata) error {
+	if m.empty() {
+		return nil
+	}
+	data, err := disk.ReadFile(outPath)
+	if err != nil {
+		return fmt.Errorf("metadata failed: %v", err)
+	}
+	head := data[:min(len(data), xmpScanLimit)]
+	if bytes.Contains(head, []byte("<x:xmpmeta")) || bytes.Contains(head, []byte("Exif\x00\x00")) {
+		return nil
+	}
+	var tagged []byte
+	switch sniffFormat(outPath) {
+	case ".jpg":
+		tagged, err = withJPEGXMP(data, xmpPacket(m))
+	case ".png":
+		tagged, err = withPNGXMP(data, xmpPacket(m))
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("metadata failed: %v", err)
+	}
+	return atomicWrite(context.Background(), outPath, func(tmp string) error { return disk.WriteFile(tmp, tagged, 0644) })
+}
+
+type infoSidecar struct {
+	Source      string    `json:"source"`
+	Output      string    `json:"output"`
+	SourceBytes int64     `json:"sourceBytes"`
+	OutputBytes int64     `json:"outputBytes"`
+	Quality     int       `json:"quality,omitempty"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Captured    string    `json:"captured,omitempty"`
+	Keywords    []string  `json:"keywords,omitempty"`
+	Settings    string    `json:"settings"` // as in the manifest
+	Processed   time.Time `json:"processed"`
+}
+
+// writeInfoSidecar writes "<output name>.info.json" next to the output.
+func writeInfoSidecar(res fileResult, width, height int, m sourceMetadata, opts compressOptions) error {
+	info := infoSidecar{
+		Source: res.inPath, Output: filepath.Base(res.outPath), SourceBytes: res.inSize, OutputBytes: res.outSize,
+		Quality: res.quality, Width: width, Height: height, Keywords: m.keywords,
+		Settings: settingsSignature(opts), Processed: time.Now().UTC().Truncate(time.Second),
+	}
+	if !m.captured.IsZero() {
+		info.Captured = m.captured.Format("2006-01-02T15:04:05")
+	}
+	js, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	base := res.outPath[:len(res.outPath)-len(filepath.Ext(res.outPath))]
+	if err := os.WriteFile(base+".info.json", js, 0644); err != nil {
+		return fmt.Errorf("sidecar failed: %v", err)
+	}
+	return nil
+}
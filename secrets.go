@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+//
+// Credentials
+// - Secrets live in the OS secret store (macOS Keychain, libsecret on
+//   Linux), never in preferences or the queue file
+// - Environment variables still work and are used when nothing is stored
+//
+
+const secretService = "com.sanyam.imagecompressor"
+
+// credential is one secret the app knows how to store.
+type credential struct {
+	account string // key in the secret store
+	label   string
+	env     string // fallback environment variable
+}
+
+var credentials = []credential{
+	{"s3-access-key-id", "S3 access key ID", "AWS_ACCESS_KEY_ID"},
+	{"s3-secret-access-key", "S3 secret access key", "AWS_SECRET_ACCESS_KEY"},
+	{"s3-session-token", "S3 session token (optional)", "AWS_SESSION_TOKEN"},
+}
+
+// lookupCredential returns the stored secret, else the environment value.
+func lookupCredential(account string) (string, error) {
+	for _, c := range credentials {
+		if c.account != account {
+			continue
+		}
+		v, err := getSecret(account)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", c.label, err)
+		}
+		if v == "" {
+			v = os.Getenv(c.env)
+		}
+		return v, nil
+	}
+	return "", fmt.Errorf("unknown credential %q", account)
+}
@@ -0,0 +1,39 @@
+//go:build darwin
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// exit status of the security tool when no item matches
+const securityNotFound = 44
+
+func getSecret(account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", secretService, "-a", account, "-w").Output()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == securityNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func setSecret(account, value string) error {
+	// -U updates an existing item instead of failing. The tool only takes the
+	// password as an argument, so it is briefly visible to local processes.
+	return exec.Command("security", "add-generic-password", "-U", "-s", secretService, "-a", account, "-w", value).Run()
+}
+
+func deleteSecret(account string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", secretService, "-a", account).Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == securityNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secret-tool talks to the desktop keyring (GNOME Keyring, KWallet) over libsecret.
+
+func getSecret(account string) (string, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", nil // no keyring; fall back to the environment
+	}
+	out, err := exec.Command("secret-tool", "lookup", "service", secretService, "account", account).Output()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && len(out) == 0 {
+		return "", nil // not found
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func setSecret(account, value string) error {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return fmt.Errorf("secret-tool (libsecret) is not installed")
+	}
+	cmd := exec.Command("secret-tool", "store", "--label", "Image Compressor "+account,
+		"service", secretService, "account", account)
+	cmd.Stdin = strings.NewReader(value) // keeps the secret off the command line
+	return cmd.Run()
+}
+
+func deleteSecret(account string) error {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil
+	}
+	return exec.Command("secret-tool", "clear", "service", secretService, "account", account).Run()
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !linux
+
+package main
+
+import "fmt"
+
+func getSecret(account string) (string, error) { return "", nil }
+
+func setSecret(account, value string) error {
+	return fmt.Errorf("no secret store on this platform; use environment variables instead")
+}
+
+func deleteSecret(account string) error { return nil }
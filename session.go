@@ -0,0 +1,69 @@
+//go:build !headless
+
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+//
+// Session autosave
+// - Besides the queue (queue.go), the window's settings are saved as named
+//   string values, periodically and every autosaveInterval during a run
+// - A session saved without a clean quit is offered for recovery on the
+//   next launch
+//
+
+const autosaveInterval = 30 * time.Second
+
+// sessionField reads and writes one setting as a string.
+type sessionField struct {
+	get func() string
+	set func(string)
+}
+
+func entryField(e *widget.Entry) sessionField {
+	return sessionField{get: func() string { return e.Text }, set: e.SetText}
+}
+
+func checkField(c *widget.Check) sessionField {
+	return sessionField{
+		get: func() string { return strconv.FormatBool(c.Checked) },
+		set: func(v string) { c.SetChecked(v == "true") },
+	}
+}
+
+func selectField(s *widget.Select) sessionField {
+	return sessionField{get: func() string { return s.Selected }, set: s.SetSelected}
+}
+
+func sliderField(s *widget.Slider) sessionField {
+	return sessionField{
+		get: func() string { return strconv.FormatFloat(s.Value, 'f', -1, 64) },
+		set: func(v string) {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				s.SetValue(f)
+			}
+		},
+	}
+}
+
+func captureSettings(fields map[string]sessionField) map[string]string {
+	m := make(map[string]string, len(fields))
+	for k, f := range fields {
+		m[k] = f.get()
+	}
+	return m
+}
+
+// applySettings restores saved values; keys from older versions are ignored.
+func applySettings(fields map[string]sessionField, m map[string]string) {
+	for k, v := range m {
+		if f, ok := fields[k]; ok {
+			f.set(v)
+		}
+	}
+}
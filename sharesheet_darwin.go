@@ -0,0 +1,42 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework AppKit
+#include <stdlib.h>
+#import <AppKit/AppKit.h>
+
+// showSharePicker opens the system share sheet (AirDrop, Messages, Notes...)
+// for the given files, anchored to the middle of the key window.
+static void showSharePicker(char **paths, int n) {
+	NSMutableArray *urls = [NSMutableArray arrayWithCapacity:n];
+	for (int i = 0; i < n; i++) {
+		[urls addObject:[NSURL fileURLWithPath:[NSString stringWithUTF8String:paths[i]]]];
+	}
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow *win = [NSApp keyWindow];
+		if (win == nil) {
+			return;
+		}
+		NSView *view = [win contentView];
+		NSSharingServicePicker *picker = [[NSSharingServicePicker alloc] initWithItems:urls];
+		NSRect r = NSMakeRect(NSMidX(view.bounds), NSMidY(view.bounds), 1, 1);
+		[picker showRelativeToRect:r ofView:view preferredEdge:NSMinYEdge];
+	});
+}
+*/
+import "C"
+
+import "unsafe"
+
+func showShareSheet(paths []string) error {
+	cs := make([]*C.char, len(paths))
+	for i, p := range paths {
+		cs[i] = C.CString(p)
+		defer C.free(unsafe.Pointer(cs[i]))
+	}
+	C.showSharePicker(&cs[0], C.int(len(cs)))
+	return nil
+}
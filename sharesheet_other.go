@@ -0,0 +1,9 @@
+//go:build !darwin
+
+package main
+
+import "fmt"
+
+func showShareSheet(paths []string) error {
+	return fmt.Errorf("the share sheet is only available on macOS")
+}
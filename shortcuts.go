@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//
+// Shortcuts actions (macOS)
+// - App Intents need a Swift target, so the actions are URLs instead: the
+//   app handles the imagecompressor:// scheme (declared in the bundle's
+//   Info.plist), which Shortcuts drives with "Open X-Callback URL":
+//     imagecompressor://compress?path=/a.jpg&path=/b.png
+//     imagecompressor://compress-folder?path=/Inbox&preset=Open%20Graph%20Image%20(1200×630)
+//   "out" overrides the output folder, but only with one of the recent
+//   output folders; otherwise the window's settings apply
+// - Any web page or app can open such a URL, so a run it asks for is
+//   confirmed in the window first, unless the user chose to trust
+//   Shortcuts from then on
+// - x-success is opened afterwards with done, failed and output added to
+//   its query, so a shortcut can carry on with the results; x-error gets
+//   errorMessage when the run could not start
+// - Runs are unattended, like scheduled jobs: no dialogs, problems go to
+//   the run log
+//
+
+const (
+	shortcutScheme   = "imagecompressor"
+	shortcutTrustKey = "shortcuts.trusted" // runs start without asking
+)
+
+const (
+	shortcutCompress       = "compress"        // "Compress Files"
+	shortcutCompressFolder = "compress-folder" // "Compress Folder with Preset"
+)
+
+type shortcutRequest struct {
+	action  string
+	paths   []string
+	preset  string // "" keeps the window's preset
+	out     string // "" keeps the window's output folder
+	success string // x-success callback
+	failure string // x-error callback
+}
+
+func parseShortcutURL(raw string) (shortcutRequest, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != shortcutScheme {
+		return shortcutRequest{}, fmt.Errorf("not an %s:// URL: %s", shortcutScheme, raw)
+	}
+	q := u.Query()
+	req := shortcutRequest{action: u.Host, paths: q["path"], preset: q.Get("preset"), out: q.Get("out"),
+		success: q.Get("x-success"), failure: q.Get("x-error")}
+	switch req.action {
+	case shortcutCompress:
+		if len(req.paths) == 0 {
+			return req, fmt.Errorf("compress needs at least one path")
+		}
+	case shortcutCompressFolder:
+		if len(req.paths) != 1 {
+			return req, fmt.Errorf("compress-folder needs exactly one path")
+		}
+		if info, err := os.Stat(req.paths[0]); err != nil || !info.IsDir() {
+			return req, fmt.Errorf("folder not found: %s", req.paths[0])
+		}
+	default:
+		return req, fmt.Errorf("unknown action %q; use %s or %s", req.action, shortcutCompress, shortcutCompressFolder)
+	}
+	if req.preset != "" && findPreset(req.preset).name != req.preset {
+		return req, fmt.Errorf("unknown preset %q", req.preset)
+	}
+	return req, nil
+}
+
+// callback is base with params added to its query, or nil without a base.
+func callback(base string, params map[string]string) *url.URL {
+	if base == "" {
+		return nil
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil
+	}
+	q := u.Query()
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+	return u
+}
+
+// checkShortcutOutput accepts out only when it is one of the recent output
+// folders, so a URL cannot send outputs somewhere the user never chose.
+func checkShortcutOutput(out string, recent []string) error {
+	clean := filepath.Clean(out)
+	for _, d := range recent {
+		if filepath.Clean(d) == clean {
+			return nil
+		}
+	}
+	return fmt.Errorf("out must be one of the recent output folders, got %q", out)
+}
+
+// describe is what the confirmation shows for req.
+func (req shortcutRequest) describe() string {
+	lines := []string{"Paths:\n  " + strings.Join(req.paths, "\n  ")}
+	if req.preset != "" {
+		lines = append(lines, "Preset: "+req.preset)
+	}
+	if req.out != "" {
+		lines = append(lines, "Output folder: "+req.out)
+	}
+	return strings.Join(lines, "\n")
+}
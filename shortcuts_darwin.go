@@ -0,0 +1,53 @@
+//go:build darwin && !headless
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework AppKit
+#import <AppKit/AppKit.h>
+
+extern void goShortcutURL(char *url);
+
+@interface ShortcutURLHandler : NSObject
+@end
+
+@implementation ShortcutURLHandler
+- (void)handleGetURL:(NSAppleEventDescriptor *)event withReplyEvent:(NSAppleEventDescriptor *)reply {
+	NSString *url = [[event paramDescriptorForKeyword:keyDirectObject] stringValue];
+	if (url != nil) {
+		goShortcutURL((char *)[url UTF8String]);
+	}
+}
+@end
+
+// listenForURLs routes "open URL" Apple Events, which Shortcuts and
+// `open imagecompressor://...` send, to goShortcutURL.
+static void listenForURLs(void) {
+	static ShortcutURLHandler *handler;
+	handler = [ShortcutURLHandler new];
+	[[NSAppleEventManager sharedAppleEventManager] setEventHandler:handler
+		andSelector:@selector(handleGetURL:withReplyEvent:)
+		forEventClass:kInternetEventClass andEventID:kAEGetURL];
+}
+*/
+import "C"
+
+import "fyne.io/fyne/v2"
+
+var shortcutHandler func(string)
+
+// listenShortcutURLs calls fn on the UI goroutine for each URL the app is
+// opened with. The scheme must be declared in the bundle's Info.plist.
+func listenShortcutURLs(fn func(string)) {
+	shortcutHandler = fn
+	C.listenForURLs()
+}
+
+func deliverShortcutURL(url string) {
+	fyne.Do(func() {
+		if shortcutHandler != nil {
+			shortcutHandler(url)
+		}
+	})
+}
@@ -0,0 +1,6 @@
+//go:build !darwin
+
+package main
+
+// listenShortcutURLs does nothing here; Shortcuts is macOS only.
+func listenShortcutURLs(fn func(string)) {}
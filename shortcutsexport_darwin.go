@@ -0,0 +1,13 @@
+//go:build darwin && !headless
+
+package main
+
+// The export lives apart from shortcuts_darwin.go: cgo allows no
+// definitions in the preamble of a file with //export.
+
+import "C"
+
+//export goShortcutURL
+func goShortcutURL(url *C.char) {
+	deliverShortcutURL(C.GoString(url))
+}
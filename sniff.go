@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+//
+// Format sniffing
+// - Inputs are identified by their magic bytes, so a PNG named .jpg or a
+//   HEIC photo exported as .jpg is routed (or rejected) by what it is
+// - The extension is only a fallback for content that is not recognised
+// - Outputs are sniffed too once written: whatever path an encoder was
+//   handed, the file ends up named after what it holds (no .jpg files with
+//   PNG inside), still unique in its folder
+//
+
+// sniffFormat identifies a file by its magic bytes and returns the
+// canonical extension for what it really is (".jpg", ".heic", ...), or ""
+// when the content is not recognised. Exports regularly get this wrong,
+// e.g. WhatsApp saving HEIC photos as .jpg.
+func sniffFormat(path string) string {
+	f, err := disk.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, 32)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return ".jpg"
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return ".png"
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return ".gif"
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return ".webp"
+	case bytes.HasPrefix(head, []byte("BM")):
+		return ".bmp"
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return ".tiff"
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return ".pdf"
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		// ISO base media: the major brand tells HEIF and AVIF apart
+		switch string(head[8:12]) {
+		case "avif", "avis":
+			return ".avif"
+		case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1":
+			return ".heic"
+		}
+	}
+	return ""
+}
+
+// settleOutputExt renames a written output whose extension does not match
+// its content and returns where it now is.
+func settleOutputExt(path string) (string, error) {
+	got := sniffFormat(path)
+	if got == "" {
+		return path, nil // e.g. video: not sniffed, trust the encoder
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == got || (ext == ".jpeg" && got == ".jpg") || (ext == ".tif" && got == ".tiff") {
+		return path, nil
+	}
+	fixed := withExt(path, got)
+	if err := disk.Rename(path, fixed); err != nil {
+		return path, fmt.Errorf("rename failed: %v", err)
+	}
+	return fixed, nil
+}
+
+// inputFormat is the sniffed format, falling back to the extension.
+func inputFormat(path string) string {
+	if f := sniffFormat(path); f != "" {
+		return f
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".jpeg":
+		return ".jpg"
+	case ".tif":
+		return ".tiff"
+	case ".heif":
+		return ".heic"
+	}
+	return ext
+}
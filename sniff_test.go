@@ -0,0 +1,167 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// magic is the start of a file of each sniffed kind.
+var magic = map[string][]byte{
+	".jpg":  {0xFF, 0xD8, 0xFF, 0xE0},
+	".png":  []byte("\x89PNG\r\n\x1a\n"),
+	".gif":  []byte("GIF89a"),
+	".webp": []byte("RIFF\x00\x00\x00\x00WEBPVP8 "),
+	".bmp":  []byte("BM\x00\x00"),
+	".tiff": []byte("II*\x00"),
+	".pdf":  []byte("%PDF-1.7"),
+	".heic": []byte("\x00\x00\x00\x18ftypheic"),
+	".avif": []byte("\x00\x00\x00\x18ftypavif"),
+	"":      []byte("\x1aE\xdf\xa3 not sniffed"), // e.g. WebM video
+}
+
+func TestOutputExt(t *testing.T) {
+	for format, want := range map[string]string{
+		"jpeg": ".jpg", "png": ".png", "heic": ".heic",
+		animationMP4: ".mp4", animationWebM: ".webm",
+		"": ".jpg", "webp": ".jpg", // no encoder: falls back to JPEG
+	} {
+		if got := outputExt(format); got != want {
+			t.Errorf("outputExt(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
+
+func TestSettleOutputExt(t *testing.T) {
+	tests := []struct {
+		content, name, want string
+	}{
+		// matching, including the accepted spellings
+		{".jpg", "a.jpg", "a.jpg"},
+		{".jpg", "a.jpeg", "a.jpeg"},
+		{".jpg", "a.JPG", "a.JPG"},
+		{".png", "a.png", "a.png"},
+		{".tiff", "a.tif", "a.tif"},
+		{".tiff", "a.tiff", "a.tiff"},
+		{".heic", "a.heic", "a.heic"},
+		// mismatched: renamed after the content
+		{".png", "a.jpg", "a.png"},
+		{".jpg", "a.png", "a.jpg"},
+		{".jpg", "a.heic", "a.jpg"},
+		{".heic", "a.jpg", "a.heic"},
+		{".avif", "a.heic", "a.avif"},
+		{".webp", "a.jpg", "a.webp"},
+		{".gif", "a.png", "a.gif"},
+		{".bmp", "a.jpg", "a.bmp"},
+		{".pdf", "a.jpg", "a.pdf"},
+		{".tiff", "a.jpg", "a.tiff"},
+		{".jpg", "a.tif", "a.jpg"},
+		// not sniffed: the encoder's name is trusted
+		{"", "a.webm", "a.webm"},
+		{"", "a.jpg", "a.jpg"},
+	}
+	for _, tt := range tests {
+		m := useMemFS(t)
+		path := filepath.Join("out", tt.name)
+		m.WriteFile(path, magic[tt.content], 0644)
+		got, err := settleOutputExt(path)
+		if err != nil {
+			t.Errorf("%s holding %q: %v", tt.name, tt.content, err)
+			continue
+		}
+		if want := filepath.Join("out", tt.want); got != want {
+			t.Errorf("%s holding %q: settled to %q, want %q", tt.name, tt.content, got, want)
+		}
+		if _, err := disk.Stat(got); err != nil {
+			t.Errorf("%s holding %q: %v", tt.name, tt.content, err)
+		}
+	}
+}
+
+// TestSettleOutputExtCollision keeps a renamed output from replacing a
+// file already named after its content.
+func TestSettleOutputExtCollision(t *testing.T) {
+	m := useMemFS(t)
+	m.WriteFile(filepath.Join("out", "a.png"), magic[".png"], 0644)
+	m.WriteFile(filepath.Join("out", "a.jpg"), magic[".png"], 0644)
+	got, err := settleOutputExt(filepath.Join("out", "a.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("out", "a (1).png"); got != want {
+		t.Fatalf("settled to %q, want %q", got, want)
+	}
+}
+
+func TestInputFormat(t *testing.T) {
+	tests := []struct {
+		content, name, want string
+	}{
+		{".png", "a.jpg", ".png"},
+		{".heic", "a.jpg", ".heic"},
+		{".jpg", "a.heic", ".jpg"},
+		{"", "a.jpeg", ".jpg"},
+		{"", "a.tif", ".tiff"},
+		{"", "a.HEIF", ".heic"},
+		{"", "a.raw", ".raw"},
+	}
+	for _, tt := range tests {
+		m := useMemFS(t)
+		path := filepath.Join("in", tt.name)
+		m.WriteFile(path, magic[tt.content], 0644)
+		if got := inputFormat(path); got != tt.want {
+			t.Errorf("%s holding %q: inputFormat = %q, want %q", tt.name, tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueOutputPathCollisions(t *testing.T) {
+	tests := []struct {
+		existing []string
+		path     string
+		want     string
+	}{
+		{nil, "a.jpg", "a.jpg"},
+		{[]string{"a.jpg"}, "a.jpg", "a (1).jpg"},
+		{[]string{"a.jpg", "a (1).jpg", "a (2).jpg"}, "a.jpg", "a (3).jpg"},
+		{[]string{"a.jpg", "a (2).jpg"}, "a.jpg", "a (1).jpg"},
+		{[]string{"a.png"}, "a.jpg", "a.jpg"},                 // other extension is no clash
+		{[]string{"a (1).jpg"}, "a (1).jpg", "a (1) (1).jpg"}, // suffix is added, not bumped
+		{[]string{"archive.tar.gz"}, "archive.tar.gz", "archive.tar (1).gz"},
+		{[]string{"noext"}, "noext", "noext (1)"},
+	}
+	for _, tt := range tests {
+		m := useMemFS(t)
+		for _, e := range tt.existing {
+			m.WriteFile(filepath.Join("out", e), []byte("x"), 0644)
+		}
+		if got, want := uniqueOutputPath(filepath.Join("out", tt.path)), filepath.Join("out", tt.want); got != want {
+			t.Errorf("with %q: uniqueOutputPath(%q) = %q, want %q", tt.existing, tt.path, got, want)
+		}
+	}
+}
+
+func TestWithExtCollisions(t *testing.T) {
+	tests := []struct {
+		existing []string
+		path     string
+		ext      string
+		want     string
+	}{
+		{nil, "a.jpg", ".jpg", "a.jpg"},
+		{[]string{"a.jpg"}, "a.jpg", ".jpg", "a.jpg"}, // same extension: left alone
+		{nil, "a.JPG", ".jpg", "a.JPG"},
+		{nil, "a.jpg", ".png", "a.png"},
+		{[]string{"a.png"}, "a.jpg", ".png", "a (1).png"},
+		{[]string{"a.png", "a (1).png"}, "a.jpg", ".png", "a (2).png"},
+		{nil, "a", ".heic", "a.heic"},
+	}
+	for _, tt := range tests {
+		m := useMemFS(t)
+		for _, e := range tt.existing {
+			m.WriteFile(filepath.Join("out", e), []byte("x"), 0644)
+		}
+		if got, want := withExt(filepath.Join("out", tt.path), tt.ext), filepath.Join("out", tt.want); got != want {
+			t.Errorf("with %q: withExt(%q, %q) = %q, want %q", tt.existing, tt.path, tt.ext, got, want)
+		}
+	}
+}
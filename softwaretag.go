@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+//
+// Software and processing tags
+// - With "Record software and settings in outputs" ticked, each JPEG or
+//   PNG output says how it was made, in XMP: the app and version as
+//   tiff:Software (shown as the EXIF Software field) and xmp:CreatorTool,
+//   and an xmpMM:History "converted" entry listing the settings, e.g.
+//   "JPEG q=72, 1600x1200, target 150KB, auto white balance"
+// - Written in the same packet as the searchable date and keywords;
+//   copied-through JPEGs keep the metadata they came with
+//
+
+// softwareName is what outputs name as the software that made them.
+func softwareName() string {
+	return "Image Compressor " + appVersion()
+}
+
+// processingHistory describes how an output of size w×h was produced.
+func processingHistory(opts compressOptions, ext string, q, w, h int) string {
+	var parts []string
+	switch ext {
+	case ".jpg":
+		parts = append(parts, fmt.Sprintf("JPEG q=%d", q))
+	case ".png":
+		parts = append(parts, "PNG lossless")
+	default:
+		parts = append(parts, strings.ToUpper(strings.TrimPrefix(ext, ".")))
+	}
+	parts = append(parts, fmt.Sprintf("%dx%d", w, h))
+	if opts.targetKB > 0 {
+		target := fmt.Sprintf("target %dKB", opts.targetKB)
+		if opts.shrinkToFit {
+			target += " with shrink to fit"
+		}
+		parts = append(parts, target)
+	}
+	if opts.docMode != "" {
+		parts = append(parts, "document mode "+opts.docMode)
+	}
+	if opts.autoLevel {
+		parts = append(parts, "auto-straighten")
+	}
+	if opts.autoWB {
+		parts = append(parts, "auto white balance")
+	}
+	if opts.autoExposure {
+		parts = append(parts, "auto exposure")
+	}
+	for _, s := range []fmt.Stringer{opts.transform, opts.adjust, opts.style} {
+		if d := s.String(); d != "" {
+			parts = append(parts, d)
+		}
+	}
+	if ext == ".jpg" {
+		if d := opts.jpeg.String(); d != "" {
+			parts = append(parts, "jpegtran "+d)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+//
+// Burst stacking
+// - Frames are aligned to the first by a global translation search
+// - "denoise" averages the aligned frames
+// - "hdr" is single-scale exposure fusion: each pixel is weighted by how
+//   well exposed and how saturated it is in each frame
+// - The merged frame is written losslessly to a temp folder and queued in
+//   place of the burst, so it then goes through the normal compression
+//
+
+const (
+	stackDenoise = "denoise"
+	stackHDR     = "hdr"
+
+	alignWidth  = 256 // width of the thumbnails used for alignment
+	alignRadius = 8   // max shift searched at thumbnail scale
+)
+
+// estimateShift finds the (dx, dy) that best maps frame onto ref, in ref pixels.
+func estimateShift(ref, frame image.Image) (int, int) {
+	rb := ref.Bounds()
+	scale := float64(rb.Dx()) / alignWidth
+	if scale < 1 {
+		scale = 1
+	}
+	w := int(float64(rb.Dx())/scale + 0.5)
+	a := imaging.Grayscale(imaging.Resize(ref, w, 0, imaging.Box))
+	b := imaging.Grayscale(imaging.Resize(frame, w, a.Bounds().Dy(), imaging.Box))
+	aw, ah := a.Bounds().Dx(), a.Bounds().Dy()
+
+	bestDX, bestDY, best := 0, 0, math.MaxFloat64
+	for dy := -alignRadius; dy <= alignRadius; dy++ {
+		for dx := -alignRadius; dx <= alignRadius; dx++ {
+			var sad float64
+			n := 0
+			for y := alignRadius; y < ah-alignRadius; y++ {
+				for x := alignRadius; x < aw-alignRadius; x++ {
+					d := int(a.Pix[y*a.Stride+x*4]) - int(b.Pix[(y+dy)*b.Stride+(x+dx)*4])
+					if d < 0 {
+						d = -d
+					}
+					sad += float64(d)
+					n++
+				}
+			}
+			if n > 0 && sad/float64(n) < best {
+				best, bestDX, bestDY = sad/float64(n), dx, dy
+			}
+		}
+	}
+	return int(math.Round(float64(bestDX) * scale)), int(math.Round(float64(bestDY) * scale))
+}
+
+// mergeFrames aligns and merges a burst. All frames must share dimensions.
+func mergeFrames(paths []string, mode string) (image.Image, error) {
+	if len(paths) < 2 {
+		return nil, fmt.Errorf("select at least two frames")
+	}
+	frames := make([]*image.NRGBA, len(paths))
+	for i, p := range paths {
+		img, err := loadImageApplyEXIF(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: load failed: %v", p, err)
+		}
+		frames[i] = imaging.Clone(img)
+		if frames[i].Bounds().Size() != frames[0].Bounds().Size() {
+			return nil, fmt.Errorf("%s: size %v differs from first frame %v", p, frames[i].Bounds().Size(), frames[0].Bounds().Size())
+		}
+	}
+
+	b := frames[0].Bounds()
+	w, h := b.Dx(), b.Dy()
+	shifts := make([][2]int, len(frames))
+	for i := 1; i < len(frames); i++ {
+		dx, dy := estimateShift(frames[0], frames[i])
+		shifts[i] = [2]int{dx, dy}
+	}
+
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var r, g, bl, wsum float64
+			for i, f := range frames {
+				sx, sy := x+shifts[i][0], y+shifts[i][1]
+				if sx < 0 || sy < 0 || sx >= w || sy >= h {
+					continue
+				}
+				o := sy*f.Stride + sx*4
+				pr, pg, pb := float64(f.Pix[o])/255, float64(f.Pix[o+1])/255, float64(f.Pix[o+2])/255
+				wt := 1.0
+				if mode == stackHDR {
+					wt = fusionWeight(pr, pg, pb)
+				}
+				r, g, bl, wsum = r+wt*pr, g+wt*pg, bl+wt*pb, wsum+wt
+			}
+			o := y*out.Stride + x*4
+			if wsum > 0 {
+				out.Pix[o] = clampByte(255 * r / wsum)
+				out.Pix[o+1] = clampByte(255 * g / wsum)
+				out.Pix[o+2] = clampByte(255 * bl / wsum)
+			}
+			out.Pix[o+3] = 255
+		}
+	}
+	return out, nil
+}
+
+// fusionWeight favours mid-tone (well-exposed) and saturated pixels.
+func fusionWeight(r, g, b float64) float64 {
+	const sigma = 0.2
+	well := math.Exp(-(r-0.5)*(r-0.5)/(2*sigma*sigma)) *
+		math.Exp(-(g-0.5)*(g-0.5)/(2*sigma*sigma)) *
+		math.Exp(-(b-0.5)*(b-0.5)/(2*sigma*sigma))
+	mean := (r + g + b) / 3
+	sat := math.Sqrt(((r-mean)*(r-mean) + (g-mean)*(g-mean) + (b-mean)*(b-mean)) / 3)
+	return well*(sat+0.05) + 1e-6
+}
+
+// writeMergedFrames merges paths and saves the result as a PNG in the
+// scratch space, returning the new file's path.
+func writeMergedFrames(paths []string, mode string) (string, error) {
+	img, err := mergeFrames(paths, mode)
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Base(paths[0])
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	dir, err := scratch.dir("stacks")
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(dir, name+"-"+mode+".png")
+	if _, err := writePNGFile(out, img); err != nil {
+		return "", fmt.Errorf("write failed: %v", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	horizonMaxTilt  = 10.0 // degrees searched either side of level
+	horizonStep     = 0.1  // degrees
+	horizonMinApply = 0.2  // smaller corrections are left alone
+	straightenMax   = 10.0 // manual slider range
+)
+
+// detectHorizon estimates the correction angle (imaging.Rotate terms) that
+// levels the dominant near-horizontal edge, e.g. a sea or skyline horizon.
+// Only the strongest 5% of mostly-horizontal Sobel edges vote.
+func detectHorizon(img image.Image) float64 {
+	small := imaging.Grayscale(imaging.Fit(img, 600, 600, imaging.Box))
+	b := small.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 3 || h < 3 {
+		return 0
+	}
+	px := func(x, y int) float64 { return float64(small.Pix[y*small.Stride+x*4]) }
+
+	type edge struct{ x, y, mag float64 }
+	var edges []edge
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			gx := px(x+1, y-1) + 2*px(x+1, y) + px(x+1, y+1) - px(x-1, y-1) - 2*px(x-1, y) - px(x-1, y+1)
+			gy := px(x-1, y+1) + 2*px(x, y+1) + px(x+1, y+1) - px(x-1, y-1) - 2*px(x, y-1) - px(x+1, y-1)
+			if math.Abs(gy) > 2*math.Abs(gx) {
+				edges = append(edges, edge{float64(x), float64(y), math.Hypot(gx, gy)})
+			}
+		}
+	}
+	if len(edges) < 100 {
+		return 0
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].mag > edges[j].mag })
+	edges = edges[:len(edges)/20+1]
+
+	xs := make([]float64, len(edges))
+	ys := make([]float64, len(edges))
+	for i, e := range edges {
+		xs[i], ys[i] = e.x, e.y
+	}
+	return bestProjectionAngle(xs, ys, w, h, horizonMaxTilt, horizonStep)
+}
+
+// straighten rotates img by angle degrees (counter-clockwise) and crops to
+// the largest upright rectangle, so no blank corners end up in the output.
+func straighten(img image.Image, angle float64) image.Image {
+	if angle == 0 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	rotated := imaging.Rotate(img, angle, color.Transparent)
+
+	sinA, cosA := math.Sincos(math.Abs(angle) * math.Pi / 180)
+	long, short := math.Max(w, h), math.Min(w, h)
+	var cw, ch float64
+	if short <= 2*sinA*cosA*long || math.Abs(sinA-cosA) < 1e-10 {
+		// half-constrained: two crop corners touch the longer side
+		x := 0.5 * short
+		if w >= h {
+			cw, ch = x/sinA, x/cosA
+		} else {
+			cw, ch = x/cosA, x/sinA
+		}
+	} else {
+		cos2a := cosA*cosA - sinA*sinA
+		cw = (w*cosA - h*sinA) / cos2a
+		ch = (h*cosA - w*sinA) / cos2a
+	}
+	return imaging.CropCenter(rotated, int(math.Floor(cw)), int(math.Floor(ch)))
+}
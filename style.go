@@ -0,0 +1,112 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	toneNone  = ""
+	toneMono  = "mono"
+	toneSepia = "sepia"
+
+	vignetteStrength = 0.35 // corner darkening
+	grainSigma       = 7.0  // luma noise, in 0..255 units
+)
+
+// styleFilter is the batch-wide look applied after colour adjustments.
+type styleFilter struct {
+	tone     string // toneNone, toneMono or toneSepia
+	vignette bool
+	grain    bool
+}
+
+func (s styleFilter) identity() bool {
+	return s == styleFilter{}
+}
+
+func (s styleFilter) String() string {
+	var parts []string
+	switch s.tone {
+	case toneMono:
+		parts = append(parts, "B&W")
+	case toneSepia:
+		parts = append(parts, "sepia")
+	}
+	if s.vignette {
+		parts = append(parts, "vignette")
+	}
+	if s.grain {
+		parts = append(parts, "grain")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (s styleFilter) apply(img image.Image) image.Image {
+	if s.identity() {
+		return img
+	}
+	switch s.tone {
+	case toneMono:
+		img = imaging.Grayscale(img)
+	case toneSepia:
+		img = imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+			r, g, b := float64(c.R), float64(c.G), float64(c.B)
+			return color.NRGBA{
+				R: clampByte(0.393*r + 0.769*g + 0.189*b),
+				G: clampByte(0.349*r + 0.686*g + 0.168*b),
+				B: clampByte(0.272*r + 0.534*g + 0.131*b),
+				A: c.A,
+			}
+		})
+	}
+	if s.vignette {
+		img = vignette(img, vignetteStrength)
+	}
+	if s.grain {
+		img = filmGrain(img, grainSigma)
+	}
+	return img
+}
+
+// vignette darkens towards the corners with a quadratic falloff.
+func vignette(img image.Image, strength float64) *image.NRGBA {
+	out := imaging.Clone(img)
+	b := out.Bounds()
+	cx, cy := float64(b.Dx())/2, float64(b.Dy())/2
+	maxD := cx*cx + cy*cy
+	for y := 0; y < b.Dy(); y++ {
+		dy := float64(y) + 0.5 - cy
+		for x := 0; x < b.Dx(); x++ {
+			dx := float64(x) + 0.5 - cx
+			f := 1 - strength*(dx*dx+dy*dy)/maxD
+			o := y*out.Stride + x*4
+			out.Pix[o] = clampByte(float64(out.Pix[o]) * f)
+			out.Pix[o+1] = clampByte(float64(out.Pix[o+1]) * f)
+			out.Pix[o+2] = clampByte(float64(out.Pix[o+2]) * f)
+		}
+	}
+	return out
+}
+
+// filmGrain adds monochrome gaussian noise. The seed depends only on the
+// image size, so re-running a batch reproduces the same output bytes.
+func filmGrain(img image.Image, sigma float64) *image.NRGBA {
+	out := imaging.Clone(img)
+	b := out.Bounds()
+	rng := rand.New(rand.NewSource(int64(b.Dx())<<32 | int64(b.Dy())))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			n := rng.NormFloat64() * sigma
+			o := y*out.Stride + x*4
+			out.Pix[o] = clampByte(float64(out.Pix[o]) + n)
+			out.Pix[o+1] = clampByte(float64(out.Pix[o+1]) + n)
+			out.Pix[o+2] = clampByte(float64(out.Pix[o+2]) + n)
+		}
+	}
+	return out
+}
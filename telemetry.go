@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Crash reports and usage statistics
+// - A panic that reaches main, and every decoder crash a file survives,
+//   leaves a report in the app's storage (crashes/): the version, platform,
+//   input format, the panic's type and message and the stack trace. File
+//   names, paths and the home folder are taken out of the message and the
+//   stack before the report is written
+// - Both sending options are off until the user turns them on in
+//   "Privacy...". Crash reports are sent at the next launch and removed once
+//   delivered; usage statistics are counts only (files read and written by
+//   format, failures and decoder crashes by input format), sent weekly
+// - Builds made without a report server (-ldflags "-X main.reportURL=...")
+//   keep crash reports on this computer and collect no statistics
+//
+
+const (
+	crashReportsKey   = "telemetry.crashReports"
+	usageStatsKey     = "telemetry.usage"
+	usageCountsKey    = "telemetry.counts"
+	usageSentKey      = "telemetry.usageSent"
+	crashAskedKey     = "telemetry.asked"
+	maxCrashReports   = 20
+	usageSendPeriod   = 7 * 24 * time.Hour
+	crashReportGlob   = "crash-*.json"
+	reportPostTimeout = 30 * time.Second
+)
+
+// reportURL receives crash reports (POST /crash) and usage statistics
+// (POST /usage); empty sends nothing.
+var reportURL string
+
+// crashDir is where crash reports are kept; main sets it, and "" records none.
+var crashDir string
+
+type crashReport struct {
+	App     string    `json:"app"`
+	Version string    `json:"version"`
+	OS      string    `json:"os"`
+	Arch    string    `json:"arch"`
+	Time    time.Time `json:"time"`
+	Where   string    `json:"where"`            // "main" or "decoder"
+	Format  string    `json:"format,omitempty"` // input format, for decoder crashes
+	Panic   string    `json:"panic"`
+	Stack   string    `json:"stack"`
+}
+
+// absPath matches an absolute path, Unix or Windows, as a whole word.
+var absPath = regexp.MustCompile(`(^|[\s"'(=\[])((?:[A-Za-z]:\\|/)[^\s"':)\]]*)`)
+
+// scrub takes paths out of text: the named files, anything that looks like
+// an absolute path, and the home folder wherever it is left.
+func scrub(text string, paths ...string) string {
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		text = strings.ReplaceAll(text, p, "<file>")
+		if base := filepath.Base(p); len(base) > 1 {
+			text = strings.ReplaceAll(text, base, "<file>")
+		}
+	}
+	text = absPath.ReplaceAllString(text, "${1}<path>")
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		text = strings.ReplaceAll(text, home, "~")
+	}
+	return text
+}
+
+// panicText is what a report keeps of r: its type and a scrubbed message.
+func panicText(r any, paths ...string) string {
+	return fmt.Sprintf("%T: %s", r, scrub(fmt.Sprint(r), paths...))
+}
+
+// knownFormat is format when a codec lists it, else ".other", so a report
+// never carries a made-up extension from a file name.
+func knownFormat(format string) string {
+	for _, c := range loadCodecs() {
+		for _, e := range c.exts {
+			if e == format {
+				return format
+			}
+		}
+	}
+	return ".other"
+}
+
+// writeCrashReport saves one report, dropping the oldest beyond
+// maxCrashReports so a folder of broken files cannot fill the disk. paths
+// are the files involved, scrubbed from the report.
+func writeCrashReport(where, format string, r any, stack []byte, paths ...string) {
+	if crashDir == "" {
+		return
+	}
+	if err := os.MkdirAll(crashDir, 0700); err != nil {
+		return
+	}
+	rep := crashReport{
+		App: "Image Compressor", Version: appVersion(), OS: runtime.GOOS, Arch: runtime.GOARCH,
+		Time: time.Now().UTC().Truncate(time.Second), Where: where, Format: format,
+		Panic: panicText(r, paths...), Stack: scrubStack(stack),
+	}
+	data, _ := json.MarshalIndent(rep, "", "  ")
+	f, err := os.CreateTemp(crashDir, "crash-"+rep.Time.Format("20060102-150405")+"-*.json")
+	if err != nil {
+		return
+	}
+	f.Write(data)
+	f.Close()
+	if saved := savedCrashReports(); len(saved) > maxCrashReports {
+		for _, p := range saved[:len(saved)-maxCrashReports] {
+			os.Remove(p)
+		}
+	}
+}
+
+// scrubStack takes the home folder out of the stack's file locations;
+// function names and line numbers stay.
+func scrubStack(stack []byte) string {
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		return strings.ReplaceAll(string(stack), home, "~")
+	}
+	return string(stack)
+}
+
+// savedCrashReports lists the unsent reports, oldest first.
+func savedCrashReports() []string {
+	if crashDir == "" {
+		return nil
+	}
+	found, _ := filepath.Glob(filepath.Join(crashDir, crashReportGlob))
+	sort.Strings(found)
+	return found
+}
+
+// reportPanic is deferred in main: it saves the report, then lets the
+// panic end the program as before.
+func reportPanic() {
+	if r := recover(); r != nil {
+		writeCrashReport("main", "", r, debug.Stack())
+		panic(r)
+	}
+}
+
+// usageStats counts what a session did, by format; nothing is counted
+// unless the user opted in.
+type usageStats struct {
+	mu      sync.Mutex
+	enabled bool
+	counts  map[string]int // e.g. "read.jpg", "wrote.png", "failed.heic", "crashed.tiff"
+}
+
+var usage = &usageStats{counts: map[string]int{}}
+
+func (u *usageStats) add(key string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.enabled {
+		u.counts[key]++
+	}
+}
+
+// file counts one processed input; skipped files are not counted.
+func (u *usageStats) file(inPath string, res fileResult) {
+	if res.skipped {
+		return
+	}
+	in := knownFormat(inputFormat(inPath))
+	u.add("read" + in)
+	if res.err != nil {
+		u.add("failed" + in)
+	} else if res.outPath != "" {
+		u.add("wrote" + knownFormat(strings.ToLower(filepath.Ext(res.outPath))))
+	}
+}
+
+// load turns counting on or off from p and adds the counts saved by
+// earlier sessions.
+func (u *usageStats) load(p preferences) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.enabled = reportURL != "" && p.Bool(usageStatsKey)
+	saved := map[string]int{}
+	json.Unmarshal([]byte(p.String(usageCountsKey)), &saved) // unreadable means none
+	for k, n := range saved {
+		u.counts[k] += n
+	}
+}
+
+// enable turns counting on or off; off drops what was counted.
+func (u *usageStats) enable(on bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.enabled = on && reportURL != ""
+	if !u.enabled {
+		u.counts = map[string]int{}
+	}
+}
+
+// save keeps the counts for the next send; they are dropped when the
+// user has opted out.
+func (u *usageStats) save(p preferences) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.enabled {
+		u.counts = map[string]int{}
+	}
+	data, _ := json.Marshal(u.counts)
+	p.SetString(usageCountsKey, string(data))
+}
+
+// payload is exactly what a usage send posts.
+func (u *usageStats) payload() []byte {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	data, _ := json.MarshalIndent(map[string]any{
+		"app": "Image Compressor", "version": appVersion(), "os": runtime.GOOS, "arch": runtime.GOARCH,
+		"counts": u.counts,
+	}, "", "  ")
+	return data
+}
+
+func postReport(path string, body []byte) error {
+	client := &http.Client{Timeout: reportPostTimeout}
+	resp, err := client.Post(strings.TrimSuffix(reportURL, "/")+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// sendCrashReports posts the saved reports, removing each once delivered.
+func sendCrashReports() (sent int, err error) {
+	if reportURL == "" {
+		return 0, fmt.Errorf("this build has no report server")
+	}
+	for _, p := range savedCrashReports() {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		if err := postReport("/crash", data); err != nil {
+			return sent, fmt.Errorf("sending crash reports failed: %v", err)
+		}
+		os.Remove(p)
+		sent++
+	}
+	return sent, nil
+}
+
+// send posts the counts when a week has passed since the last send,
+// then starts counting afresh.
+func (u *usageStats) send(p preferences) error {
+	u.mu.Lock()
+	enabled := u.enabled
+	u.mu.Unlock()
+	last := time.Unix(int64(p.IntWithFallback(usageSentKey, 0)), 0)
+	if !enabled || time.Since(last) < usageSendPeriod {
+		return nil
+	}
+	if err := postReport("/usage", u.payload()); err != nil {
+		return fmt.Errorf("sending usage statistics failed: %v", err)
+	}
+	u.mu.Lock()
+	u.counts = map[string]int{}
+	u.mu.Unlock()
+	p.SetInt(usageSentKey, int(time.Now().Unix()))
+	u.save(p)
+	return nil
+}
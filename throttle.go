@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//
+// Upload bandwidth limit
+// - One limiter paces every upload of a run to the configured KB/s, read
+//   in small chunks so the pace stays even
+//
+
+const uploadChunk = 32 * 1024
+
+// rateLimiter paces bytes across all uploads; a nil limiter is unlimited.
+type rateLimiter struct {
+	mu   sync.Mutex
+	rate float64 // bytes per second
+	next time.Time
+}
+
+func newRateLimiter(kbps int) *rateLimiter {
+	if kbps <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: float64(kbps) * 1024}
+}
+
+func (l *rateLimiter) wait(n int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	l.mu.Unlock()
+	time.Sleep(time.Until(at))
+}
+
+// throttledReader reads in chunks, waiting on the limiter and counting progress.
+type throttledReader struct {
+	r       io.Reader
+	limiter *rateLimiter
+	sent    *int64
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > uploadChunk {
+		p = p[:uploadChunk]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.limiter.wait(n)
+		atomic.AddInt64(t.sent, int64(n))
+	}
+	return n, err
+}
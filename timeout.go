@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultFileTimeout is the per-file limit the window starts with.
+const defaultFileTimeout = 5 * time.Minute
+
+// runWithTimeout runs one file's work with a deadline. The decoders and
+// encoders cannot be interrupted, so on timeout the work is abandoned: it
+// keeps running in the background and stops at its next stage check. The
+// stage after each encode checks too, and atomicWrite drops a temp file
+// finished after the deadline, so no late output is put in place.
+// timeout <= 0 means no limit.
+func runWithTimeout(inPath string, timeout time.Duration, work func(ctx context.Context) (fileResult, error)) (fileResult, error) {
+	if timeout <= 0 {
+		return guardFile(inPath, func() (fileResult, error) { return work(context.Background()) })
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type outcome struct {
+		res fileResult
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		res, err := guardFile(inPath, func() (fileResult, error) { return work(ctx) })
+		done <- outcome{res, err}
+	}()
+	select {
+	case o := <-done:
+		if errors.Is(o.err, context.DeadlineExceeded) {
+			o.err = fmt.Errorf("timed out after %s, skipped", timeout)
+		}
+		return o.res, o.err
+	case <-ctx.Done():
+		return fileResult{inPath: inPath}, fmt.Errorf("timed out after %s, skipped", timeout)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Time by step
+// - Every run times its steps across the whole batch: decode, each
+//   pipeline step, the target-size search's trial encodes, the final
+//   encode, the write, metadata and metrics, delivery to other folders and
+//   uploads
+// - Results show the totals, each step's share and the average per call,
+//   and the run log ends with them, so it is clear whether a slow batch is
+//   spent decoding, searching for a size or uploading
+// - JPEGs are encoded straight to disk, so their write counts as encode;
+//   uploads run alongside compression and are not part of the share
+//
+
+const (
+	stepSearch = "search"
+	stepWrite  = "write"
+	stepFinish = "finish"
+	stepUpload = "upload"
+)
+
+// timingOrder is the order steps are reported in.
+var timingOrder = []string{stepDecode, stepRotate, stepAdjust, stepDeskew, stepCrop, stepResize, stepBleed,
+	stepSearch, stepEncode, stepWrite, stepFinish, stepDeliver, stepUpload}
+
+var timingLabels = map[string]string{
+	stepSearch:  "search (target-size trial encodes)",
+	stepFinish:  "finish (metadata, metrics, sidecars)",
+	stepDeliver: "deliver (permissions, copies)",
+}
+
+type stepTime struct {
+	total time.Duration
+	calls int
+}
+
+// stepTimer adds up a batch's time per step; files that time out may still
+// report from their own goroutine, hence the lock. A nil timer records
+// nothing.
+type stepTimer struct {
+	mu    sync.Mutex
+	times map[string]stepTime
+}
+
+func newStepTimer() *stepTimer {
+	return &stepTimer{times: map[string]stepTime{}}
+}
+
+// start times one call of step until the returned func runs.
+func (t *stepTimer) start(step string) func() {
+	if t == nil {
+		return func() {}
+	}
+	began := time.Now()
+	return func() {
+		took := time.Since(began)
+		t.mu.Lock()
+		st := t.times[step]
+		st.total += took
+		st.calls++
+		t.times[step] = st
+		t.mu.Unlock()
+	}
+}
+
+// report is the breakdown, one step per line, or "" when nothing ran.
+func (t *stepTimer) report() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var sum time.Duration
+	for step, st := range t.times {
+		if step != stepUpload {
+			sum += st.total
+		}
+	}
+	if sum == 0 && t.times[stepUpload].calls == 0 {
+		return ""
+	}
+	lines := []string{fmt.Sprintf("%-38s %9s %6s %7s %10s", "step", "total", "share", "calls", "each")}
+	for _, step := range timingOrder {
+		st, ok := t.times[step]
+		if !ok {
+			continue
+		}
+		label := step
+		if l, ok := timingLabels[step]; ok {
+			label = l
+		}
+		share := "—"
+		if step != stepUpload && sum > 0 {
+			share = fmt.Sprintf("%.0f%%", float64(st.total)/float64(sum)*100)
+		}
+		each := st.total / time.Duration(max(1, st.calls))
+		lines = append(lines, fmt.Sprintf("%-38s %9s %6s %7d %10s", label, roundDuration(st.total), share, st.calls, roundDuration(each)))
+	}
+	lines = append(lines, fmt.Sprintf("%-38s %9s", "all steps but upload", roundDuration(sum)))
+	return strings.Join(lines, "\n")
+}
+
+// roundDuration keeps three significant places or so, for the report.
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= 10*time.Second:
+		return d.Round(100 * time.Millisecond)
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(100 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
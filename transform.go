@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// itemTransform is a manual orientation fix recorded for one queue item,
+// applied after EXIF orientation. Flips are applied first, then turns,
+// then the fine straighten angle.
+type itemTransform struct {
+	turns        int // quarter turns clockwise, 0..3
+	flipH, flipV bool
+	angle        float64 // straighten, degrees counter-clockwise
+}
+
+func (t itemTransform) identity() bool {
+	return t == itemTransform{}
+}
+
+func (t itemTransform) normalize() itemTransform {
+	t.turns = ((t.turns % 4) + 4) % 4
+	if t.flipH && t.flipV {
+		// both flips are a half turn
+		t.flipH, t.flipV = false, false
+		t.turns = (t.turns + 2) % 4
+	}
+	return t
+}
+
+func (t itemTransform) rotateCW() itemTransform  { t.turns++; return t.normalize() }
+func (t itemTransform) rotateCCW() itemTransform { t.turns--; return t.normalize() }
+
+// A flip after a rotation equals the flip first followed by the opposite rotation.
+func (t itemTransform) flipHorizontal() itemTransform {
+	t.flipH = !t.flipH
+	t.turns, t.angle = -t.turns, -t.angle
+	return t.normalize()
+}
+
+func (t itemTransform) flipVertical() itemTransform {
+	t.flipV = !t.flipV
+	t.turns, t.angle = -t.turns, -t.angle
+	return t.normalize()
+}
+
+func (t itemTransform) apply(img image.Image) image.Image {
+	if t.flipH {
+		img = imaging.FlipH(img)
+	}
+	if t.flipV {
+		img = imaging.FlipV(img)
+	}
+	switch t.turns {
+	case 1:
+		img = imaging.Rotate270(img) // imaging rotates counter-clockwise
+	case 2:
+		img = imaging.Rotate180(img)
+	case 3:
+		img = imaging.Rotate90(img)
+	}
+	return straighten(img, t.angle)
+}
+
+func (t itemTransform) String() string {
+	var parts []string
+	if t.flipH {
+		parts = append(parts, "flipped H")
+	}
+	if t.flipV {
+		parts = append(parts, "flipped V")
+	}
+	if t.turns != 0 {
+		parts = append(parts, fmt.Sprintf("%d° CW", t.turns*90))
+	}
+	if t.angle != 0 {
+		parts = append(parts, fmt.Sprintf("straightened %.1f°", t.angle))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// itemSettings are the per-file edits made from the preview pane.
+type itemSettings struct {
+	transform   itemTransform
+	skipAutoFix bool   // opt out of batch auto white balance/exposure
+	excluded    bool   // unticked: kept in the queue but left out of runs
+	priority    int    // priorityFirst, 0 for normal, or priorityLast
+	redoQuality int    // fixed quality for a redo from the review gallery, 0 for none
+	redoOf      string // output the redo replaces
+}
+
+const (
+	priorityFirst = 1
+	priorityLast  = -1
+)
+
+// byPriority orders queue entries first, normal, last, keeping the queue
+// order within each; a folder's images share its priority.
+func byPriority(items []string, perItem map[string]itemSettings) []string {
+	ordered := append([]string(nil), items...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return perItem[ordered[i]].priority > perItem[ordered[j]].priority
+	})
+	return ordered
+}
+
+// activeItems returns the queue entries that are ticked for processing.
+func activeItems(items []string, perItem map[string]itemSettings) []string {
+	var active []string
+	for _, p := range items {
+		if !perItem[p].excluded {
+			active = append(active, p)
+		}
+	}
+	return active
+}
+
+// String describes the edits shown next to the file name; exclusion has
+// its own checkbox.
+func (st itemSettings) String() string {
+	var parts []string
+	if !st.transform.identity() {
+		parts = append(parts, st.transform.String())
+	}
+	if st.skipAutoFix {
+		parts = append(parts, "no auto colour")
+	}
+	switch st.priority {
+	case priorityFirst:
+		parts = append(parts, "first")
+	case priorityLast:
+		parts = append(parts, "last")
+	}
+	return strings.Join(parts, ", ")
+}
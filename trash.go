@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//
+// Moving originals to the Trash
+// - "Move originals to the Trash" clears each source away once its output
+//   is written (and uploaded, with an upload destination), never deleting
+//   it outright: the macOS Trash, the freedesktop trash on Linux, the
+//   Recycle Bin on Windows
+// - The last run's trashed originals are remembered; "Restore Originals"
+//   puts them all back where they were, as long as nothing new has taken
+//   their place
+// - Left alone: sources inside archives and, with "Protect sources" on,
+//   every source
+//
+
+const trashedKey = "trash.last"
+
+// trashedFile is one original moved to the trash by a run.
+type trashedFile struct {
+	Original string `json:"original"`
+	Trashed  string `json:"trashed"` // where it went; "" when the platform does not say
+}
+
+// moveToTrash moves path to the platform's trash.
+func moveToTrash(path string) (trashedFile, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return trashedFile{}, err
+	}
+	trashed, err := trashFile(abs)
+	if err != nil {
+		return trashedFile{}, fmt.Errorf("moving to the Trash failed: %v", err)
+	}
+	return trashedFile{Original: abs, Trashed: trashed}, nil
+}
+
+// restore moves t back to where it was.
+func (t trashedFile) restore() error {
+	if t.Trashed == "" {
+		return fmt.Errorf("%s: put it back from the Trash by hand", filepath.Base(t.Original))
+	}
+	if _, err := os.Lstat(t.Original); err == nil {
+		return fmt.Errorf("%s exists again; the original stays in the Trash", t.Original)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.Original), 0755); err != nil {
+		return fmt.Errorf("restore failed: %v", err)
+	}
+	if err := os.Rename(t.Trashed, t.Original); err != nil {
+		return fmt.Errorf("restore failed: %v", err)
+	}
+	untrashed(t)
+	return nil
+}
+
+func loadTrashed(p preferences) []trashedFile {
+	var files []trashedFile
+	json.Unmarshal([]byte(p.String(trashedKey)), &files) // unreadable means none
+	return files
+}
+
+func saveTrashed(p preferences, files []trashedFile) {
+	data, _ := json.Marshal(files)
+	p.SetString(trashedKey, string(data))
+}
+
+// restoreOriginals puts files back, returning those that could not be.
+func restoreOriginals(files []trashedFile) (left []trashedFile, errs []error) {
+	for _, t := range files {
+		if err := t.restore(); err != nil {
+			left = append(left, t)
+			errs = append(errs, err)
+		}
+	}
+	return left, errs
+}
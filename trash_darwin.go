@@ -0,0 +1,48 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Foundation
+#include <stdlib.h>
+#include <string.h>
+#import <Foundation/Foundation.h>
+
+// trashItem moves path to the Trash and returns the malloc'd path it got
+// there, or NULL with a malloc'd message in *errOut.
+static char *trashItem(const char *path, char **errOut) {
+	@autoreleasepool {
+		NSURL *url = [NSURL fileURLWithPath:[NSString stringWithUTF8String:path]];
+		NSURL *result = nil;
+		NSError *err = nil;
+		if (![[NSFileManager defaultManager] trashItemAtURL:url resultingItemURL:&result error:&err]) {
+			*errOut = strdup([[err localizedDescription] UTF8String]);
+			return NULL;
+		}
+		return strdup(result != nil ? [[result path] UTF8String] : "");
+	}
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+)
+
+func trashFile(path string) (string, error) {
+	cp := C.CString(path)
+	defer C.free(unsafe.Pointer(cp))
+	var cerr *C.char
+	cpath := C.trashItem(cp, &cerr)
+	if cpath == nil {
+		defer C.free(unsafe.Pointer(cerr))
+		return "", errors.New(C.GoString(cerr))
+	}
+	defer C.free(unsafe.Pointer(cpath))
+	return C.GoString(cpath), nil
+}
+
+// untrashed has nothing to tidy: the Trash keeps no records of its own.
+func untrashed(t trashedFile) {}
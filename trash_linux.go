@@ -0,0 +1,107 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// trashFile follows the freedesktop.org trash spec: the file goes into
+// files/ of the home trash, or of $topdir/.Trash-$uid when it lives on
+// another volume, next to an info/<name>.trashinfo recording where it was.
+func trashFile(path string) (string, error) {
+	dir, rel, err := trashDirFor(path)
+	if err != nil {
+		return "", err
+	}
+	for _, sub := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return "", err
+		}
+	}
+	base := filepath.Base(path)
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			ext := filepath.Ext(base)
+			name = fmt.Sprintf("%s.%d%s", base[:len(base)-len(ext)], i, ext)
+		}
+		infoPath := filepath.Join(dir, "info", name+".trashinfo")
+		info, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue // the name is taken in this trash
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = fmt.Fprintf(info, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			(&url.URL{Path: rel}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+		if cerr := info.Close(); err == nil {
+			err = cerr
+		}
+		trashed := filepath.Join(dir, "files", name)
+		if err == nil {
+			err = os.Rename(path, trashed)
+		}
+		if err != nil {
+			os.Remove(infoPath)
+			return "", err
+		}
+		return trashed, nil
+	}
+}
+
+// trashDirFor is the trash for path and the path as its .trashinfo
+// records it: absolute for the home trash, relative to the volume's top
+// folder for a volume trash.
+func trashDirFor(path string) (dir, rel string, err error) {
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", err
+		}
+		data = filepath.Join(home, ".local", "share")
+	}
+	home := filepath.Join(data, "Trash")
+	os.MkdirAll(home, 0700)
+	dev := func(p string) (uint64, bool) {
+		var st syscall.Stat_t
+		if syscall.Stat(p, &st) != nil {
+			return 0, false
+		}
+		return uint64(st.Dev), true
+	}
+	src, ok := dev(path)
+	if !ok {
+		return "", "", fmt.Errorf("cannot read %s", path)
+	}
+	if d, ok := dev(home); ok && d == src {
+		return home, path, nil
+	}
+	top := filepath.Dir(path)
+	for {
+		parent := filepath.Dir(top)
+		if d, ok := dev(parent); parent == top || !ok || d != src {
+			break
+		}
+		top = parent
+	}
+	rel, err = filepath.Rel(top, path)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(top, ".Trash-"+strconv.Itoa(os.Getuid())), rel, nil
+}
+
+// untrashed removes t's .trashinfo, so file managers stop listing it.
+func untrashed(t trashedFile) {
+	dir := filepath.Dir(filepath.Dir(t.Trashed))
+	os.Remove(filepath.Join(dir, "info", filepath.Base(t.Trashed)+".trashinfo"))
+}
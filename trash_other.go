@@ -0,0 +1,39 @@
+//go:build !darwin && !linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// recycleScript sends $env:IC_TRASH_PATH to the Recycle Bin and prints
+// where it went, found by its original location among the bin's items.
+const recycleScript = `Add-Type -AssemblyName Microsoft.VisualBasic
+$p = $env:IC_TRASH_PATH
+[Microsoft.VisualBasic.FileIO.FileSystem]::DeleteFile($p, 'OnlyErrorDialogs', 'SendToRecycleBin')
+$bin = (New-Object -ComObject Shell.Application).NameSpace(10)
+$bin.Items() | Where-Object { [IO.Path]::Combine($_.ExtendedProperty('System.Recycle.DeletedFrom'), [IO.Path]::GetFileName($p)) -eq $p } | Select-Object -Last 1 -ExpandProperty Path`
+
+func trashFile(path string) (string, error) {
+	if runtime.GOOS != "windows" {
+		return "", fmt.Errorf("no trash on this platform; the original is kept")
+	}
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", recycleScript)
+	cmd.Env = append(os.Environ(), "IC_TRASH_PATH="+path)
+	out, err := cmd.Output()
+	if _, serr := os.Lstat(path); serr == nil {
+		if err == nil {
+			err = fmt.Errorf("still in place")
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil // "" if the bin could not be searched
+}
+
+// untrashed leaves the Recycle Bin's own record; Windows drops it once
+// the recycled file is gone.
+func untrashed(t trashedFile) {}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// Updates
+// - "Updates..." and a daily check at launch read the release feed: the
+//   latest version, its notes and one download per platform, signed with
+//   the release key (Ed25519, a detached base64 .sig like the integrity
+//   manifests)
+// - "Install and Restart" downloads the platform's file, checks its size
+//   and SHA-256 against the signed feed and swaps it in: the .app bundle on
+//   macOS (the download is a zip of it), else the executable itself. The
+//   old copy is kept until the next launch, then removed. A bundle with a
+//   symlink leading out of it, or an entry written through one, is refused
+// - App Store copies run sandboxed and update through the store; they only
+//   say that a new version is out. Builds without a release key do the same
+//   and link to the release page
+//
+
+const (
+	maxUpdateBytes    = 500 << 20
+	updateCheckPeriod = 24 * time.Hour
+	updateOldSuffix   = ".old"
+)
+
+// updateFeedURL and updatePublicKey (base64 Ed25519) are set for release
+// builds with -ldflags "-X main.updatePublicKey=...".
+var (
+	updateFeedURL   = "https://github.com/sanyamkunwar/Image-compressor-golang-desktop-app/releases/latest/download/update.json"
+	updatePublicKey string
+)
+
+var updateClient = &http.Client{Timeout: 10 * time.Minute}
+
+// updateAsset is one platform's download in the feed.
+type updateAsset struct {
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// updateFeed is the signed description of the latest release.
+type updateFeed struct {
+	Version string                 `json:"version"`
+	Notes   string                 `json:"notes"`
+	Page    string                 `json:"page"`   // release page for manual downloads
+	Assets  map[string]updateAsset `json:"assets"` // by GOOS/GOARCH, e.g. darwin/arm64
+}
+
+// asset is the download for this platform, if the release has one.
+func (f updateFeed) asset() (updateAsset, bool) {
+	a, ok := f.Assets[runtime.GOOS+"/"+runtime.GOARCH]
+	return a, ok && a.URL != "" && a.SHA256 != ""
+}
+
+// parseVersion reads "v1.2.3" as [1 2 3]; ok is false for anything else,
+// including pre-releases and development builds.
+func parseVersion(v string) (parts []int, ok bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if v == "" || strings.ContainsAny(v, "-+") {
+		return nil, false
+	}
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// newerVersion reports whether latest is a release after current. A
+// current version that is not a release compares older than any release.
+func newerVersion(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return true
+	}
+	for i := 0; i < max(len(l), len(c)); i++ {
+		var a, b int
+		if i < len(l) {
+			a = l[i]
+		}
+		if i < len(c) {
+			b = c[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
+
+// selfUpdateBlocker says why this copy cannot replace itself, or "".
+func selfUpdateBlocker() string {
+	if os.Getenv("APP_SANDBOX_CONTAINER_ID") != "" {
+		return "This copy updates through the App Store."
+	}
+	if updatePublicKey == "" {
+		return "This build cannot verify updates; download the new version from the release page."
+	}
+	return ""
+}
+
+func fetchUpdate(url string, limit int64) ([]byte, error) {
+	resp, err := updateClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err == nil && int64(len(data)) > limit {
+		err = fmt.Errorf("%s is larger than %dMB", url, limit>>20)
+	}
+	return data, err
+}
+
+// checkForUpdate reads the release feed. With a release key the feed's
+// signature must match it; without one the feed is only used to tell the
+// user about a new version, never to install it.
+func checkForUpdate() (updateFeed, error) {
+	var feed updateFeed
+	data, err := fetchUpdate(updateFeedURL, 1<<20)
+	if err != nil {
+		return feed, fmt.Errorf("update check failed: %v", err)
+	}
+	if updatePublicKey != "" {
+		pub, err := base64.StdEncoding.DecodeString(updatePublicKey)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return feed, fmt.Errorf("update check failed: the release key is corrupt")
+		}
+		sigText, err := fetchUpdate(updateFeedURL+".sig", 4096)
+		if err != nil {
+			return feed, fmt.Errorf("update check failed: %v", err)
+		}
+		sig, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigText)))
+		if !ed25519.Verify(pub, data, sig) {
+			return feed, fmt.Errorf("update check failed: the release feed's signature is invalid")
+		}
+	}
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return feed, fmt.Errorf("update check failed: %v", err)
+	}
+	if _, ok := parseVersion(feed.Version); !ok {
+		return feed, fmt.Errorf("update check failed: %q is not a version", feed.Version)
+	}
+	return feed, nil
+}
+
+// installTarget is what an update replaces: the .app bundle the executable
+// runs from, else the executable itself.
+func installTarget() (path string, bundle bool, err error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", false, err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", false, err
+	}
+	if macOS := filepath.Dir(exe); filepath.Base(macOS) == "MacOS" {
+		if app := filepath.Dir(filepath.Dir(macOS)); strings.HasSuffix(app, ".app") {
+			return app, true, nil
+		}
+	}
+	return exe, false, nil
+}
+
+// downloadUpdate fetches a next to target, so the swap is a rename on one
+// volume, and checks it against the feed. progress gets the bytes so far.
+func downloadUpdate(a updateAsset, target string, progress func(done, total int64)) (string, error) {
+	if a.Size > maxUpdateBytes {
+		return "", fmt.Errorf("update is larger than %dMB", maxUpdateBytes>>20)
+	}
+	resp, err := updateClient.Get(a.URL)
+	if err != nil {
+		return "", fmt.Errorf("update download failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("update download failed: %s", resp.Status)
+	}
+	f, err := os.CreateTemp(filepath.Dir(target), ".update-*")
+	if err != nil {
+		return "", fmt.Errorf("update download failed: %v", err)
+	}
+	h := sha256.New()
+	var n int64
+	buf := make([]byte, 256*1024)
+	for {
+		m, rerr := resp.Body.Read(buf)
+		if m > 0 {
+			if _, err = f.Write(buf[:m]); err != nil {
+				break
+			}
+			h.Write(buf[:m])
+			n += int64(m)
+			if n > maxUpdateBytes {
+				err = fmt.Errorf("larger than %dMB", maxUpdateBytes>>20)
+				break
+			}
+			if progress != nil {
+				progress(n, a.Size)
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			err = rerr
+			break
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && a.Size > 0 && n != a.Size {
+		err = fmt.Errorf("got %d bytes, expected %d", n, a.Size)
+	}
+	if err == nil && !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), a.SHA256) {
+		err = fmt.Errorf("the download does not match the signed release")
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("update download failed: %v", err)
+	}
+	return f.Name(), nil
+}
+
+// throughSymlink reports whether name, below dir, or any folder on the way
+// to it is a symlink already extracted, which a later entry could use to
+// write outside dir.
+func throughSymlink(dir, name string) bool {
+	p := dir
+	for _, part := range strings.Split(name, string(filepath.Separator)) {
+		p = filepath.Join(p, part)
+		if info, err := os.Lstat(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unzipBundle extracts the .app inside the zip at path into dir and
+// returns its path. Symlinks must stay inside the bundle they are in, and
+// nothing is written through one.
+func unzipBundle(path, dir string) (string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	app := ""
+	for _, zf := range zr.File {
+		name := filepath.FromSlash(zf.Name)
+		if !filepath.IsLocal(name) {
+			return "", fmt.Errorf("unsafe path %s in the update", zf.Name)
+		}
+		top := strings.Split(name, string(filepath.Separator))[0]
+		if strings.HasSuffix(top, ".app") {
+			app = filepath.Join(dir, top)
+		}
+		if throughSymlink(dir, name) {
+			return "", fmt.Errorf("unsafe path %s in the update: it goes through a symlink", zf.Name)
+		}
+		dest := filepath.Join(dir, name)
+		if zf.FileInfo().IsDir() {
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return "", err
+		}
+		r, err := zf.Open()
+		if err != nil {
+			return "", err
+		}
+		if zf.Mode()&os.ModeSymlink != 0 {
+			link, err := io.ReadAll(io.LimitReader(r, 4096))
+			r.Close()
+			if err != nil {
+				return "", err
+			}
+			target := filepath.Join(filepath.Dir(name), filepath.FromSlash(string(link)))
+			if name == top || filepath.IsAbs(string(link)) || !filepath.IsLocal(target) ||
+				strings.Split(target, string(filepath.Separator))[0] != top {
+				return "", fmt.Errorf("unsafe symlink %s -> %s in the update", zf.Name, link)
+			}
+			if err := os.Symlink(string(link), dest); err != nil {
+				return "", err
+			}
+			continue
+		}
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, zf.Mode().Perm()|0600)
+		if err == nil {
+			_, err = io.Copy(f, r)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	if app == "" {
+		return "", fmt.Errorf("the update has no .app bundle")
+	}
+	return app, nil
+}
+
+// installUpdate swaps the verified download into target's place, keeping
+// the old copy next to it until cleanupUpdate.
+func installUpdate(download, target string, bundle bool) error {
+	defer os.Remove(download)
+	next := download
+	if bundle {
+		dir, err := os.MkdirTemp(filepath.Dir(target), ".update-*")
+		if err != nil {
+			return fmt.Errorf("update install failed: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		if next, err = unzipBundle(download, dir); err != nil {
+			return fmt.Errorf("update install failed: %v", err)
+		}
+	} else if err := os.Chmod(next, 0755); err != nil {
+		return fmt.Errorf("update install failed: %v", err)
+	}
+	old := target + updateOldSuffix
+	os.RemoveAll(old)
+	if err := os.Rename(target, old); err != nil {
+		return fmt.Errorf("update install failed: %v", err)
+	}
+	if err := os.Rename(next, target); err != nil {
+		os.Rename(old, target)
+		return fmt.Errorf("update install failed: %v", err)
+	}
+	return nil
+}
+
+// cleanupUpdate removes the copy an update replaced; on Windows it could
+// not go while it was running.
+func cleanupUpdate() {
+	if target, _, err := installTarget(); err == nil {
+		os.RemoveAll(target + updateOldSuffix)
+	}
+}
+
+// relaunch starts the installed update; the caller then quits.
+func relaunch(target string, bundle bool) error {
+	cmd := exec.Command(target, os.Args[1:]...)
+	if bundle {
+		cmd = exec.Command("open", "-n", target)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("restart failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	updateAutoKey      = "update.auto"
+	updateLastCheckKey = "update.lastCheck"
+	updateSkipKey      = "update.skip"
+)
+
+// checkUpdatesAtLaunch looks for a new release at most once a day, unless
+// turned off; development builds never check on their own.
+func checkUpdatesAtLaunch(a fyne.App, w fyne.Window, prefs fyne.Preferences, busy func() bool) {
+	cleanupUpdate()
+	if !prefs.BoolWithFallback(updateAutoKey, true) {
+		return
+	}
+	if _, ok := parseVersion(appVersion()); !ok {
+		return
+	}
+	if last := time.Unix(int64(prefs.Int(updateLastCheckKey)), 0); time.Since(last) < updateCheckPeriod {
+		return
+	}
+	go func() {
+		feed, err := checkForUpdate()
+		fyne.Do(func() {
+			if err != nil {
+				return // tried again at the next launch
+			}
+			prefs.SetInt(updateLastCheckKey, int(time.Now().Unix()))
+			if newerVersion(feed.Version, appVersion()) && feed.Version != prefs.String(updateSkipKey) {
+				showUpdateOffer(a, w, prefs, feed, busy)
+			}
+		})
+	}()
+}
+
+// showUpdates checks for a new release now, for "Updates...".
+func showUpdates(a fyne.App, w fyne.Window, prefs fyne.Preferences, busy func() bool) {
+	spinner := widget.NewProgressBarInfinite()
+	checking := dialog.NewCustomWithoutButtons("Checking for Updates", spinner, w)
+	checking.Show()
+	go func() {
+		feed, err := checkForUpdate()
+		fyne.Do(func() {
+			checking.Hide()
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			prefs.SetInt(updateLastCheckKey, int(time.Now().Unix()))
+			if newerVersion(feed.Version, appVersion()) {
+				showUpdateOffer(a, w, prefs, feed, busy)
+				return
+			}
+			auto := widget.NewCheck("Check for updates at launch", func(on bool) { prefs.SetBool(updateAutoKey, on) })
+			auto.SetChecked(prefs.BoolWithFallback(updateAutoKey, true))
+			msg := widget.NewLabel(fmt.Sprintf("You have the latest version (%s).", appVersion()))
+			dialog.ShowCustom("No Updates", "Close", container.NewVBox(msg, auto), w)
+		})
+	}()
+}
+
+// showUpdateOffer describes feed's release and offers to install it.
+func showUpdateOffer(a fyne.App, w fyne.Window, prefs fyne.Preferences, feed updateFeed, busy func() bool) {
+	header := widget.NewLabel(fmt.Sprintf("Version %s is available; you have %s.", feed.Version, appVersion()))
+	notes := widget.NewLabel(feed.Notes)
+	notes.Wrapping = fyne.TextWrapWord
+	scroll := container.NewVScroll(notes)
+	scroll.SetMinSize(fyne.NewSize(520, 200))
+	why := selfUpdateBlocker()
+	if _, ok := feed.asset(); !ok && why == "" {
+		why = "There is no download for this platform in the release; see the release page."
+	}
+
+	var d dialog.Dialog
+	later := widget.NewButton("Later", func() { d.Hide() })
+	skip := widget.NewButton("Skip This Version", func() {
+		prefs.SetString(updateSkipKey, feed.Version)
+		d.Hide()
+	})
+	buttons := container.NewHBox(later, skip)
+	if u, err := url.Parse(feed.Page); err == nil && feed.Page != "" {
+		buttons.Add(widget.NewButton("Release Page", func() { a.OpenURL(u) }))
+	}
+	top := container.NewVBox(header)
+	if why != "" {
+		top.Add(widget.NewLabel(why))
+	} else {
+		install := widget.NewButton("Install and Restart", func() {
+			d.Hide()
+			installWithProgress(a, w, feed, busy)
+		})
+		install.Importance = widget.HighImportance
+		buttons.Add(install)
+	}
+	d = dialog.NewCustomWithoutButtons("Update Available", container.NewBorder(top, buttons, nil, nil, scroll), w)
+	d.Show()
+}
+
+// installWithProgress downloads, verifies and swaps in feed's release, then
+// offers to restart into it.
+func installWithProgress(a fyne.App, w fyne.Window, feed updateFeed, busy func() bool) {
+	bar := widget.NewProgressBar()
+	progress := dialog.NewCustomWithoutButtons("Downloading "+feed.Version, bar, w)
+	progress.Resize(fyne.NewSize(420, 100))
+	progress.Show()
+	go func() {
+		asset, _ := feed.asset()
+		target, bundle, err := installTarget()
+		var download string
+		if err == nil {
+			download, err = downloadUpdate(asset, target, func(done, total int64) {
+				if total > 0 {
+					fyne.Do(func() { bar.SetValue(float64(done) / float64(total)) })
+				}
+			})
+		}
+		if err == nil {
+			err = installUpdate(download, target, bundle)
+		}
+		fyne.Do(func() {
+			progress.Hide()
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			dialog.ShowConfirm("Update Installed", fmt.Sprintf("Version %s is installed. Restart now?", feed.Version), func(ok bool) {
+				if !ok {
+					return
+				}
+				if busy() {
+					dialog.ShowInformation("Uploads Running", "Restart once the uploads have finished; the update is already installed.", w)
+					return
+				}
+				if err := relaunch(target, bundle); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				a.Quit()
+			}, w)
+		})
+	}()
+}
@@ -0,0 +1,152 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+//
+// Background uploads
+// - Remote destinations upload on worker goroutines while compression
+//   carries on, with a cap on parallel uploads and a shared bandwidth limit
+// - Completion callbacks run on the UI goroutine via fyne.Do
+//
+
+const defaultUploadWorkers = 2
+
+type uploadJob struct {
+	localPath string
+	done      func(err error) // runs on the UI goroutine
+}
+
+// uploadQueue sends each job to every remote destination. Jobs are held in
+// an unbounded list so adding never blocks the compression loop.
+type uploadQueue struct {
+	dests  []destination
+	mu     sync.Mutex
+	cond   *sync.Cond
+	jobs   []uploadJob
+	closed bool
+	limit  int // uploads allowed at once below the worker count; 0 = all workers
+	active int
+	wg     sync.WaitGroup
+	stop   chan struct{}
+	queued int64 // bytes
+	sent   int64 // bytes, updated by throttledReader
+
+	timings *stepTimer // set before the first add; nil records nothing
+}
+
+// newUploadQueue starts the workers; onProgress runs on the UI goroutine a
+// few times a second until the queue drains.
+func newUploadQueue(dests []destination, workers, kbps int, onProgress func(sent, queued int64)) *uploadQueue {
+	if workers <= 0 {
+		workers = defaultUploadWorkers
+	}
+	q := &uploadQueue{dests: dests, stop: make(chan struct{})}
+	q.cond = sync.NewCond(&q.mu)
+	limiter := newRateLimiter(kbps)
+	for _, d := range dests {
+		if s, ok := d.(*s3Dest); ok {
+			s.limiter, s.sent = limiter, &q.sent
+		}
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	go func() {
+		t := time.NewTicker(250 * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-q.stop:
+				return
+			}
+			sent, queued := atomic.LoadInt64(&q.sent), atomic.LoadInt64(&q.queued)
+			fyne.Do(func() { onProgress(sent, queued) })
+		}
+	}()
+	return q
+}
+
+func (q *uploadQueue) next() (uploadJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for (len(q.jobs) == 0 || (q.limit > 0 && q.active >= q.limit)) && !(q.closed && len(q.jobs) == 0) {
+		q.cond.Wait()
+	}
+	if len(q.jobs) == 0 {
+		return uploadJob{}, false
+	}
+	job := q.jobs[0]
+	q.jobs = q.jobs[1:]
+	q.active++
+	return job, true
+}
+
+// setLimit caps how many uploads run at once, e.g. on battery; 0 lifts it.
+func (q *uploadQueue) setLimit(n int) {
+	q.mu.Lock()
+	q.limit = n
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+func (q *uploadQueue) work() {
+	defer q.wg.Done()
+	for {
+		job, ok := q.next()
+		if !ok {
+			return
+		}
+		var err error
+		uploadDone := q.timings.start(stepUpload)
+		for _, d := range q.dests {
+			if err = d.put(job.localPath); err != nil {
+				err = fmt.Errorf("upload to %s failed: %v", d.name(), err)
+				break
+			}
+		}
+		uploadDone()
+		q.mu.Lock()
+		q.active--
+		q.mu.Unlock()
+		q.cond.Broadcast()
+		done := job.done
+		fyne.Do(func() { done(err) })
+	}
+}
+
+// add queues localPath; the file counts once per remote destination.
+func (q *uploadQueue) add(localPath string, done func(err error)) {
+	if info, err := os.Stat(localPath); err == nil {
+		atomic.AddInt64(&q.queued, info.Size()*int64(len(q.dests)))
+	}
+	q.mu.Lock()
+	q.jobs = append(q.jobs, uploadJob{localPath: localPath, done: done})
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// finish stops accepting jobs and calls allDone on the UI goroutine once the
+// queue drains.
+func (q *uploadQueue) finish(allDone func()) {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+	go func() {
+		q.wg.Wait()
+		close(q.stop)
+		fyne.Do(allDone)
+	}()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//
+// URL input
+// - Pasted or loaded URL lists are downloaded into a temp folder and
+//   queued like local files
+// - Names come from the URL path; the extension falls back to the
+//   response's Content-Type
+//
+
+const maxDownloadBytes = 200 << 20
+
+var downloadClient = &http.Client{Timeout: 2 * time.Minute}
+
+// parseURLList returns the http(s) URLs in text, one per line; blank lines
+// and # comments are ignored.
+func parseURLList(text string) ([]string, error) {
+	var urls []string
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("line %d: not an http(s) URL: %s", n+1, line)
+		}
+		urls = append(urls, line)
+	}
+	return urls, nil
+}
+
+// downloadName picks a file name for u, e.g. https://cdn.example.com/a/hero.jpg?w=2000 -> hero.jpg.
+func downloadName(u *url.URL, contentType string) string {
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		name = strings.ReplaceAll(u.Hostname(), ".", "-")
+	}
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, name)
+	if filepath.Ext(name) == "" {
+		if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+			name += downloadExts[mt]
+		}
+	}
+	return name
+}
+
+// downloadExts maps Content-Type to an extension listImages accepts.
+var downloadExts = map[string]string{
+	"image/jpeg": ".jpg", "image/png": ".png", "image/webp": ".webp", "image/gif": ".gif",
+	"image/bmp": ".bmp", "image/tiff": ".tiff", "application/pdf": ".pdf",
+}
+
+func downloadImage(rawURL, dir string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	resp, err := downloadClient.Get(rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", resp.Status)
+	}
+	ct := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(ct, "image/") && ct != "application/pdf" && ct != "application/octet-stream" {
+		return "", fmt.Errorf("not an image (%s)", ct)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	out := uniqueOutputPath(filepath.Join(dir, downloadName(u, ct)))
+	f, err := os.Create(out)
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxDownloadBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxDownloadBytes {
+		err = fmt.Errorf("larger than %dMB", maxDownloadBytes>>20)
+	}
+	if err != nil {
+		os.Remove(out)
+		return "", err
+	}
+	return out, nil
+}
+
+// downloadImages fetches every URL into a fresh temp folder. Failures are
+// reported per URL and do not stop the rest.
+func downloadImages(urls []string) ([]string, []error) {
+	dir, err := scratch.dir("downloads")
+	if err != nil {
+		return nil, []error{err}
+	}
+	var paths []string
+	var errs []error
+	for _, u := range urls {
+		p, err := downloadImage(u, dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", u, err))
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths, errs
+}
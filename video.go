@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image/gif"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+//
+// Animated GIF → MP4/WebM
+// - Uses an external ffmpeg from PATH; nothing is bundled
+// - Frame timing is passed through unchanged (variable frame rate), so
+//   per-frame GIF delays survive the conversion
+//
+
+const (
+	animationMP4  = "mp4"
+	animationWebM = "webm"
+)
+
+// isAnimatedGIF reports whether path is a GIF with more than one frame.
+func isAnimatedGIF(path string) (animated bool) {
+	defer func() {
+		if recover() != nil {
+			animated = false // let the regular decoder report the broken file
+		}
+	}()
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	return err == nil && len(g.Image) > 1
+}
+
+func ffmpegArgs(inPath, outPath, container string) []string {
+	args := []string{"-y", "-hide_banner", "-loglevel", "error", "-i", inPath,
+		"-fps_mode", "passthrough",
+		// yuv420p needs even dimensions
+		"-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2", "-pix_fmt", "yuv420p", "-an"}
+	if container == animationWebM {
+		return append(args, "-c:v", "libvpx-vp9", "-b:v", "0", "-crf", "35", outPath)
+	}
+	return append(args, "-c:v", "libx264", "-crf", "23", "-movflags", "+faststart", outPath)
+}
+
+// convertAnimation re-encodes an animated GIF as a video file.
+func convertAnimation(ctx context.Context, inPath, outPath, container string) (fileResult, error) {
+	fail := fileResult{inPath: inPath, outPath: outPath}
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return fail, fmt.Errorf("ffmpeg not found in PATH; install it to convert animations")
+	}
+	out, err := exec.CommandContext(ctx, ffmpeg, ffmpegArgs(inPath, outPath, container)...).CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if i := strings.LastIndex(msg, "\n"); i >= 0 {
+			msg = msg[i+1:]
+		}
+		os.Remove(outPath)
+		return fail, fmt.Errorf("ffmpeg failed: %v %s", err, msg)
+	}
+
+	res := fileResult{inPath: inPath, outPath: outPath}
+	if info, err := os.Stat(inPath); err == nil {
+		res.inSize = info.Size()
+	}
+	info, err := os.Stat(outPath)
+	if err != nil {
+		return fail, fmt.Errorf("stat failed: %v", err)
+	}
+	res.outSize = info.Size()
+	res.msg = fmt.Sprintf("OK %s -> %s (%s, %dKB, %.0f%% smaller)",
+		inPath, outPath, container, res.outSize/1024, savedPercent(res))
+	return res, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// Stuck-file watchdog
+// - One odd file (a huge multi-page TIFF, a damaged PNG) can take far
+//   longer than the rest. Once a few files are done, a file that takes
+//   more than N× the batch's median time is flagged: "⚠ Slow" in Results
+//   and a warning in the log. The time is known once the file finishes,
+//   so that is when the flag appears; only "Skip stuck files" acts while
+//   the file is still running
+// - With "Skip stuck files" on, such a file is abandoned at that point
+//   instead of at the per-file timeout, with the reason recorded as its
+//   error, and the run moves on
+// - Short batches are left alone: nothing counts as stuck before
+//   stuckMinSamples files have finished, or under stuckMinTime
+//
+
+const (
+	defaultStuckFactor = 10
+	stuckMinSamples    = 5
+	stuckMinTime       = 15 * time.Second
+)
+
+// parseStuckFactor reads the "N× the median" setting; empty means off.
+func parseStuckFactor(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) || (f > 0 && f < 1) {
+		return 0, fmt.Errorf("stuck-file factor must be a number of 1 or more, or 0 for off; got %q", text)
+	}
+	return f, nil
+}
+
+type stuckWatchdog struct {
+	factor float64 // 0 disables the watchdog
+	skip   bool
+	times  []time.Duration // of the files finished so far
+}
+
+func (w *stuckWatchdog) median() time.Duration {
+	if len(w.times) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), w.times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// limit is how long a file may take before it counts as stuck; 0 while
+// the batch is too young to tell.
+func (w *stuckWatchdog) limit() time.Duration {
+	if w.factor <= 0 || len(w.times) < stuckMinSamples {
+		return 0
+	}
+	return max(stuckMinTime, time.Duration(float64(w.median())*w.factor))
+}
+
+// run is runWithTimeout under the watchdog: it sets res.stuckFor when the
+// file overran the limit and, with skip on, abandons it there.
+func (w *stuckWatchdog) run(inPath string, timeout time.Duration, work func(ctx context.Context) (fileResult, error)) (fileResult, error) {
+	limit, median := w.limit(), w.median()
+	abandon := w.skip && limit > 0 && (timeout <= 0 || limit < timeout)
+	if abandon {
+		timeout = limit
+	}
+	start := time.Now()
+	res, err := runWithTimeout(inPath, timeout, work)
+	took := time.Since(start)
+	if err == nil {
+		w.times = append(w.times, took)
+	}
+	if limit > 0 && took >= limit {
+		res.stuckFor = took.Round(time.Second)
+		if abandon && err != nil {
+			err = fmt.Errorf("stuck: no result after %s, over %.0f× the batch median of %s; skipped",
+				res.stuckFor, w.factor, median.Round(100*time.Millisecond))
+		}
+	}
+	return res, err
+}